@@ -33,6 +33,9 @@ type ClusterUsageCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
 	// GlobalCapacity displays the total storage capacity of the cluster. This
 	// information is based on the actual no. of objects that are
 	// allocated. It does not take overcommitment into consideration.
@@ -50,27 +53,28 @@ type ClusterUsageCollector struct {
 // ClusterUsageCollector and internally defines each metric that display
 // cluster stats.
 func NewClusterUsageCollector(exporter *Exporter) *ClusterUsageCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	return &ClusterUsageCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
 
 		GlobalCapacity: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace:   cephNamespace,
+			Namespace:   namespace,
 			Name:        "cluster_capacity_bytes",
 			Help:        "Total capacity of the cluster",
 			ConstLabels: labels,
 		}),
 		UsedCapacity: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace:   cephNamespace,
+			Namespace:   namespace,
 			Name:        "cluster_used_bytes",
 			Help:        "Capacity of the cluster currently in use",
 			ConstLabels: labels,
 		}),
 		AvailableCapacity: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace:   cephNamespace,
+			Namespace:   namespace,
 			Name:        "cluster_available_bytes",
 			Help:        "Available space within the cluster",
 			ConstLabels: labels,
@@ -106,6 +110,7 @@ func (c *ClusterUsageCollector) collect() error {
 	}
 	stats := &cephClusterStats{}
 	if err := json.Unmarshal(buf, stats); err != nil {
+		c.parseErrors.observe("clusterUsage")
 		return err
 	}
 
@@ -140,14 +145,16 @@ func (c *ClusterUsageCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect sends the metric values for each metric pertaining to the global
 // cluster usage over to the provided prometheus Metric channel.
-func (c *ClusterUsageCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (c *ClusterUsageCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
 	c.logger.Debug("collecting cluster usage metrics")
 	if err := c.collect(); err != nil {
 		c.logger.WithError(err).Error("error collecting cluster usage metrics")
-		return
+		return err
 	}
 
 	for _, metric := range c.metricsList() {
 		ch <- metric
 	}
+
+	return nil
 }
@@ -0,0 +1,73 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CollectorStatusCollector reports how long each collector took and whether
+// it succeeded during the last scrape. Collectors keep serving their
+// previous values or nothing at all when a command fails, these metrics
+// tell a stale or missing value apart from a healthy cluster.
+type CollectorStatusCollector struct {
+	// Duration reports the time in seconds the collector took.
+	Duration *prometheus.Desc
+
+	// Success reports whether the collector succeeded.
+	Success *prometheus.Desc
+}
+
+// NewCollectorStatusCollector creates a new CollectorStatusCollector.
+func NewCollectorStatusCollector(exporter *Exporter) *CollectorStatusCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &CollectorStatusCollector{
+		Duration: prometheus.NewDesc(fmt.Sprintf("%s_collector_duration_seconds", namespace), "Time spent by the collector during the last scrape",
+			[]string{"collector"}, labels,
+		),
+		Success: prometheus.NewDesc(fmt.Sprintf("%s_collector_success", namespace), "Whether the collector succeeded during the last scrape",
+			[]string{"collector"}, labels,
+		),
+	}
+}
+
+// observe sends the duration and the outcome of a collector run to the
+// provided channel. It is a no-op on a nil receiver, so that exporters built
+// without a CollectorStatusCollector don't have to care.
+func (c *CollectorStatusCollector) observe(ch chan<- prometheus.Metric, collector string, duration time.Duration, err error) {
+	if c == nil {
+		return
+	}
+
+	var success float64
+	if err == nil {
+		success = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.Duration, prometheus.GaugeValue, duration.Seconds(), collector)
+	ch <- prometheus.MustNewConstMetric(c.Success, prometheus.GaugeValue, success, collector)
+}
+
+// Describe sends the descriptors of the collector status metrics to the
+// provided channel.
+func (c *CollectorStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.Duration
+	ch <- c.Success
+}
@@ -0,0 +1,79 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorStatusCollector(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).Return([]byte(`{"stats": {"total_bytes": 10, "total_used_bytes": 6, "total_avail_bytes": 4}}`), "", nil)
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.collectorStatus = NewCollectorStatusCollector(e)
+	e.cc = map[string]versionedCollector{
+		"clusterUsage": NewClusterUsageCollector(e),
+		"mon":          NewMonitorCollector(e),
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_collector_success{cluster="ceph",collector="clusterUsage"} 1\n`),
+		regexp.MustCompile(`ceph_collector_success{cluster="ceph",collector="mon"} 0\n`),
+		regexp.MustCompile(`ceph_collector_duration_seconds{cluster="ceph",collector="clusterUsage"} [0-9.e-]+\n`),
+		regexp.MustCompile(`ceph_collector_duration_seconds{cluster="ceph",collector="mon"} [0-9.e-]+\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+}
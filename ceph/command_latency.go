@@ -0,0 +1,127 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// CommandLatencyCollector wraps a Conn and measures how long each mon and mgr
+// command takes to get a reply, as seen by the exporter itself. This gives a
+// direct signal of mon/mgr responsiveness that is independent of how the
+// individual collectors interpret the replies.
+type CommandLatencyCollector struct {
+	conn   Conn
+	logger *logrus.Logger
+
+	// Latency tracks the time in seconds taken by each command, labelled by
+	// the command prefix.
+	Latency *prometheus.HistogramVec
+}
+
+// *CommandLatencyCollector must implement the Conn.
+var _ Conn = &CommandLatencyCollector{}
+
+// NewCommandLatencyCollector creates a new CommandLatencyCollector wrapping
+// the exporter's Conn. The returned collector should be used as the Conn for
+// all other collectors so that their commands are timed.
+func NewCommandLatencyCollector(exporter *Exporter) *CommandLatencyCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &CommandLatencyCollector{
+		conn:   exporter.Conn,
+		logger: exporter.Logger,
+
+		Latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				Name:        "rados_command_latency_seconds",
+				Help:        "Time taken by the cluster to reply to a mon or mgr command issued by the exporter",
+				ConstLabels: labels,
+				Buckets:     []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"prefix"},
+		),
+	}
+}
+
+// commandPrefix extracts the "prefix" field out of a JSON encoded command.
+func commandPrefix(args []byte) string {
+	cmd := struct {
+		Prefix string `json:"prefix"`
+	}{}
+
+	if err := json.Unmarshal(args, &cmd); err != nil || cmd.Prefix == "" {
+		return "unknown"
+	}
+
+	return cmd.Prefix
+}
+
+// MonCommand executes a monitor command and records its latency.
+func (c *CommandLatencyCollector) MonCommand(args []byte) ([]byte, string, error) {
+	start := time.Now()
+	defer func() {
+		c.Latency.WithLabelValues(commandPrefix(args)).Observe(time.Since(start).Seconds())
+	}()
+
+	return c.conn.MonCommand(args)
+}
+
+// MgrCommand executes a manager command and records its latency.
+func (c *CommandLatencyCollector) MgrCommand(args [][]byte) ([]byte, string, error) {
+	start := time.Now()
+	defer func() {
+		var prefix string
+		if len(args) > 0 {
+			prefix = commandPrefix(args[0])
+		} else {
+			prefix = commandPrefix(nil)
+		}
+		c.Latency.WithLabelValues(prefix).Observe(time.Since(start).Seconds())
+	}()
+
+	return c.conn.MgrCommand(args)
+}
+
+// GetPoolStats passes through to the wrapped Conn, it is not a mon or mgr
+// command and is therefore not timed.
+func (c *CommandLatencyCollector) GetPoolStats(pool string) (*PoolStat, error) {
+	return c.conn.GetPoolStats(pool)
+}
+
+// GetPoolStatsContext passes through to the wrapped Conn, it is not a mon
+// or mgr command and is therefore not timed.
+func (c *CommandLatencyCollector) GetPoolStatsContext(ctx context.Context, pool string) (*PoolStat, error) {
+	return c.conn.GetPoolStatsContext(ctx, pool)
+}
+
+// Describe sends the descriptors of the command latency metrics to the
+// provided channel.
+func (c *CommandLatencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.Latency.Describe(ch)
+}
+
+// Collect sends the command latency metrics to the provided channel.
+func (c *CommandLatencyCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	c.Latency.Collect(ch)
+	return nil
+}
@@ -0,0 +1,87 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLatencyCollector(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).After(delay).Return([]byte(`{"stats": {"total_bytes": 10}}`), "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	commandLatency := NewCommandLatencyCollector(e)
+	e.Conn = commandLatency
+	e.cc = map[string]versionedCollector{
+		"commandLatency": commandLatency,
+		"clusterUsage":   NewClusterUsageCollector(e),
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	// Collectors run concurrently, so latencies observed during a scrape are
+	// only guaranteed to be exposed by the following one.
+	var buf []byte
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+
+		buf, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	require.Regexp(t, regexp.MustCompile(`ceph_rados_command_latency_seconds_count{cluster="ceph",prefix="df"} [12]`), string(buf))
+
+	matched := regexp.MustCompile(`ceph_rados_command_latency_seconds_sum{cluster="ceph",prefix="df"} (\S+)`).FindSubmatch(buf)
+	require.Len(t, matched, 2)
+
+	latency, err := strconv.ParseFloat(string(matched[1]), 64)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, latency, delay.Seconds())
+}
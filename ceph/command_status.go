@@ -0,0 +1,230 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// commandRetryDelay is how long to wait before retrying a command that
+	// failed transiently the first time, the delay doubles on every retry.
+	commandRetryDelay = 500 * time.Millisecond
+
+	// defaultCommandAttempts is how many times a command is attempted if
+	// the exporter doesn't say otherwise.
+	defaultCommandAttempts = 3
+)
+
+// CommandError is returned for the mon and mgr commands the cluster failed.
+// librados only turns the return code into an error, the status message
+// explaining it is kept here along with the command prefix.
+type CommandError struct {
+	Prefix string
+	Status string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	if e.Status == "" {
+		return fmt.Sprintf("%s: %v", e.Prefix, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Prefix, e.Err, e.Status)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// isTransientCommandError returns whether the command may succeed if
+// retried, e.g. while the mgr fails over or the mons hold an election.
+func isTransientCommandError(err error) bool {
+	code, ok := commandErrorCode(err)
+	if !ok {
+		return false
+	}
+
+	return code == -int(syscall.EAGAIN) || code == -int(syscall.ETIMEDOUT)
+}
+
+// commandErrorCode returns the negative errno of a failed command, as set by
+// librados.
+func commandErrorCode(err error) (int, bool) {
+	var coded interface{ ErrorCode() int }
+	if !errors.As(err, &coded) {
+		return 0, false
+	}
+	return coded.ErrorCode(), true
+}
+
+// CommandStatusConn wraps a Conn and turns the failed mon and mgr commands
+// into a CommandError. Commands failing transiently, with EAGAIN while the
+// mgr fails over or ETIMEDOUT while the mons hold an election, are retried
+// with exponential backoff, as long as the retry fits in the scrape.
+type CommandStatusConn struct {
+	conn   Conn
+	logger *logrus.Logger
+
+	// attempts is how many times a command is attempted.
+	attempts int
+
+	// retryDelay is how long to wait before the first retry of a command.
+	retryDelay time.Duration
+
+	// scrapeMu protects scrapeCtx and scrapeCancel.
+	scrapeMu sync.Mutex
+	// scrapeCtx is done when the ongoing scrape times out or ends, the
+	// retries waiting for their turn give up then. nil means no scrape
+	// deadline.
+	scrapeCtx    context.Context
+	scrapeCancel context.CancelFunc
+}
+
+// *CommandStatusConn must implement the Conn.
+var _ Conn = &CommandStatusConn{}
+
+// NewCommandStatusConn creates a new CommandStatusConn wrapping the
+// exporter's Conn.
+func NewCommandStatusConn(exporter *Exporter) *CommandStatusConn {
+	attempts := exporter.CommandAttempts
+	if attempts <= 0 {
+		attempts = defaultCommandAttempts
+	}
+
+	return &CommandStatusConn{
+		conn:       exporter.Conn,
+		logger:     exporter.Logger,
+		attempts:   attempts,
+		retryDelay: commandRetryDelay,
+	}
+}
+
+// setDeadline sets when the ongoing scrape times out, the zero time ends
+// it, along with the retries still waiting. It is a no-op on a nil receiver,
+// so that exporters built without a CommandStatusConn don't have to care.
+func (c *CommandStatusConn) setDeadline(deadline time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.scrapeMu.Lock()
+	defer c.scrapeMu.Unlock()
+	if c.scrapeCancel != nil {
+		c.scrapeCancel()
+	}
+
+	c.scrapeCtx, c.scrapeCancel = nil, nil
+	if !deadline.IsZero() {
+		c.scrapeCtx, c.scrapeCancel = context.WithDeadline(context.Background(), deadline)
+	}
+}
+
+// scrapeContext returns the context of the ongoing scrape.
+func (c *CommandStatusConn) scrapeContext() context.Context {
+	c.scrapeMu.Lock()
+	defer c.scrapeMu.Unlock()
+	if c.scrapeCtx == nil {
+		return context.Background()
+	}
+
+	return c.scrapeCtx
+}
+
+// waitRetry waits for the given delay before a retry. It returns false
+// right away if the retry wouldn't fit in the scrape of ctx, and as soon as
+// the scrape times out or ends while waiting.
+func waitRetry(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Add(delay).Before(deadline) {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *CommandStatusConn) run(prefix string, fn func() ([]byte, string, error)) ([]byte, string, error) {
+	// The retries belong to the scrape the command was issued in.
+	ctx := c.scrapeContext()
+
+	buf, status, err := fn()
+	delay := c.retryDelay
+	for attempt := 1; attempt < c.attempts && isTransientCommandError(err); attempt++ {
+		if !waitRetry(ctx, delay) {
+			c.logger.WithError(err).WithField("prefix", prefix).Debug("not retrying command past the scrape deadline")
+			break
+		}
+
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"prefix":  prefix,
+			"attempt": attempt + 1,
+		}).Debug("retrying command")
+		delay *= 2
+
+		buf, status, err = fn()
+	}
+
+	if err != nil {
+		return buf, status, &CommandError{Prefix: prefix, Status: status, Err: err}
+	}
+
+	return buf, status, nil
+}
+
+// MonCommand executes a monitor command and reports its failure.
+func (c *CommandStatusConn) MonCommand(args []byte) ([]byte, string, error) {
+	return c.run(commandPrefix(args), func() ([]byte, string, error) {
+		return c.conn.MonCommand(args)
+	})
+}
+
+// MgrCommand executes a manager command and reports its failure.
+func (c *CommandStatusConn) MgrCommand(args [][]byte) ([]byte, string, error) {
+	var prefix string
+	if len(args) > 0 {
+		prefix = commandPrefix(args[0])
+	} else {
+		prefix = commandPrefix(nil)
+	}
+
+	return c.run(prefix, func() ([]byte, string, error) {
+		return c.conn.MgrCommand(args)
+	})
+}
+
+// GetPoolStats passes through to the wrapped Conn, it is not a mon or mgr
+// command.
+func (c *CommandStatusConn) GetPoolStats(pool string) (*PoolStat, error) {
+	return c.conn.GetPoolStats(pool)
+}
+
+// GetPoolStatsContext passes through to the wrapped Conn, it is not a mon
+// or mgr command.
+func (c *CommandStatusConn) GetPoolStatsContext(ctx context.Context, pool string) (*PoolStat, error) {
+	return c.conn.GetPoolStatsContext(ctx, pool)
+}
@@ -0,0 +1,206 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRadosError mimics the errors librados commands fail with.
+type fakeRadosError int
+
+func (e fakeRadosError) Error() string {
+	return fmt.Sprintf("rados: ret=%d", int(e))
+}
+
+func (e fakeRadosError) ErrorCode() int {
+	return int(e)
+}
+
+func TestCommandStatusConn(t *testing.T) {
+	dfCommand := mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})
+
+	for _, tt := range []struct {
+		name      string
+		setup     func(conn *MockConn)
+		calls     int
+		errStatus string
+		success   string
+	}{
+		{
+			name: "eagain retried",
+			setup: func(conn *MockConn) {
+				conn.On("MonCommand", dfCommand).Return([]byte(""), "mgr is not available, try again", fakeRadosError(-int(syscall.EAGAIN))).Once()
+				conn.On("MonCommand", dfCommand).Return([]byte(`{"stats": {"total_bytes": 10}}`), "", nil).Once()
+			},
+			calls:   2,
+			success: "1",
+		},
+		{
+			name: "election retried with backoff",
+			setup: func(conn *MockConn) {
+				conn.On("MonCommand", dfCommand).Return([]byte(""), "", fakeRadosError(-int(syscall.ETIMEDOUT))).Once()
+				conn.On("MonCommand", dfCommand).Return([]byte(""), "mgr is not available, try again", fakeRadosError(-int(syscall.EAGAIN))).Once()
+				conn.On("MonCommand", dfCommand).Return([]byte(`{"stats": {"total_bytes": 10}}`), "", nil).Once()
+			},
+			calls:   3,
+			success: "1",
+		},
+		{
+			name: "eagain exhausting the attempts",
+			setup: func(conn *MockConn) {
+				conn.On("MonCommand", dfCommand).Return([]byte(""), "mgr is not available, try again", fakeRadosError(-int(syscall.EAGAIN))).Times(3)
+			},
+			calls:     3,
+			errStatus: "mgr is not available, try again",
+			success:   "0",
+		},
+		{
+			name: "enoent",
+			setup: func(conn *MockConn) {
+				conn.On("MonCommand", dfCommand).Return([]byte(""), "unrecognized command", fakeRadosError(-int(syscall.ENOENT))).Once()
+			},
+			calls:     1,
+			errStatus: "unrecognized command",
+			success:   "0",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+			tt.setup(conn)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			statusConn := NewCommandStatusConn(e)
+			statusConn.retryDelay = 0
+
+			_, _, err := statusConn.MonCommand(NewClusterUsageCollector(e).cephUsageCommand())
+			if tt.errStatus == "" {
+				require.NoError(t, err)
+			} else {
+				var cmdErr *CommandError
+				require.True(t, errors.As(err, &cmdErr))
+				require.Equal(t, "df", cmdErr.Prefix)
+				require.Equal(t, tt.errStatus, cmdErr.Status)
+				require.Contains(t, err.Error(), tt.errStatus)
+			}
+			conn.AssertNumberOfCalls(t, "MonCommand", tt.calls)
+
+			// Scrape through the exporter, the collector must report the
+			// failed command.
+			conn = setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+			tt.setup(conn)
+
+			e = &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			statusConn = NewCommandStatusConn(e)
+			statusConn.retryDelay = 0
+			e.Conn = statusConn
+			e.collectorStatus = NewCollectorStatusCollector(e)
+			e.cc = map[string]versionedCollector{
+				"clusterUsage": NewClusterUsageCollector(e),
+			}
+
+			err = prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Regexp(t, regexp.MustCompile(`ceph_collector_success{cluster="ceph",collector="clusterUsage"} `+tt.success+`\n`), string(buf))
+		})
+	}
+}
+
+func TestCommandStatusConnDeadline(t *testing.T) {
+	conn := &MockConn{}
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "mgr is not available, try again", fakeRadosError(-int(syscall.EAGAIN)))
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), CommandAttempts: 5}
+	statusConn := NewCommandStatusConn(e)
+	statusConn.retryDelay = time.Hour
+
+	// Waiting for the retry would outlive the scrape, give up right away.
+	statusConn.setDeadline(time.Now().Add(time.Second))
+
+	_, _, err := statusConn.MonCommand([]byte(`{"prefix": "df"}`))
+	require.Error(t, err)
+	conn.AssertNumberOfCalls(t, "MonCommand", 1)
+}
+
+func TestCommandStatusConnScrapeEnd(t *testing.T) {
+	called := make(chan struct{}, 5)
+	conn := &MockConn{}
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "mgr is not available, try again", fakeRadosError(-int(syscall.EAGAIN))).Run(func(mock.Arguments) {
+		called <- struct{}{}
+	})
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), CommandAttempts: 5}
+	statusConn := NewCommandStatusConn(e)
+	statusConn.retryDelay = time.Minute
+	statusConn.setDeadline(time.Now().Add(time.Hour))
+
+	done := make(chan error)
+	go func() {
+		_, _, err := statusConn.MonCommand([]byte(`{"prefix": "df"}`))
+		done <- err
+	}()
+
+	// The retry waiting when the scrape ends gives up rather than sleep
+	// through its delay.
+	<-called
+	statusConn.setDeadline(time.Time{})
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retry outlived the scrape")
+	}
+	conn.AssertNumberOfCalls(t, "MonCommand", 1)
+}
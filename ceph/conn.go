@@ -14,6 +14,8 @@
 
 package ceph
 
+import "context"
+
 // Conn interface implements only necessary methods that are used in this
 // repository on top of *rados.Conn. This keeps rest of the implementation
 // clean and *rados.Conn doesn't need to show up everywhere (it being more of
@@ -22,7 +24,14 @@ package ceph
 type Conn interface {
 	MonCommand([]byte) ([]byte, string, error)
 	MgrCommand([][]byte) ([]byte, string, error)
+
+	// Deprecated: GetPoolStats can't be given up on, use
+	// GetPoolStatsContext instead.
 	GetPoolStats(string) (*PoolStat, error)
+
+	// GetPoolStatsContext retrieves the stats of a pool, giving up once the
+	// context is done.
+	GetPoolStatsContext(context.Context, string) (*PoolStat, error)
 }
 
 // PoolStats contains data for a single pool.
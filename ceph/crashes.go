@@ -17,6 +17,8 @@ package ceph
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -34,24 +36,59 @@ type CrashesCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// now returns the current time, report ages are computed relative to it.
+	now func() time.Time
+
 	crashReportsDesc *prometheus.Desc
+
+	// crashReportsTotalDesc counts the crash reports per daemon type.
+	crashReportsTotalDesc *prometheus.Desc
+	// crashNewReportsTotalDesc counts the crash reports not archived yet per
+	// daemon type.
+	crashNewReportsTotalDesc *prometheus.Desc
+	// crashLastReportAgeDesc gives the time since the most recent crash per
+	// daemon type.
+	crashLastReportAgeDesc *prometheus.Desc
 }
 
 // NewCrashesCollector creates a new CrashesCollector instance
 func NewCrashesCollector(exporter *Exporter) *CrashesCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	collector := &CrashesCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+		now:         time.Now,
 
 		crashReportsDesc: prometheus.NewDesc(
-			fmt.Sprintf("%s_crash_reports", cephNamespace),
+			fmt.Sprintf("%s_crash_reports", namespace),
 			"Count of crashes reports per daemon, according to `ceph crash ls`",
 			[]string{"entity", "hostname", "status"},
 			labels,
 		),
+		crashReportsTotalDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_crash_reports_total", namespace),
+			"Count of crash reports per daemon type, according to `ceph crash ls`",
+			[]string{"daemon_type"},
+			labels,
+		),
+		crashNewReportsTotalDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_crash_new_reports_total", namespace),
+			"Count of crash reports not archived yet per daemon type, according to `ceph crash ls`",
+			[]string{"daemon_type"},
+			labels,
+		),
+		crashLastReportAgeDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_crash_last_report_age_seconds", namespace),
+			"Time since the most recent crash per daemon type, according to `ceph crash ls`",
+			[]string{"daemon_type"},
+			labels,
+		),
 	}
 
 	return collector
@@ -64,48 +101,96 @@ type crashEntry struct {
 }
 
 type cephCrashLs struct {
-	Entity   string `json:"entity_name"`
-	Hostname string `json:"utsname_hostname"`
-	Archived string `json:"archived"`
+	Entity    string `json:"entity_name"`
+	Hostname  string `json:"utsname_hostname"`
+	Timestamp string `json:"timestamp"`
+	Archived  string `json:"archived"`
+}
+
+// DaemonType returns the type of the crashed daemon, e.g. osd for osd.3.
+func (c cephCrashLs) DaemonType() string {
+	return strings.SplitN(c.Entity, ".", 2)[0]
+}
+
+// crashTimeLayout is the layout of the crash timestamps, older releases
+// separate the date and time with a space rather than a T.
+const crashTimeLayout = "2006-01-02 15:04:05.999999Z"
+
+// Time returns when the crash happened.
+func (c cephCrashLs) Time() (time.Time, error) {
+	return time.Parse(crashTimeLayout, strings.Replace(c.Timestamp, "T", " ", 1))
+}
+
+// crashTypeStats holds the crash report aggregates of a daemon type.
+type crashTypeStats struct {
+	total  int
+	new    int
+	latest time.Time
 }
 
 // getCrashLs runs the 'ceph crash ls' command and process its results
-func (c *CrashesCollector) getCrashLs() (map[crashEntry]int, error) {
+func (c *CrashesCollector) getCrashLs() (map[crashEntry]int, map[string]*crashTypeStats, error) {
 	crashes := make(map[crashEntry]int)
+	types := make(map[string]*crashTypeStats)
 
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "crash ls",
 		"format": "json",
 	})
 	if err != nil {
-		return crashes, err
+		return crashes, types, err
 	}
 
 	buf, _, err := c.conn.MonCommand(cmd)
 	if err != nil {
-		return crashes, err
+		return crashes, types, err
 	}
 
 	var crashData []cephCrashLs
 	if err = json.Unmarshal(buf, &crashData); err != nil {
-		return crashes, err
+		c.parseErrors.observe("crashes")
+		return crashes, types, err
 	}
 
 	for _, crash := range crashData {
-		crashes[crashEntry{crash.Entity, crash.Hostname, len(crash.Archived) == 0}]++
+		isNew := len(crash.Archived) == 0
+		crashes[crashEntry{crash.Entity, crash.Hostname, isNew}]++
+
+		stats, ok := types[crash.DaemonType()]
+		if !ok {
+			stats = &crashTypeStats{}
+			types[crash.DaemonType()] = stats
+		}
+
+		stats.total++
+		if isNew {
+			stats.new++
+		}
+
+		t, err := crash.Time()
+		if err != nil {
+			c.logger.WithError(err).WithField("entity", crash.Entity).Debug("failed parsing crash timestamp")
+			continue
+		}
+		if t.After(stats.latest) {
+			stats.latest = t
+		}
 	}
 
-	return crashes, nil
+	return crashes, types, nil
 }
 
 // Describe provides the metrics descriptions to Prometheus
 func (c *CrashesCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.crashReportsDesc
+	ch <- c.crashReportsTotalDesc
+	ch <- c.crashNewReportsTotalDesc
+	ch <- c.crashLastReportAgeDesc
 }
 
 // Collect sends all the collected metrics Prometheus.
-func (c *CrashesCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
-	crashes, err := c.getCrashLs()
+func (c *CrashesCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	crashes, types, err := c.getCrashLs()
 	if err != nil {
 		c.logger.WithError(err).Error("failed to run 'ceph crash ls'")
 	}
@@ -120,4 +205,31 @@ func (c *CrashesCollector) Collect(ch chan<- prometheus.Metric, version *Version
 			statusNames[crash.isNew],
 		)
 	}
+
+	now := c.now()
+	for daemonType, stats := range types {
+		ch <- prometheus.MustNewConstMetric(
+			c.crashReportsTotalDesc,
+			prometheus.GaugeValue,
+			float64(stats.total),
+			daemonType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.crashNewReportsTotalDesc,
+			prometheus.GaugeValue,
+			float64(stats.new),
+			daemonType,
+		)
+
+		if !stats.latest.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.crashLastReportAgeDesc,
+				prometheus.GaugeValue,
+				now.Sub(stats.latest).Seconds(),
+				daemonType,
+			)
+		}
+	}
+
+	return err
 }
@@ -20,6 +20,7 @@ import (
 	"net/http/httptest"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -129,6 +130,9 @@ func TestCrashesCollector(t *testing.T) {
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`crash_reports{cluster="ceph",entity="osd.0",hostname="test-ceph-server.company.example",status="new"} 2`),
+				regexp.MustCompile(`ceph_crash_reports_total{cluster="ceph",daemon_type="osd"} 2`),
+				regexp.MustCompile(`ceph_crash_new_reports_total{cluster="ceph",daemon_type="osd"} 2`),
+				regexp.MustCompile(`ceph_crash_last_report_age_seconds{cluster="ceph",daemon_type="osd"} 3600`),
 			},
 		},
 		{
@@ -153,6 +157,9 @@ func TestCrashesCollector(t *testing.T) {
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`crash_reports{cluster="ceph",entity="osd.0",hostname="test-ceph-server.company.example",status="new"} 1`),
 				regexp.MustCompile(`crash_reports{cluster="ceph",entity="osd.0",hostname="test-ceph-server.company.example",status="archived"} 1`),
+				regexp.MustCompile(`ceph_crash_reports_total{cluster="ceph",daemon_type="osd"} 2`),
+				regexp.MustCompile(`ceph_crash_new_reports_total{cluster="ceph",daemon_type="osd"} 1`),
+				regexp.MustCompile(`ceph_crash_last_report_age_seconds{cluster="ceph",daemon_type="osd"} 3600`),
 			},
 		},
 		{
@@ -176,6 +183,26 @@ func TestCrashesCollector(t *testing.T) {
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`crash_reports{cluster="ceph",entity="mgr.mgr-node-01",hostname="test-ceph-server.company.example",status="new"} 1`),
 				regexp.MustCompile(`crash_reports{cluster="ceph",entity="client.admin",hostname="test-ceph-server.company.example",status="new"} 1`),
+				regexp.MustCompile(`ceph_crash_reports_total{cluster="ceph",daemon_type="mgr"} 1`),
+				regexp.MustCompile(`ceph_crash_reports_total{cluster="ceph",daemon_type="client"} 1`),
+				regexp.MustCompile(`ceph_crash_last_report_age_seconds{cluster="ceph",daemon_type="mgr"} 115378.73`),
+			},
+		},
+		{
+			name: "newer timestamp format",
+			input: `
+[
+	{
+		"entity_name": "mds.mds-node-01",
+		"utsname_hostname": "test-ceph-server.company.example",
+		"timestamp": "2022-02-03T05:00:45.419226Z",
+		"crash_id": "2022-02-03T05:00:45.419226Z_5a1e8f36-8f0e-4b2c-9d4e-1f0f4a6f6a10"
+	}
+]`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_crash_new_reports_total{cluster="ceph",daemon_type="mds"} 1`),
+				regexp.MustCompile(`ceph_crash_last_report_age_seconds{cluster="ceph",daemon_type="mds"} 300`),
 			},
 		},
 		{
@@ -195,8 +222,12 @@ func TestCrashesCollector(t *testing.T) {
 				)
 
 				e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+				crashes := NewCrashesCollector(e)
+				crashes.now = func() time.Time {
+					return time.Date(2022, 2, 3, 5, 5, 45, 419226000, time.UTC)
+				}
 				e.cc = map[string]versionedCollector{
-					"crashes": NewCrashesCollector(e),
+					"crashes": crashes,
 				}
 				err := prometheus.Register(e)
 				require.NoError(t, err)
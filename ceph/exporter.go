@@ -15,9 +15,14 @@
 package ceph
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Jeffail/gabs"
 	"github.com/prometheus/client_golang/prometheus"
@@ -25,85 +30,470 @@ import (
 )
 
 type versionedCollector interface {
-	Collect(chan<- prometheus.Metric, *Version)
+	// Collect sends the collected metrics to the channel. It returns an
+	// error if any part of the collection failed, in which case some of
+	// the metrics may be missing or stale.
+	Collect(chan<- prometheus.Metric, *Version) error
 	Describe(chan<- *prometheus.Desc)
 }
 
+// collectErrors gathers the errors of the parts of a collection that run
+// concurrently.
+type collectErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (c *collectErrors) add(err error) {
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+func (c *collectErrors) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Join(c.errs...)
+}
+
 // Exporter wraps all the ceph collectors and provides a single global
 // exporter to extracts metrics out of. It also ensures that the collection
 // is done in a thread-safe manner, the necessary requirement stated by
 // prometheus. It also implements a prometheus.Collector interface in order
 // to register it correctly.
 type Exporter struct {
-	mu        sync.Mutex
-	Conn      Conn
-	Cluster   string
-	Config    string
-	User      string
-	RgwMode   int
-	MDSMode   int
-	RbdMirror bool
-	Logger    *logrus.Logger
-	Version   *Version
-	cc        map[string]versionedCollector
-}
-
-// NewExporter returns an initialized *Exporter
-// We can choose to enable a collector to extract stats out of by adding it to the list of collectors.
-func NewExporter(conn Conn, cluster, config, user string, rgwMode, mdsMode int, logger *logrus.Logger) *Exporter {
-	e := &Exporter{
-		Conn:    conn,
-		Cluster: cluster,
-		Config:  config,
-		User:    user,
-		RgwMode: rgwMode,
-		MDSMode: mdsMode,
-		Logger:  logger,
+	mu             sync.Mutex
+	Conn           Conn
+	Cluster        string
+	Config         string
+	User           string
+	Keyring        string
+	RgwMode        int
+	RgwBucketStats bool
+	RgwUserStats   bool
+	RgwTimeout     time.Duration
+	MDSMode        int
+	RbdMirror      bool
+	PoolFilter     *regexp.Regexp
+	CacheTTL       time.Duration
+	Logger         *logrus.Logger
+	Version        *Version
+	cc             map[string]versionedCollector
+	scrapeTime     *ScrapeTimeCollector
+	parseErrors    *ParseErrorsCollector
+
+	// collectorStatus reports the duration and outcome of each collector.
+	collectorStatus *CollectorStatusCollector
+
+	// cache holds the metrics of the last successful collection, served
+	// instead of collecting again until CacheTTL has elapsed since
+	// cachedAt.
+	cache    []prometheus.Metric
+	cachedAt time.Time
+
+	// OSDConfigOverrideKeys are the config options whose overrides on the
+	// OSDs are counted.
+	OSDConfigOverrideKeys []string
+
+	// CommandAttempts is how many times a mon or mgr command failing
+	// transiently is attempted, retries are only made within ScrapeTimeout
+	// of the start of the scrape.
+	CommandAttempts int
+	ScrapeTimeout   time.Duration
+
+	// OSDLatencyHistograms enables the OSD op latency histograms, read
+	// from every up OSD through the ceph CLI.
+	OSDLatencyHistograms bool
+
+	// PoolOpsRates enables the per second read and write op rates of the
+	// pools, derived from the op counters of consecutive collections.
+	PoolOpsRates bool
+
+	// PoolInconsistentObjects enables listing the inconsistent objects of
+	// the inconsistent PGs through the rados CLI.
+	PoolInconsistentObjects bool
+
+	// OSDHeartbeatPings enables the heartbeat ping times between every
+	// pair of OSDs, as aggregated by the active mgr.
+	OSDHeartbeatPings bool
+
+	// PGPerOSDMin and PGPerOSDMax bound the recommended count of PG
+	// replicas per OSD, zero means 30 and 250.
+	PGPerOSDMin int
+	PGPerOSDMax int
+
+	// MDSHistoricOps enables the durations of the ops in the history of the
+	// active MDS daemons, read through the ceph CLI.
+	MDSHistoricOps bool
+
+	// MDSExemplars attaches the reqid of a blocked op to the MDS blocked ops
+	// samples as an exemplar, only exposed in the OpenMetrics format.
+	MDSExemplars bool
+
+	// RgwOrphansFile is the output of the last rgw-orphan-list run, whose
+	// orphans the RGW collector reports. Empty means none.
+	RgwOrphansFile string
+
+	// RgwPerfCounters enables the request counters the RGW collector reads
+	// from the admin sockets of the radosgw instances on the local host.
+	RgwPerfCounters bool
+
+	// OmitClusterLabel leaves the cluster label out of every metric, for
+	// the single cluster deployments labeling the targets at scrape time.
+	OmitClusterLabel bool
+
+	// Namespace prefixes the name of every metric, empty means ceph.
+	Namespace string
+
+	// FSID is the fsid of the cluster, read once when the exporter is
+	// created. Every metric carries it.
+	FSID string
+
+	// commandStatus retries the transient command failures.
+	commandStatus *CommandStatusConn
+
+	// versionMu protects versionErr.
+	versionMu sync.Mutex
+	// versionErr is the error of the last ceph version call, nil if the
+	// cluster answered it.
+	versionErr error
+
+	// pgDumps shares the PG dump among the collectors of a scrape.
+	pgDumps *pgDumpCache
+
+	// offline is set on the exporters built by NewOfflineExporter.
+	offline bool
+}
+
+// ExporterOptions configure the collectors of an Exporter. The zero value
+// enables the collectors that only need mon and mgr commands, with their
+// defaults.
+type ExporterOptions struct {
+	// Config is the ceph config file and User the ceph user, without the
+	// client. prefix, the CLI and radosgw-admin are run with.
+	Config string
+	User   string
+
+	// Keyring is the keyring of the user for the ceph CLI and
+	// radosgw-admin, empty leaves it to the config to locate it.
+	Keyring string
+
+	// RgwMode enables the RGW collector, in the foreground or background.
+	// RgwBucketStats and RgwUserStats enable its per-bucket and per-user
+	// metrics.
+	RgwMode        int
+	RgwBucketStats bool
+	RgwUserStats   bool
+
+	// RgwTimeout bounds every radosgw-admin command, zero means 60s.
+	RgwTimeout time.Duration
+
+	// RgwOrphansFile is the output of the last rgw-orphan-list run, empty
+	// disables the RGW orphan metrics.
+	RgwOrphansFile string
+
+	// RgwPerfCounters enables the RGW request counters, read through the
+	// admin sockets of the radosgw instances running on the exporter host.
+	RgwPerfCounters bool
+
+	// MDSMode enables the MDS collector, in the foreground or background.
+	// MDSHistoricOps enables its historic op durations and MDSExemplars
+	// the reqid exemplars of its blocked ops.
+	MDSMode        int
+	MDSHistoricOps bool
+	MDSExemplars   bool
+
+	// PoolFilter selects the pools whose usage stats are collected, nil
+	// means all of them.
+	PoolFilter *regexp.Regexp
+
+	// CacheTTL is how long the metrics of a collection are served to the
+	// next scrapes, zero disables the caching.
+	CacheTTL time.Duration
+
+	// OSDConfigOverrideKeys are the config options whose overrides on the
+	// OSDs are counted, none disables the OSD config override metrics.
+	OSDConfigOverrideKeys []string
+
+	// CommandAttempts is how many times a transiently failing command is
+	// attempted, zero means 3. ScrapeTimeout bounds the retries, zero
+	// doesn't.
+	CommandAttempts int
+	ScrapeTimeout   time.Duration
+
+	// OSDLatencyHistograms, PoolOpsRates, PoolInconsistentObjects and
+	// OSDHeartbeatPings enable the corresponding opt-in metrics.
+	OSDLatencyHistograms    bool
+	PoolOpsRates            bool
+	PoolInconsistentObjects bool
+	OSDHeartbeatPings       bool
+
+	// PGPerOSDMin and PGPerOSDMax bound the recommended count of PG
+	// replicas per OSD, zero means 30 and 250.
+	PGPerOSDMin int
+	PGPerOSDMax int
+
+	// OmitClusterLabel leaves the cluster label out of every metric.
+	OmitClusterLabel bool
+
+	// Namespace prefixes the name of every metric, empty means ceph.
+	Namespace string
+}
+
+// newExporter returns an *Exporter for the cluster reached through conn,
+// configured by opts, without its collectors.
+func newExporter(conn Conn, cluster string, opts ExporterOptions, logger *logrus.Logger) *Exporter {
+	return &Exporter{
+		Conn:           conn,
+		Cluster:        cluster,
+		Config:         opts.Config,
+		User:           opts.User,
+		Keyring:        opts.Keyring,
+		RgwMode:        opts.RgwMode,
+		RgwBucketStats: opts.RgwBucketStats,
+		RgwUserStats:   opts.RgwUserStats,
+		RgwTimeout:     opts.RgwTimeout,
+		RgwOrphansFile: opts.RgwOrphansFile,
+		MDSMode:        opts.MDSMode,
+		MDSHistoricOps: opts.MDSHistoricOps,
+		MDSExemplars:   opts.MDSExemplars,
+		PoolFilter:     opts.PoolFilter,
+		CacheTTL:       opts.CacheTTL,
+		Logger:         logger,
+
+		RgwPerfCounters:         opts.RgwPerfCounters,
+		OSDConfigOverrideKeys:   opts.OSDConfigOverrideKeys,
+		CommandAttempts:         opts.CommandAttempts,
+		ScrapeTimeout:           opts.ScrapeTimeout,
+		OSDLatencyHistograms:    opts.OSDLatencyHistograms,
+		PoolOpsRates:            opts.PoolOpsRates,
+		PoolInconsistentObjects: opts.PoolInconsistentObjects,
+		OSDHeartbeatPings:       opts.OSDHeartbeatPings,
+		PGPerOSDMin:             opts.PGPerOSDMin,
+		PGPerOSDMax:             opts.PGPerOSDMax,
+		OmitClusterLabel:        opts.OmitClusterLabel,
+		Namespace:               opts.Namespace,
 	}
+}
+
+// NewExporter returns an initialized *Exporter exporting the cluster reached
+// through conn, with the collectors enabled by opts. It returns nil if the
+// version or the fsid of the cluster can't be read, the collectors would be
+// labeled without the fsid otherwise.
+func NewExporter(conn Conn, cluster string, opts ExporterOptions, logger *logrus.Logger) *Exporter {
+	e := newExporter(conn, cluster, opts, logger)
 	err := e.setCephVersion()
 	if err != nil {
 		e.Logger.WithError(err).Error("failed to set ceph version")
 		return nil
 	}
+	if err := e.setFSID(); err != nil {
+		e.Logger.WithError(err).Error("failed to set ceph fsid")
+		return nil
+	}
 	e.cc = e.initCollectors()
 
 	return e
 }
 
+// errOffline is the error of the commands of the offline exporters.
+var errOffline = errors.New("offline exporter, no cluster to send the command to")
+
+// offlineConn is the Conn of the offline exporters, failing every command.
+type offlineConn struct{}
+
+func (offlineConn) MonCommand([]byte) ([]byte, string, error) {
+	return nil, "", errOffline
+}
+
+func (offlineConn) MgrCommand([][]byte) ([]byte, string, error) {
+	return nil, "", errOffline
+}
+
+func (offlineConn) GetPoolStats(string) (*PoolStat, error) {
+	return nil, errOffline
+}
+
+func (offlineConn) GetPoolStatsContext(context.Context, string) (*PoolStat, error) {
+	return nil, errOffline
+}
+
+// NewOfflineExporter returns an *Exporter with the collectors opts enables
+// on a cluster of the given version, without talking to any cluster. It is
+// meant for DescribeAll and ValidateDescriptors, e.g. in CI, as its scrapes
+// fail. The collectors shelling out to the ceph CLI are included even if the
+// CLI is missing, and so is the rbd-mirror one.
+func NewOfflineExporter(cluster string, version *Version, opts ExporterOptions, logger *logrus.Logger) *Exporter {
+	e := newExporter(offlineConn{}, cluster, opts, logger)
+	e.Version = version
+	e.offline = true
+	e.cc = e.initCollectors()
+	e.cc["rbdMirror"] = NewRbdMirrorStatusCollector(e)
+
+	return e
+}
+
 func (exporter *Exporter) initCollectors() map[string]versionedCollector {
+	// All other collectors talk to the cluster through the latency collector
+	// so that every mon/mgr command they issue gets timed.
+	commandLatency := NewCommandLatencyCollector(exporter)
+	exporter.Conn = commandLatency
+
+	// The scrape time collector isn't part of the collectors map as it has
+	// to be collected after all the others, see Collect.
+	exporter.scrapeTime = NewScrapeTimeCollector(exporter)
+	exporter.Conn = exporter.scrapeTime
+
+	// Failed commands carry the status message of their reply from here on.
+	exporter.commandStatus = NewCommandStatusConn(exporter)
+	exporter.Conn = exporter.commandStatus
+
+	exporter.parseErrors = NewParseErrorsCollector(exporter)
+
+	exporter.pgDumps = newPGDumpCache(exporter)
+
+	// The collector status isn't part of the collectors map either, it
+	// reports on the collectors in it.
+	exporter.collectorStatus = NewCollectorStatusCollector(exporter)
+
 	standardCollectors := map[string]versionedCollector{
-		"clusterUsage":  NewClusterUsageCollector(exporter),
-		"poolUsage":     NewPoolUsageCollector(exporter),
-		"poolInfo":      NewPoolInfoCollector(exporter),
-		"clusterHealth": NewClusterHealthCollector(exporter),
-		"mon":           NewMonitorCollector(exporter),
-		"osd":           NewOSDCollector(exporter),
-		"crashes":       NewCrashesCollector(exporter),
+		"commandLatency": commandLatency,
+		"parseErrors":    exporter.parseErrors,
+		"clusterUsage":   NewClusterUsageCollector(exporter),
+		"poolUsage":      NewPoolUsageCollector(exporter),
+		"poolInfo":       NewPoolInfoCollector(exporter),
+		"clusterHealth":  NewClusterHealthCollector(exporter),
+		"mon":            NewMonitorCollector(exporter),
+		"osd":            NewOSDCollector(exporter),
+		"crashes":        NewCrashesCollector(exporter),
+		"versionInfo":    NewVersionInfoCollector(exporter),
+	}
+
+	if exporter.OSDHeartbeatPings {
+		standardCollectors["osdNetwork"] = NewOSDNetworkCollector(exporter)
 	}
 
 	switch exporter.RgwMode {
 	case RGWModeForeground:
-		standardCollectors["rgw"] = NewRGWCollector(exporter, false)
+		standardCollectors["rgw"] = NewRGWCollector(exporter, false, exporter.RgwBucketStats, exporter.RgwUserStats)
 	case RGWModeBackground:
-		standardCollectors["rgw"] = NewRGWCollector(exporter, true)
+		standardCollectors["rgw"] = NewRGWCollector(exporter, true, exporter.RgwBucketStats, exporter.RgwUserStats)
 	case RGWModeDisabled:
 		// nothing to do
 	default:
 		exporter.Logger.WithField("RgwMode", exporter.RgwMode).Warn("RGW collector disabled due to invalid mode")
 	}
 
+	exporter.addCephCLICollectors(standardCollectors)
+
+	return standardCollectors
+}
+
+// addCephCLICollectors adds the enabled collectors shelling out to the ceph
+// CLI. Without the CLI, e.g. on rados-only deployments, they would fail on
+// every scrape, so they're left out with a warning instead; the collectors
+// talking to the cluster through librados don't need it.
+func (exporter *Exporter) addCephCLICollectors(collectors map[string]versionedCollector) {
+	// The offline exporters don't run the collectors, only describe them.
+	var cliErr error
+	if !exporter.offline {
+		cliErr = cephCLIAvailable()
+	}
+
+	if exporter.OSDLatencyHistograms {
+		if cliErr != nil {
+			exporter.Logger.WithError(cliErr).Warn("OSD latency collector disabled, the ceph CLI is missing")
+		} else {
+			collectors["osdLatency"] = NewOSDLatencyCollector(exporter)
+		}
+	}
+
+	if exporter.MDSMode != MDSModeDisabled && cliErr != nil {
+		exporter.Logger.WithError(cliErr).Warn("MDS collector disabled, the ceph CLI is missing")
+		return
+	}
+
 	switch exporter.MDSMode {
 	case MDSModeForeground:
-		standardCollectors["mds"] = NewMDSCollector(exporter, false)
+		collectors["mds"] = NewMDSCollector(exporter, false)
 	case MDSModeBackground:
-		standardCollectors["mds"] = NewMDSCollector(exporter, true)
+		collectors["mds"] = NewMDSCollector(exporter, true)
 	case MDSModeDisabled:
 		// nothing to do
 	default:
 		exporter.Logger.WithField("MDSMode", exporter.MDSMode).Warn("MDS collector disabled due to invalid mode")
 	}
+}
 
-	return standardCollectors
+// sharedPGDumps returns the PG dump cache of the exporter, the exporters
+// whose collectors are built by hand, e.g. in tests, get one on first use.
+func (exporter *Exporter) sharedPGDumps() *pgDumpCache {
+	if exporter.pgDumps == nil {
+		exporter.pgDumps = newPGDumpCache(exporter)
+	}
+
+	return exporter.pgDumps
+}
+
+// constLabels returns the labels every metric of the exporter carries.
+func (exporter *Exporter) constLabels() prometheus.Labels {
+	labels := make(prometheus.Labels)
+	if !exporter.OmitClusterLabel {
+		labels["cluster"] = exporter.Cluster
+	}
+	if exporter.FSID != "" {
+		labels["fsid"] = exporter.FSID
+	}
+
+	return labels
+}
+
+// namespace returns the prefix of the metric names.
+func (exporter *Exporter) namespace() string {
+	if exporter.Namespace == "" {
+		return cephNamespace
+	}
+
+	return exporter.Namespace
+}
+
+func (exporter *Exporter) cephFSIDCmd() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "fsid",
+		"format": "json",
+	})
+	if err != nil {
+		exporter.Logger.WithError(err).Panic("failed to marshal ceph fsid command")
+	}
+	return cmd
+}
+
+// setFSID reads the fsid of the cluster. It is only read once, as it never
+// changes.
+func (exporter *Exporter) setFSID() error {
+	buf, _, err := exporter.Conn.MonCommand(exporter.cephFSIDCmd())
+	if err != nil {
+		return err
+	}
+
+	cephFSID := &struct {
+		FSID string `json:"fsid"`
+	}{}
+
+	err = json.Unmarshal(buf, cephFSID)
+	if err != nil {
+		return err
+	}
+	if cephFSID.FSID == "" {
+		return errors.New("empty fsid")
+	}
+
+	exporter.FSID = cephFSID.FSID
+	return nil
 }
 
 func (exporter *Exporter) cephVersionCmd() []byte {
@@ -200,7 +590,13 @@ func (exporter *Exporter) setRbdMirror() error {
 	return nil
 }
 
-func (exporter *Exporter) setCephVersion() error {
+func (exporter *Exporter) setCephVersion() (err error) {
+	defer func() {
+		exporter.versionMu.Lock()
+		exporter.versionErr = err
+		exporter.versionMu.Unlock()
+	}()
+
 	buf, _, err := exporter.Conn.MonCommand(exporter.cephVersionCmd())
 	if err != nil {
 		return err
@@ -244,15 +640,131 @@ func (exporter *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	for _, cc := range exporter.cc {
 		cc.Describe(ch)
 	}
+
+	if exporter.scrapeTime != nil {
+		exporter.scrapeTime.Describe(ch)
+	}
+
+	if exporter.collectorStatus != nil {
+		exporter.collectorStatus.Describe(ch)
+	}
+}
+
+// Healthy returns the error of the last ceph version call, which every
+// scrape starts with, nil if the cluster answered it. It doesn't talk to the
+// cluster itself, so it's cheap enough for liveness and readiness probes.
+func (exporter *Exporter) Healthy() error {
+	exporter.versionMu.Lock()
+	defer exporter.versionMu.Unlock()
+
+	return exporter.versionErr
+}
+
+// describeCollectors returns the descriptors of each of the collectors
+// included, by collector name. Unlike Describe it doesn't talk to the cluster.
+func (exporter *Exporter) describeCollectors() map[string][]*prometheus.Desc {
+	collectors := make(map[string]interface {
+		Describe(chan<- *prometheus.Desc)
+	}, len(exporter.cc)+2)
+	for name, cc := range exporter.cc {
+		collectors[name] = cc
+	}
+	if exporter.scrapeTime != nil {
+		collectors["scrapeTime"] = exporter.scrapeTime
+	}
+	if exporter.collectorStatus != nil {
+		collectors["collectorStatus"] = exporter.collectorStatus
+	}
+
+	descs := make(map[string][]*prometheus.Desc, len(collectors))
+	for name, cc := range collectors {
+		ch := make(chan *prometheus.Desc)
+		go func() {
+			cc.Describe(ch)
+			close(ch)
+		}()
+		for desc := range ch {
+			descs[name] = append(descs[name], desc)
+		}
+	}
+
+	return descs
+}
+
+// DescribeAll returns the descriptors of all the collectors included, sorted,
+// without needing a reachable cluster, e.g. to list the metrics the exporter
+// exposes.
+func (exporter *Exporter) DescribeAll() []*prometheus.Desc {
+	var descs []*prometheus.Desc
+	for _, ccDescs := range exporter.describeCollectors() {
+		descs = append(descs, ccDescs...)
+	}
+
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].String() < descs[j].String()
+	})
+
+	return descs
+}
+
+// descCollector is a collector only describing the given descriptors.
+type descCollector []*prometheus.Desc
+
+func (d descCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range d {
+		ch <- desc
+	}
+}
+
+func (d descCollector) Collect(chan<- prometheus.Metric) {}
+
+// ValidateDescriptors checks the descriptors of all the collectors included
+// the way registering them with Prometheus would: it fails on invalid metric
+// or label names, on the same metric described by several collectors and on
+// a metric described with different label names or help strings.
+func (exporter *Exporter) ValidateDescriptors() error {
+	descs := exporter.describeCollectors()
+
+	names := make([]string, 0, len(descs))
+	for name := range descs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registry := prometheus.NewRegistry()
+	for _, name := range names {
+		if err := registry.Register(descCollector(descs[name])); err != nil {
+			return fmt.Errorf("invalid descriptors of the %s collector: %w", name, err)
+		}
+	}
+
+	return nil
 }
 
 // Collect sends the collected metrics from each of the collectors to
 // prometheus. Collect could be called several times concurrently
 // and thus its run is protected by a single mutex.
+//
+// If CacheTTL is set, the metrics of the last successful collection are sent
+// instead as long as it's more recent than CacheTTL, so that scrapes from
+// several Prometheus replicas don't each hit the cluster.
 func (exporter *Exporter) Collect(ch chan<- prometheus.Metric) {
 	exporter.mu.Lock()
 	defer exporter.mu.Unlock()
 
+	if exporter.CacheTTL > 0 && !exporter.cachedAt.IsZero() && time.Since(exporter.cachedAt) < exporter.CacheTTL {
+		for _, metric := range exporter.cache {
+			ch <- metric
+		}
+		return
+	}
+
+	// Don't let the retries of the commands outlive the scrape.
+	if exporter.ScrapeTimeout > 0 {
+		exporter.commandStatus.setDeadline(time.Now().Add(exporter.ScrapeTimeout))
+		defer exporter.commandStatus.setDeadline(time.Time{})
+	}
+
 	err := exporter.setCephVersion()
 	if err != nil {
 		exporter.Logger.WithError(err).Error("failed to set ceph Version")
@@ -265,13 +777,66 @@ func (exporter *Exporter) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	for _, cc := range exporter.cc {
+	if exporter.CacheTTL <= 0 {
+		exporter.collect(ch)
+		return
+	}
+
+	metrics := make(chan prometheus.Metric)
+	var (
+		cache  []prometheus.Metric
+		failed bool
+	)
+	go func() {
+		failed = exporter.collect(metrics) != nil
+		close(metrics)
+	}()
+
+	for metric := range metrics {
+		cache = append(cache, metric)
+		ch <- metric
+	}
+
+	// A partial collection is only served to this scrape, the next one
+	// collects again.
+	if failed {
+		return
+	}
+
+	exporter.cache = cache
+	exporter.cachedAt = time.Now()
+}
+
+// collect runs every collector, it returns the errors of the collectors
+// that failed.
+func (exporter *Exporter) collect(ch chan<- prometheus.Metric) error {
+	// The PG dump is only shared within the scrape, and can be large.
+	defer exporter.pgDumps.reset()
+
+	var (
+		wg   = &sync.WaitGroup{}
+		mu   sync.Mutex
+		errs []error
+	)
+	for name, cc := range exporter.cc {
 		wg.Add(1)
-		go func(cc versionedCollector, wg *sync.WaitGroup) {
-			cc.Collect(ch, exporter.Version)
+		go func(name string, cc versionedCollector, wg *sync.WaitGroup) {
+			start := time.Now()
+			err := cc.Collect(ch, exporter.Version)
+			exporter.collectorStatus.observe(ch, name, time.Since(start), err)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				mu.Unlock()
+			}
 			wg.Done()
-		}(cc, wg)
+		}(name, cc, wg)
 	}
 	wg.Wait()
+
+	if exporter.scrapeTime != nil {
+		exporter.scrapeTime.Collect(ch, exporter.Version)
+	}
+
+	return errors.Join(errs...)
 }
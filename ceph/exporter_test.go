@@ -0,0 +1,284 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporterCache(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		cacheTTL time.Duration
+		dfErr    error
+		dfCalls  int32
+	}{
+		{
+			name:     "disabled",
+			cacheTTL: 0,
+			dfCalls:  2,
+		},
+		{
+			name:     "enabled",
+			cacheTTL: time.Hour,
+			dfCalls:  1,
+		},
+		{
+			// The failed collections aren't cached.
+			name:     "enabled with errors",
+			cacheTTL: time.Hour,
+			dfErr:    errors.New("timed out"),
+			dfCalls:  2,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var dfCalls int32
+
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "df",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return(func([]byte) []byte {
+				atomic.AddInt32(&dfCalls, 1)
+				return []byte(`{"stats": {"total_bytes": 10, "total_used_bytes": 6, "total_avail_bytes": 4}}`)
+			}, "", tt.dfErr)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", CacheTTL: tt.cacheTTL, Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"clusterUsage": NewClusterUsageCollector(e),
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			for i := 0; i < 2; i++ {
+				resp, err := http.Get(server.URL)
+				require.NoError(t, err)
+
+				buf, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoError(t, err)
+
+				if tt.dfErr == nil {
+					require.Contains(t, string(buf), `ceph_cluster_capacity_bytes{cluster="ceph"} 10`)
+				}
+			}
+
+			require.Equal(t, tt.dfCalls, atomic.LoadInt32(&dfCalls))
+		})
+	}
+}
+
+func TestExporterConstLabels(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		fsid        string
+		fsidOK      bool
+		omitCluster bool
+		namespace   string
+		want        string
+	}{
+		{
+			name:   "fsid",
+			fsid:   `{"fsid":"d8a3d4d2-7f3e-11ee-9c2a-0242ac120002"}`,
+			fsidOK: true,
+			want:   `ceph_cluster_capacity_bytes{cluster="ceph",fsid="d8a3d4d2-7f3e-11ee-9c2a-0242ac120002"} 10`,
+		},
+		{
+			name:   "empty fsid",
+			fsid:   `{}`,
+			fsidOK: false,
+			want:   `ceph_cluster_capacity_bytes{cluster="ceph"} 10`,
+		},
+		{
+			name:        "fsid without cluster",
+			fsid:        `{"fsid":"d8a3d4d2-7f3e-11ee-9c2a-0242ac120002"}`,
+			fsidOK:      true,
+			omitCluster: true,
+			want:        `ceph_cluster_capacity_bytes{fsid="d8a3d4d2-7f3e-11ee-9c2a-0242ac120002"} 10`,
+		},
+		{
+			name:        "no const labels",
+			fsid:        `{`,
+			fsidOK:      false,
+			omitCluster: true,
+			want:        "ceph_cluster_capacity_bytes 10",
+		},
+		{
+			name:      "namespace",
+			fsid:      `{`,
+			fsidOK:    false,
+			namespace: "acme_ceph",
+			want:      `acme_ceph_cluster_capacity_bytes{cluster="ceph"} 10`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "fsid",
+					"format": "json",
+				})
+			})).Return([]byte(tt.fsid), "", nil)
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "df",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return([]byte(`{"stats": {"total_bytes": 10, "total_used_bytes": 6, "total_avail_bytes": 4}}`), "", nil)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), OmitClusterLabel: tt.omitCluster, Namespace: tt.namespace}
+			err := e.setFSID()
+			require.Equal(t, tt.fsidOK, err == nil)
+
+			e.cc = map[string]versionedCollector{
+				"clusterUsage": NewClusterUsageCollector(e),
+			}
+
+			// The default registry rejects the same metrics with other labels.
+			reg := prometheus.NewRegistry()
+			err = reg.Register(e)
+			require.NoError(t, err)
+
+			server := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Contains(t, string(buf), tt.want)
+		})
+	}
+}
+
+func TestNewExporterFSIDError(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.Anything).Return(nil, "", errors.New("timed out"))
+
+	// The collectors aren't labeled with the fsid unless it's read first.
+	require.Nil(t, NewExporter(conn, "ceph", ExporterOptions{}, logrus.New()))
+}
+
+func TestExporterCephCLIMissing(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		cliErr  error
+		present bool
+	}{
+		{
+			name:    "ceph cli",
+			present: true,
+		},
+		{
+			name:    "no ceph cli",
+			cliErr:  os.ErrNotExist,
+			present: false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(f func() error) { cephCLIAvailable = f }(cephCLIAvailable)
+			cephCLIAvailable = func() error { return tt.cliErr }
+
+			e := &Exporter{
+				Cluster:              "ceph",
+				MDSMode:              MDSModeForeground,
+				OSDLatencyHistograms: true,
+				Logger:               logrus.New(),
+			}
+			cc := make(map[string]versionedCollector)
+			e.addCephCLICollectors(cc)
+
+			_, ok := cc["mds"]
+			require.Equal(t, tt.present, ok)
+			_, ok = cc["osdLatency"]
+			require.Equal(t, tt.present, ok)
+		})
+	}
+}
+
+func TestExporterValidateDescriptors(t *testing.T) {
+	// The collectors shelling out to the CLI are described even without it.
+	defer func(f func() error) { cephCLIAvailable = f }(cephCLIAvailable)
+	cephCLIAvailable = func() error { return errors.New("ceph CLI not found") }
+
+	e := NewOfflineExporter("ceph", Reef, ExporterOptions{
+		RgwMode:              RGWModeForeground,
+		MDSMode:              MDSModeForeground,
+		OSDLatencyHistograms: true,
+	}, logrus.New())
+
+	require.NoError(t, e.ValidateDescriptors())
+
+	var names []string
+	for _, desc := range e.DescribeAll() {
+		names = append(names, desc.String())
+	}
+	for _, want := range []string{`"ceph_osd_up"`, `"ceph_mds_daemon_state"`, `"ceph_rgw_op_total"`, `"ceph_exporter_cli_time_seconds"`} {
+		found := false
+		for _, name := range names {
+			if strings.Contains(name, want) {
+				found = true
+			}
+		}
+		require.True(t, found, "missing %s", want)
+	}
+
+	// The same metric described by two collectors is caught.
+	e.cc["clusterUsageCopy"] = NewClusterUsageCollector(e)
+	require.Error(t, e.ValidateDescriptors())
+}
@@ -51,6 +51,12 @@ type ClusterHealthCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// pgDumps shares the PG dump with the other collectors of the scrape.
+	pgDumps *pgDumpCache
+
 	// healthChecksMap stores warnings and their criticality
 	healthChecksMap map[string]int
 
@@ -68,9 +74,16 @@ type ClusterHealthCollector struct {
 	// TotalPGs shows the total no. of PGs the cluster constitutes of.
 	TotalPGs *prometheus.Desc
 
+	// TotalPools shows the total no. of pools in the cluster.
+	TotalPools *prometheus.Desc
+
 	// PGstate contains state of all PGs labelled with the name of states.
 	PGState *prometheus.Desc
 
+	// PGStateCount contains the no. of PGs in each compound state, e.g.
+	// active+clean or active+undersized+degraded.
+	PGStateCount *prometheus.Desc
+
 	// ActivePGs shows the no. of PGs the cluster is actively serving data
 	// from.
 	ActivePGs *prometheus.Desc
@@ -112,6 +125,10 @@ type ClusterHealthCollector struct {
 	// that need to be communicated to the remaining peers.
 	PeeringPGs *prometheus.Desc
 
+	// ActivatingPGs depicts no. of PGs that are done peering and wait for all
+	// the replicas to persist the result before going active.
+	ActivatingPGs *prometheus.Desc
+
 	// ScrubbingPGs depicts no. of PGs that are in scrubbing state.
 	// Light scrubbing checks the object size and attributes.
 	ScrubbingPGs *prometheus.Desc
@@ -169,6 +186,13 @@ type ClusterHealthCollector struct {
 	// This includes object replicas in its count.
 	DegradedObjectsCount *prometheus.Desc
 
+	// DegradedObjectsWeighted gives the no. of degraded objects summed across
+	// all PGs according to the PG dump, a degraded PG weighs as much as its
+	// degraded objects. DegradedObjectsCount is the same count as summarized
+	// in the pgmap of the cluster status instead, which the mons only refresh
+	// from the mgr digest and leave out when nothing is degraded.
+	DegradedObjectsWeighted *prometheus.Desc
+
 	// MisplacedObjectsCount gives the no. of RADOS objects that constitute the misplaced PGs.
 	// Misplaced PGs usually represent the PGs that are not in the storage locations that
 	// they should be in. This is different than degraded PGs which means a PG has fewer copies
@@ -179,12 +203,24 @@ type ClusterHealthCollector struct {
 	// MisplacedRatio shows the ratio of misplaced objects to total objects
 	MisplacedRatio *prometheus.Desc
 
+	// DegradedRatio shows the ratio of degraded objects to total objects,
+	// replicas included.
+	DegradedRatio *prometheus.Desc
+
 	// NewCrashReportCount reports if new Ceph daemon crash reports are available
 	NewCrashReportCount *prometheus.Desc
 
 	// TooManyRepairs reports the number of OSDs exceeding mon_osd_warn_num_repaired
 	TooManyRepairs *prometheus.Desc
 
+	// OSDResourceWarning reports the OSDs raising a resource exhaustion health
+	// check, labeled by OSD and by the resource running out
+	OSDResourceWarning *prometheus.Desc
+
+	// HealthCheck reports every active health check, labeled by name,
+	// severity and whether it is muted
+	HealthCheck *prometheus.Desc
+
 	// Objects show the total no. of RADOS objects that are currently allocated
 	Objects *prometheus.Desc
 
@@ -283,12 +319,14 @@ const (
 // NewClusterHealthCollector creates a new instance of ClusterHealthCollector to collect health
 // metrics on.
 func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	collector := &ClusterHealthCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+		pgDumps:     exporter.sharedPGDumps(),
 
 		healthChecksMap: map[string]int{
 			"AUTH_BAD_CAPS":                        2,
@@ -366,56 +404,65 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 			"TOO_FEW_PGS":                          1,
 			"TOO_MANY_PGS":                         1},
 
-		HealthStatus: prometheus.NewDesc(fmt.Sprintf("%s_health_status", cephNamespace), "Health status of Cluster, can vary only between 3 states (err:2, warn:1, ok:0)", nil, labels),
-		//HealthStatusInterpreter: prometheus.NewDesc(fmt.Sprintf("%s_health_status_interp", cephNamespace), "Health status of Cluster, can vary only between 4 states (err:3, critical_warn:2, soft_warn:1, ok:0)", nil, labels),
+		HealthStatus: prometheus.NewDesc(fmt.Sprintf("%s_health_status", namespace), "Health status of Cluster, can vary only between 3 states (err:2, warn:1, ok:0)", nil, labels),
+		//HealthStatusInterpreter: prometheus.NewDesc(fmt.Sprintf("%s_health_status_interp", namespace), "Health status of Cluster, can vary only between 4 states (err:3, critical_warn:2, soft_warn:1, ok:0)", nil, labels),
 		HealthStatusInterpreter: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "health_status_interp",
 				Help:        "Health status of Cluster, can vary only between 4 states (err:3, critical_warn:2, soft_warn:1, ok:0)",
 				ConstLabels: labels,
 			},
 		),
-		MONsDown:          prometheus.NewDesc(fmt.Sprintf("%s_mons_down", cephNamespace), "Count of Mons that are in DOWN state", nil, labels),
-		TotalPGs:          prometheus.NewDesc(fmt.Sprintf("%s_total_pgs", cephNamespace), "Total no. of PGs in the cluster", nil, labels),
-		PGState:           prometheus.NewDesc(fmt.Sprintf("%s_pg_state", cephNamespace), "State of PGs in the cluster", []string{"state"}, labels),
-		ActivePGs:         prometheus.NewDesc(fmt.Sprintf("%s_active_pgs", cephNamespace), "No. of active PGs in the cluster", nil, labels),
-		ScrubbingPGs:      prometheus.NewDesc(fmt.Sprintf("%s_scrubbing_pgs", cephNamespace), "No. of scrubbing PGs in the cluster", nil, labels),
-		DeepScrubbingPGs:  prometheus.NewDesc(fmt.Sprintf("%s_deep_scrubbing_pgs", cephNamespace), "No. of deep scrubbing PGs in the cluster", nil, labels),
-		RecoveringPGs:     prometheus.NewDesc(fmt.Sprintf("%s_recovering_pgs", cephNamespace), "No. of recovering PGs in the cluster", nil, labels),
-		RecoveryWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_recovery_wait_pgs", cephNamespace), "No. of PGs in the cluster with recovery_wait state", nil, labels),
-		BackfillingPGs:    prometheus.NewDesc(fmt.Sprintf("%s_backfilling_pgs", cephNamespace), "No. of backfilling PGs in the cluster", nil, labels),
-		BackfillWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_backfill_wait_pgs", cephNamespace), "No. of PGs in the cluster with backfill_wait state", nil, labels),
-		ForcedRecoveryPGs: prometheus.NewDesc(fmt.Sprintf("%s_forced_recovery_pgs", cephNamespace), "No. of PGs in the cluster with forced_recovery state", nil, labels),
-		ForcedBackfillPGs: prometheus.NewDesc(fmt.Sprintf("%s_forced_backfill_pgs", cephNamespace), "No. of PGs in the cluster with forced_backfill state", nil, labels),
-		DownPGs:           prometheus.NewDesc(fmt.Sprintf("%s_down_pgs", cephNamespace), "No. of PGs in the cluster in down state", nil, labels),
-		IncompletePGs:     prometheus.NewDesc(fmt.Sprintf("%s_incomplete_pgs", cephNamespace), "No. of PGs in the cluster in incomplete state", nil, labels),
-		InconsistentPGs:   prometheus.NewDesc(fmt.Sprintf("%s_inconsistent_pgs", cephNamespace), "No. of PGs in the cluster in inconsistent state", nil, labels),
-		SnaptrimPGs:       prometheus.NewDesc(fmt.Sprintf("%s_snaptrim_pgs", cephNamespace), "No. of snaptrim PGs in the cluster", nil, labels),
-		SnaptrimWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_snaptrim_wait_pgs", cephNamespace), "No. of PGs in the cluster with snaptrim_wait state", nil, labels),
-		RepairingPGs:      prometheus.NewDesc(fmt.Sprintf("%s_repairing_pgs", cephNamespace), "No. of PGs in the cluster with repair state", nil, labels),
+		MONsDown:          prometheus.NewDesc(fmt.Sprintf("%s_mons_down", namespace), "Count of Mons that are in DOWN state", nil, labels),
+		TotalPGs:          prometheus.NewDesc(fmt.Sprintf("%s_total_pgs", namespace), "Total no. of PGs in the cluster", nil, labels),
+		TotalPools:        prometheus.NewDesc(fmt.Sprintf("%s_total_pools", namespace), "Total no. of pools in the cluster", nil, labels),
+		PGState:           prometheus.NewDesc(fmt.Sprintf("%s_pg_state", namespace), "State of PGs in the cluster", []string{"state"}, labels),
+		PGStateCount:      prometheus.NewDesc(fmt.Sprintf("%s_pg_state_count", namespace), "No. of PGs in the cluster in the exact compound state", []string{"state"}, labels),
+		ActivePGs:         prometheus.NewDesc(fmt.Sprintf("%s_active_pgs", namespace), "No. of active PGs in the cluster", nil, labels),
+		ScrubbingPGs:      prometheus.NewDesc(fmt.Sprintf("%s_scrubbing_pgs", namespace), "No. of scrubbing PGs in the cluster", nil, labels),
+		DeepScrubbingPGs:  prometheus.NewDesc(fmt.Sprintf("%s_deep_scrubbing_pgs", namespace), "No. of deep scrubbing PGs in the cluster", nil, labels),
+		RecoveringPGs:     prometheus.NewDesc(fmt.Sprintf("%s_recovering_pgs", namespace), "No. of recovering PGs in the cluster", nil, labels),
+		RecoveryWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_recovery_wait_pgs", namespace), "No. of PGs in the cluster with recovery_wait state", nil, labels),
+		BackfillingPGs:    prometheus.NewDesc(fmt.Sprintf("%s_backfilling_pgs", namespace), "No. of backfilling PGs in the cluster", nil, labels),
+		BackfillWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_backfill_wait_pgs", namespace), "No. of PGs in the cluster with backfill_wait state", nil, labels),
+		ForcedRecoveryPGs: prometheus.NewDesc(fmt.Sprintf("%s_forced_recovery_pgs", namespace), "No. of PGs in the cluster with forced_recovery state", nil, labels),
+		ForcedBackfillPGs: prometheus.NewDesc(fmt.Sprintf("%s_forced_backfill_pgs", namespace), "No. of PGs in the cluster with forced_backfill state", nil, labels),
+		DownPGs:           prometheus.NewDesc(fmt.Sprintf("%s_down_pgs", namespace), "No. of PGs in the cluster in down state", nil, labels),
+		IncompletePGs:     prometheus.NewDesc(fmt.Sprintf("%s_incomplete_pgs", namespace), "No. of PGs in the cluster in incomplete state", nil, labels),
+		InconsistentPGs:   prometheus.NewDesc(fmt.Sprintf("%s_inconsistent_pgs", namespace), "No. of PGs in the cluster in inconsistent state", nil, labels),
+		SnaptrimPGs:       prometheus.NewDesc(fmt.Sprintf("%s_snaptrim_pgs", namespace), "No. of snaptrim PGs in the cluster", nil, labels),
+		SnaptrimWaitPGs:   prometheus.NewDesc(fmt.Sprintf("%s_snaptrim_wait_pgs", namespace), "No. of PGs in the cluster with snaptrim_wait state", nil, labels),
+		RepairingPGs:      prometheus.NewDesc(fmt.Sprintf("%s_repairing_pgs", namespace), "No. of PGs in the cluster with repair state", nil, labels),
 		// with Nautilus, SLOW_OPS has replaced both REQUEST_SLOW and REQUEST_STUCK
 		// therefore slow_requests is deprecated, but for backwards compatibility
 		// the metric name will be kept the same for the time being
-		SlowOps:               prometheus.NewDesc(fmt.Sprintf("%s_slow_requests", cephNamespace), "No. of slow requests/slow ops", nil, labels),
-		DegradedPGs:           prometheus.NewDesc(fmt.Sprintf("%s_degraded_pgs", cephNamespace), "No. of PGs in a degraded state", nil, labels),
-		StuckDegradedPGs:      prometheus.NewDesc(fmt.Sprintf("%s_stuck_degraded_pgs", cephNamespace), "No. of PGs stuck in a degraded state", nil, labels),
-		UncleanPGs:            prometheus.NewDesc(fmt.Sprintf("%s_unclean_pgs", cephNamespace), "No. of PGs in an unclean state", nil, labels),
-		StuckUncleanPGs:       prometheus.NewDesc(fmt.Sprintf("%s_stuck_unclean_pgs", cephNamespace), "No. of PGs stuck in an unclean state", nil, labels),
-		UndersizedPGs:         prometheus.NewDesc(fmt.Sprintf("%s_undersized_pgs", cephNamespace), "No. of undersized PGs in the cluster", nil, labels),
-		StuckUndersizedPGs:    prometheus.NewDesc(fmt.Sprintf("%s_stuck_undersized_pgs", cephNamespace), "No. of stuck undersized PGs in the cluster", nil, labels),
-		StalePGs:              prometheus.NewDesc(fmt.Sprintf("%s_stale_pgs", cephNamespace), "No. of stale PGs in the cluster", nil, labels),
-		StuckStalePGs:         prometheus.NewDesc(fmt.Sprintf("%s_stuck_stale_pgs", cephNamespace), "No. of stuck stale PGs in the cluster", nil, labels),
-		PeeringPGs:            prometheus.NewDesc(fmt.Sprintf("%s_peering_pgs", cephNamespace), "No. of peering PGs in the cluster", nil, labels),
-		DegradedObjectsCount:  prometheus.NewDesc(fmt.Sprintf("%s_degraded_objects", cephNamespace), "No. of degraded objects across all PGs, includes replicas", nil, labels),
-		MisplacedObjectsCount: prometheus.NewDesc(fmt.Sprintf("%s_misplaced_objects", cephNamespace), "No. of misplaced objects across all PGs, includes replicas", nil, labels),
-		MisplacedRatio:        prometheus.NewDesc(fmt.Sprintf("%s_misplaced_ratio", cephNamespace), "ratio of misplaced objects to total objects", nil, labels),
-		NewCrashReportCount:   prometheus.NewDesc(fmt.Sprintf("%s_new_crash_reports", cephNamespace), "Number of new crash reports available", nil, labels),
-		TooManyRepairs:        prometheus.NewDesc(fmt.Sprintf("%s_osds_too_many_repair", cephNamespace), "Number of OSDs with too many repaired reads", nil, labels),
-		Objects:               prometheus.NewDesc(fmt.Sprintf("%s_cluster_objects", cephNamespace), "No. of rados objects within the cluster", nil, labels),
+		SlowOps:               prometheus.NewDesc(fmt.Sprintf("%s_slow_requests", namespace), "No. of slow requests/slow ops", nil, labels),
+		DegradedPGs:           prometheus.NewDesc(fmt.Sprintf("%s_degraded_pgs", namespace), "No. of PGs in a degraded state", nil, labels),
+		StuckDegradedPGs:      prometheus.NewDesc(fmt.Sprintf("%s_stuck_degraded_pgs", namespace), "No. of PGs stuck in a degraded state", nil, labels),
+		UncleanPGs:            prometheus.NewDesc(fmt.Sprintf("%s_unclean_pgs", namespace), "No. of PGs in an unclean state", nil, labels),
+		StuckUncleanPGs:       prometheus.NewDesc(fmt.Sprintf("%s_stuck_unclean_pgs", namespace), "No. of PGs stuck in an unclean state", nil, labels),
+		UndersizedPGs:         prometheus.NewDesc(fmt.Sprintf("%s_undersized_pgs", namespace), "No. of undersized PGs in the cluster", nil, labels),
+		StuckUndersizedPGs:    prometheus.NewDesc(fmt.Sprintf("%s_stuck_undersized_pgs", namespace), "No. of stuck undersized PGs in the cluster", nil, labels),
+		StalePGs:              prometheus.NewDesc(fmt.Sprintf("%s_stale_pgs", namespace), "No. of stale PGs in the cluster", nil, labels),
+		StuckStalePGs:         prometheus.NewDesc(fmt.Sprintf("%s_stuck_stale_pgs", namespace), "No. of stuck stale PGs in the cluster", nil, labels),
+		PeeringPGs:            prometheus.NewDesc(fmt.Sprintf("%s_peering_pgs", namespace), "No. of peering PGs in the cluster", nil, labels),
+		ActivatingPGs:         prometheus.NewDesc(fmt.Sprintf("%s_activating_pgs", namespace), "No. of activating PGs in the cluster", nil, labels),
+		DegradedObjectsCount:  prometheus.NewDesc(fmt.Sprintf("%s_degraded_objects", namespace), "No. of degraded objects across all PGs, includes replicas", nil, labels),
+		MisplacedObjectsCount: prometheus.NewDesc(fmt.Sprintf("%s_misplaced_objects", namespace), "No. of misplaced objects across all PGs, includes replicas", nil, labels),
+		MisplacedRatio:        prometheus.NewDesc(fmt.Sprintf("%s_misplaced_ratio", namespace), "ratio of misplaced objects to total objects", nil, labels),
+		DegradedRatio:         prometheus.NewDesc(fmt.Sprintf("%s_degraded_ratio", namespace), "ratio of degraded objects to total objects, includes replicas", nil, labels),
+		NewCrashReportCount:   prometheus.NewDesc(fmt.Sprintf("%s_new_crash_reports", namespace), "Number of new crash reports available", nil, labels),
+		TooManyRepairs:        prometheus.NewDesc(fmt.Sprintf("%s_osds_too_many_repair", namespace), "Number of OSDs with too many repaired reads", nil, labels),
+		OSDResourceWarning:    prometheus.NewDesc(fmt.Sprintf("%s_osd_resource_warning", namespace), "OSD raising a resource exhaustion health check", []string{"osd", "resource"}, labels),
+		HealthCheck:           prometheus.NewDesc(fmt.Sprintf("%s_health_check", namespace), "Active health check, the value is the number of affected entities", []string{"check", "severity", "muted"}, labels),
+		Objects:               prometheus.NewDesc(fmt.Sprintf("%s_cluster_objects", namespace), "No. of rados objects within the cluster", nil, labels),
+		DegradedObjectsWeighted: prometheus.NewDesc(fmt.Sprintf("%s_degraded_objects_weighted", namespace), "No. of degraded objects summed over the PG stats of the PG dump, unlike ceph_degraded_objects which is read from the pgmap summary of the cluster status",
+			nil, labels,
+		),
 		OSDMapFlagFull: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_full",
 				Help:        "The cluster is flagged as full and cannot service writes",
 				ConstLabels: labels,
@@ -423,7 +470,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagPauseRd: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_pauserd",
 				Help:        "Reads are paused",
 				ConstLabels: labels,
@@ -431,7 +478,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagPauseWr: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_pausewr",
 				Help:        "Writes are paused",
 				ConstLabels: labels,
@@ -439,7 +486,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoUp: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_noup",
 				Help:        "OSDs are not allowed to start",
 				ConstLabels: labels,
@@ -447,7 +494,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoDown: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_nodown",
 				Help:        "OSD failure reports are ignored, OSDs will not be marked as down",
 				ConstLabels: labels,
@@ -455,7 +502,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoIn: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_noin",
 				Help:        "OSDs that are out will not be automatically marked in",
 				ConstLabels: labels,
@@ -463,7 +510,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoOut: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_noout",
 				Help:        "OSDs will not be automatically marked out after the configured interval",
 				ConstLabels: labels,
@@ -471,7 +518,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoBackfill: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_nobackfill",
 				Help:        "OSDs will not be backfilled",
 				ConstLabels: labels,
@@ -479,7 +526,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoRecover: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_norecover",
 				Help:        "Recovery is suspended",
 				ConstLabels: labels,
@@ -487,7 +534,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoRebalance: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_norebalance",
 				Help:        "Data rebalancing is suspended",
 				ConstLabels: labels,
@@ -495,7 +542,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoScrub: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_noscrub",
 				Help:        "Scrubbing is disabled",
 				ConstLabels: labels,
@@ -503,7 +550,7 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoDeepScrub: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_nodeep_scrub",
 				Help:        "Deep scrubbing is disabled",
 				ConstLabels: labels,
@@ -511,33 +558,33 @@ func NewClusterHealthCollector(exporter *Exporter) *ClusterHealthCollector {
 		),
 		OSDMapFlagNoTierAgent: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osdmap_flag_notieragent",
 				Help:        "Cache tiering activity is suspended",
 				ConstLabels: labels,
 			},
 		),
 
-		OSDMapFlags:            prometheus.NewDesc(fmt.Sprintf("%s_osd_map_flags", cephNamespace), "A metric for all OSDMap flags", []string{"flag"}, labels),
-		OSDsDown:               prometheus.NewDesc(fmt.Sprintf("%s_osds_down", cephNamespace), "Count of OSDs that are in DOWN state", nil, labels),
-		OSDsUp:                 prometheus.NewDesc(fmt.Sprintf("%s_osds_up", cephNamespace), "Count of OSDs that are in UP state", nil, labels),
-		OSDsIn:                 prometheus.NewDesc(fmt.Sprintf("%s_osds_in", cephNamespace), "Count of OSDs that are in IN state and available to serve requests", nil, labels),
-		OSDsNum:                prometheus.NewDesc(fmt.Sprintf("%s_osds", cephNamespace), "Count of total OSDs in the cluster", nil, labels),
-		RemappedPGs:            prometheus.NewDesc(fmt.Sprintf("%s_pgs_remapped", cephNamespace), "No. of PGs that are remapped and incurring cluster-wide movement", nil, labels),
-		RecoveryIORate:         prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_bytes", cephNamespace), "Rate of bytes being recovered in cluster per second", nil, labels),
-		RecoveryIOKeys:         prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_keys", cephNamespace), "Rate of keys being recovered in cluster per second", nil, labels),
-		RecoveryIOObjects:      prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_objects", cephNamespace), "Rate of objects being recovered in cluster per second", nil, labels),
-		ClientReadBytesPerSec:  prometheus.NewDesc(fmt.Sprintf("%s_client_io_read_bytes", cephNamespace), "Rate of bytes being read by all clients per second", nil, labels),
-		ClientWriteBytesPerSec: prometheus.NewDesc(fmt.Sprintf("%s_client_io_write_bytes", cephNamespace), "Rate of bytes being written by all clients per second", nil, labels),
-		ClientIOOps:            prometheus.NewDesc(fmt.Sprintf("%s_client_io_ops", cephNamespace), "Total client ops on the cluster measured per second", nil, labels),
-		ClientIOReadOps:        prometheus.NewDesc(fmt.Sprintf("%s_client_io_read_ops", cephNamespace), "Total client read I/O ops on the cluster measured per second", nil, labels),
-		ClientIOWriteOps:       prometheus.NewDesc(fmt.Sprintf("%s_client_io_write_ops", cephNamespace), "Total client write I/O ops on the cluster measured per second", nil, labels),
-		CacheFlushIORate:       prometheus.NewDesc(fmt.Sprintf("%s_cache_flush_io_bytes", cephNamespace), "Rate of bytes being flushed from the cache pool per second", nil, labels),
-		CacheEvictIORate:       prometheus.NewDesc(fmt.Sprintf("%s_cache_evict_io_bytes", cephNamespace), "Rate of bytes being evicted from the cache pool per second", nil, labels),
-		CachePromoteIOOps:      prometheus.NewDesc(fmt.Sprintf("%s_cache_promote_io_ops", cephNamespace), "Total cache promote operations measured per second", nil, labels),
-		MgrsActive:             prometheus.NewDesc(fmt.Sprintf("%s_mgrs_active", cephNamespace), "Count of active mgrs, can be either 0 or 1", nil, labels),
-		MgrsNum:                prometheus.NewDesc(fmt.Sprintf("%s_mgrs", cephNamespace), "Total number of mgrs, including standbys", nil, labels),
-		RbdMirrorUp:            prometheus.NewDesc(fmt.Sprintf("%s_rbd_mirror_up", cephNamespace), "Alive rbd-mirror daemons", []string{"name"}, labels),
+		OSDMapFlags:            prometheus.NewDesc(fmt.Sprintf("%s_osd_map_flags", namespace), "A metric for all OSDMap flags", []string{"flag"}, labels),
+		OSDsDown:               prometheus.NewDesc(fmt.Sprintf("%s_osds_down", namespace), "Count of OSDs that are in DOWN state", nil, labels),
+		OSDsUp:                 prometheus.NewDesc(fmt.Sprintf("%s_osds_up", namespace), "Count of OSDs that are in UP state", nil, labels),
+		OSDsIn:                 prometheus.NewDesc(fmt.Sprintf("%s_osds_in", namespace), "Count of OSDs that are in IN state and available to serve requests", nil, labels),
+		OSDsNum:                prometheus.NewDesc(fmt.Sprintf("%s_osds", namespace), "Count of total OSDs in the cluster", nil, labels),
+		RemappedPGs:            prometheus.NewDesc(fmt.Sprintf("%s_pgs_remapped", namespace), "No. of PGs that are remapped and incurring cluster-wide movement", nil, labels),
+		RecoveryIORate:         prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_bytes", namespace), "Rate of bytes being recovered in cluster per second", nil, labels),
+		RecoveryIOKeys:         prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_keys", namespace), "Rate of keys being recovered in cluster per second", nil, labels),
+		RecoveryIOObjects:      prometheus.NewDesc(fmt.Sprintf("%s_recovery_io_objects", namespace), "Rate of objects being recovered in cluster per second", nil, labels),
+		ClientReadBytesPerSec:  prometheus.NewDesc(fmt.Sprintf("%s_client_io_read_bytes", namespace), "Rate of bytes being read by all clients per second", nil, labels),
+		ClientWriteBytesPerSec: prometheus.NewDesc(fmt.Sprintf("%s_client_io_write_bytes", namespace), "Rate of bytes being written by all clients per second", nil, labels),
+		ClientIOOps:            prometheus.NewDesc(fmt.Sprintf("%s_client_io_ops", namespace), "Total client ops on the cluster measured per second", nil, labels),
+		ClientIOReadOps:        prometheus.NewDesc(fmt.Sprintf("%s_client_io_read_ops", namespace), "Total client read I/O ops on the cluster measured per second", nil, labels),
+		ClientIOWriteOps:       prometheus.NewDesc(fmt.Sprintf("%s_client_io_write_ops", namespace), "Total client write I/O ops on the cluster measured per second", nil, labels),
+		CacheFlushIORate:       prometheus.NewDesc(fmt.Sprintf("%s_cache_flush_io_bytes", namespace), "Rate of bytes being flushed from the cache pool per second", nil, labels),
+		CacheEvictIORate:       prometheus.NewDesc(fmt.Sprintf("%s_cache_evict_io_bytes", namespace), "Rate of bytes being evicted from the cache pool per second", nil, labels),
+		CachePromoteIOOps:      prometheus.NewDesc(fmt.Sprintf("%s_cache_promote_io_ops", namespace), "Total cache promote operations measured per second", nil, labels),
+		MgrsActive:             prometheus.NewDesc(fmt.Sprintf("%s_mgrs_active", namespace), "Count of active mgrs, can be either 0 or 1", nil, labels),
+		MgrsNum:                prometheus.NewDesc(fmt.Sprintf("%s_mgrs", namespace), "Total number of mgrs, including standbys", nil, labels),
+		RbdMirrorUp:            prometheus.NewDesc(fmt.Sprintf("%s_rbd_mirror_up", namespace), "Alive rbd-mirror daemons", []string{"name"}, labels),
 	}
 
 	// This is here to support backwards compatibility with gauges, but also exists as a general list of possible flags
@@ -587,6 +634,7 @@ func (c *ClusterHealthCollector) descriptorList() []*prometheus.Desc {
 		c.HealthStatusInterpreter.Desc(),
 		c.MONsDown,
 		c.TotalPGs,
+		c.TotalPools,
 		c.DegradedPGs,
 		c.ActivePGs,
 		c.StuckDegradedPGs,
@@ -597,6 +645,7 @@ func (c *ClusterHealthCollector) descriptorList() []*prometheus.Desc {
 		c.StalePGs,
 		c.StuckStalePGs,
 		c.PeeringPGs,
+		c.ActivatingPGs,
 		c.ScrubbingPGs,
 		c.DeepScrubbingPGs,
 		c.RecoveringPGs,
@@ -613,10 +662,14 @@ func (c *ClusterHealthCollector) descriptorList() []*prometheus.Desc {
 		c.RepairingPGs,
 		c.SlowOps,
 		c.DegradedObjectsCount,
+		c.DegradedObjectsWeighted,
 		c.MisplacedObjectsCount,
 		c.MisplacedRatio,
+		c.DegradedRatio,
 		c.NewCrashReportCount,
 		c.TooManyRepairs,
+		c.OSDResourceWarning,
+		c.HealthCheck,
 		c.Objects,
 		c.OSDMapFlagFull.Desc(),
 		c.OSDMapFlagPauseRd.Desc(),
@@ -650,6 +703,7 @@ func (c *ClusterHealthCollector) descriptorList() []*prometheus.Desc {
 		c.MgrsActive,
 		c.MgrsNum,
 		c.PGState,
+		c.PGStateCount,
 	}
 }
 
@@ -677,6 +731,7 @@ type cephHealthStats struct {
 	OSDMap map[string]interface{} `json:"osdmap"`
 	PGMap  struct {
 		NumPGs                  float64 `json:"num_pgs"`
+		NumPools                float64 `json:"num_pools"`
 		TotalObjects            float64 `json:"num_objects"`
 		WriteOpPerSec           float64 `json:"write_op_per_sec"`
 		ReadOpPerSec            float64 `json:"read_op_per_sec"`
@@ -691,6 +746,7 @@ type cephHealthStats struct {
 		DegradedObjects         float64 `json:"degraded_objects"`
 		MisplacedObjects        float64 `json:"misplaced_objects"`
 		MisplacedRatio          float64 `json:"misplaced_ratio"`
+		DegradedRatio           float64 `json:"degraded_ratio"`
 		PGsByState              []struct {
 			Count  float64 `json:"count"`
 			States string  `json:"state_name"`
@@ -729,6 +785,7 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 
 	stats := &cephHealthStats{}
 	if err := json.Unmarshal(buf, stats); err != nil {
+		c.parseErrors.observe("clusterHealth")
 		return err
 	}
 
@@ -898,6 +955,7 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 		uncleanPGs        float64
 		undersizedPGs     float64
 		peeringPGs        float64
+		activatingPGs     float64
 		stalePGs          float64
 		scrubbingPGs      float64
 		deepScrubbingPGs  float64
@@ -920,6 +978,7 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 			"unclean":         &uncleanPGs,
 			"undersized":      &undersizedPGs,
 			"peering":         &peeringPGs,
+			"activating":      &activatingPGs,
 			"stale":           &stalePGs,
 			"scrubbing":       &scrubbingPGs,
 			"deep_scrubbing":  &deepScrubbingPGs,
@@ -942,6 +1001,7 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 			"unclean":         c.UncleanPGs,
 			"undersized":      c.UndersizedPGs,
 			"peering":         c.PeeringPGs,
+			"activating":      c.ActivatingPGs,
 			"stale":           c.StalePGs,
 			"scrubbing":       c.ScrubbingPGs,
 			"deep_scrubbing":  c.DeepScrubbingPGs,
@@ -961,6 +1021,8 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 	)
 
 	for _, p := range stats.PGMap.PGsByState {
+		ch <- prometheus.MustNewConstMetric(c.PGStateCount, prometheus.GaugeValue, p.Count, p.States)
+
 		p.States = strings.ReplaceAll(p.States, "scrubbing+deep", "deep_scrubbing")
 		stateArray := strings.Split(p.States, "+")
 
@@ -1023,11 +1085,13 @@ func (c *ClusterHealthCollector) collect(ch chan<- prometheus.Metric, version *V
 
 	ch <- prometheus.MustNewConstMetric(c.RemappedPGs, prometheus.GaugeValue, actualOsdMap.NumRemappedPGs)
 	ch <- prometheus.MustNewConstMetric(c.TotalPGs, prometheus.GaugeValue, stats.PGMap.NumPGs)
+	ch <- prometheus.MustNewConstMetric(c.TotalPools, prometheus.GaugeValue, stats.PGMap.NumPools)
 	ch <- prometheus.MustNewConstMetric(c.Objects, prometheus.GaugeValue, stats.PGMap.TotalObjects)
 
 	ch <- prometheus.MustNewConstMetric(c.DegradedObjectsCount, prometheus.GaugeValue, stats.PGMap.DegradedObjects)
 	ch <- prometheus.MustNewConstMetric(c.MisplacedObjectsCount, prometheus.GaugeValue, stats.PGMap.MisplacedObjects)
 	ch <- prometheus.MustNewConstMetric(c.MisplacedRatio, prometheus.GaugeValue, stats.PGMap.MisplacedRatio)
+	ch <- prometheus.MustNewConstMetric(c.DegradedRatio, prometheus.GaugeValue, stats.PGMap.DegradedRatio)
 
 	activeMgr := 0
 	standByMgrs := 0
@@ -1085,6 +1149,141 @@ func (c *ClusterHealthCollector) cephUsageCommand(f format) []byte {
 	return cmd
 }
 
+func (c *ClusterHealthCollector) cephHealthDetailCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "health",
+		"detail": "detail",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		c.logger.WithError(err).Panic("error marshalling ceph health detail")
+	}
+	return cmd
+}
+
+// osdResourceHealthChecks maps the health checks raised by OSDs running out
+// of a resource to the name of that resource.
+var osdResourceHealthChecks = map[string]string{
+	"OSD_FULL":                "disk",
+	"OSD_BACKFILLFULL":        "disk",
+	"OSD_NEARFULL":            "disk",
+	"BLUEFS_SPILLOVER":        "db_device",
+	"BLUEFS_AVAILABLE_SPACE":  "bluefs",
+	"BLUEFS_LOW_SPACE":        "bluefs",
+	"BLUESTORE_FRAGMENTATION": "allocator",
+}
+
+// osdDetailRegex extracts the OSD name from the detail lines of OSD health
+// checks, e.g. "osd.2 is near full".
+var osdDetailRegex = regexp.MustCompile(`\b(?P<osd>osd\.[0-9]+)\b`)
+
+type healthDetailCheck struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Severity string `json:"severity"`
+		Summary  struct {
+			Message string `json:"message"`
+			Count   int    `json:"count"`
+		} `json:"summary"`
+		Detail []struct {
+			Message string `json:"message"`
+		} `json:"detail"`
+		Muted bool `json:"muted"`
+	} `json:"checks"`
+}
+
+// parseHealthDetail parses the output of `ceph health detail`, it is shared
+// by the collectors that look into individual health checks.
+func parseHealthDetail(buf []byte) (*healthDetailCheck, error) {
+	hc := &healthDetailCheck{}
+	if err := json.Unmarshal(buf, hc); err != nil {
+		return nil, err
+	}
+
+	return hc, nil
+}
+
+func (c *ClusterHealthCollector) collectHealthDetail(ch chan<- prometheus.Metric) error {
+	cmd := c.cephHealthDetailCommand()
+	buf, _, err := c.conn.MonCommand(cmd)
+	if err != nil {
+		c.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return err
+	}
+
+	hc, err := parseHealthDetail(buf)
+	if err != nil {
+		c.parseErrors.observe("clusterHealth")
+		return err
+	}
+
+	c.collectHealthChecks(ch, hc)
+	c.collectOSDResourceWarnings(ch, hc)
+
+	return nil
+}
+
+func (c *ClusterHealthCollector) collectHealthChecks(ch chan<- prometheus.Metric, hc *healthDetailCheck) {
+	for name, check := range hc.Checks {
+		// Releases before Octopus don't count the affected entities, fall
+		// back to the number of detail lines.
+		count := check.Summary.Count
+		if count == 0 {
+			count = len(check.Detail)
+		}
+		if count == 0 {
+			count = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.HealthCheck, prometheus.GaugeValue, float64(count), name, check.Severity, strconv.FormatBool(check.Muted))
+	}
+}
+
+func (c *ClusterHealthCollector) collectOSDResourceWarnings(ch chan<- prometheus.Metric, hc *healthDetailCheck) {
+	// An OSD may show up more than once for the same resource, e.g. in both
+	// OSD_NEARFULL and OSD_BACKFILLFULL.
+	warnings := make(map[[2]string]struct{})
+	for name, check := range hc.Checks {
+		resource, ok := osdResourceHealthChecks[name]
+		if !ok {
+			continue
+		}
+
+		for _, detail := range check.Detail {
+			osd, ok := getGroups(*osdDetailRegex, detail.Message)["osd"]
+			if !ok || osd == "" {
+				continue
+			}
+			warnings[[2]string{osd, resource}] = struct{}{}
+		}
+	}
+
+	for w := range warnings {
+		ch <- prometheus.MustNewConstMetric(c.OSDResourceWarning, prometheus.GaugeValue, 1, w[0], w[1])
+	}
+}
+
+// collectDegradedObjectsWeighted sums the degraded objects of the PGs, which
+// tells a degraded PG holding millions of objects from an almost empty one.
+func (c *ClusterHealthCollector) collectDegradedObjectsWeighted(ch chan<- prometheus.Metric) error {
+	pgDump, err := c.pgDumps.get("clusterHealth")
+	if err != nil {
+		return err
+	}
+
+	var degraded float64
+	for _, pg := range pgDump.PGStats {
+		degraded += pg.StatSum.NumObjectsDegraded
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.DegradedObjectsWeighted, prometheus.GaugeValue, degraded)
+
+	return nil
+}
+
 func (c *ClusterHealthCollector) collectRecoveryClientIO(ch chan<- prometheus.Metric) error {
 	cmd := c.cephUsageCommand(plainFormat)
 	buf, _, err := c.conn.MonCommand(cmd)
@@ -1334,8 +1533,9 @@ func (c *ClusterHealthCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect sends all the collected metrics to the provided prometheus channel.
 // It requires the caller to handle synchronization.
-func (c *ClusterHealthCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (c *ClusterHealthCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
 	wg := &sync.WaitGroup{}
+	errs := &collectErrors{}
 
 	wg.Add(1)
 	go func() {
@@ -1344,6 +1544,7 @@ func (c *ClusterHealthCollector) Collect(ch chan<- prometheus.Metric, version *V
 		c.logger.Debug("collecting cluster health metrics")
 		if err := c.collect(ch, version); err != nil {
 			c.logger.WithError(err).Error("error collecting cluster health metrics " + err.Error())
+			errs.add(err)
 		}
 	}()
 
@@ -1354,6 +1555,29 @@ func (c *ClusterHealthCollector) Collect(ch chan<- prometheus.Metric, version *V
 		c.logger.Debug("collecting cluster recovery/client I/O metrics")
 		if err := c.collectRecoveryClientIO(ch); err != nil {
 			c.logger.WithError(err).Error("error collecting cluster recovery/client I/O metrics")
+			errs.add(err)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		c.logger.Debug("collecting health checks")
+		if err := c.collectHealthDetail(ch); err != nil {
+			c.logger.WithError(err).Error("error collecting health checks")
+			errs.add(err)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		c.logger.Debug("collecting weighted degraded objects")
+		if err := c.collectDegradedObjectsWeighted(ch); err != nil {
+			c.logger.WithError(err).Error("error collecting weighted degraded objects")
+			errs.add(err)
 		}
 	}()
 
@@ -1362,4 +1586,6 @@ func (c *ClusterHealthCollector) Collect(ch chan<- prometheus.Metric, version *V
 	for _, metric := range c.collectorsList() {
 		metric.Collect(ch)
 	}
+
+	return errs.err()
 }
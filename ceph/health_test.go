@@ -15,12 +15,14 @@
 package ceph
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
@@ -94,11 +96,28 @@ func TestClusterHealthCollector(t *testing.T) {
 			name: "20 misplaced objects",
 			input: `
 {
-	"pgmap": { "misplaced_objects": 20 }
+	"pgmap": { "misplaced_objects": 20, "misplaced_total": 400, "misplaced_ratio": 0.05 }
 }`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`misplaced_objects{cluster="ceph"} 20`),
+				regexp.MustCompile(`misplaced_ratio{cluster="ceph"} 0.05`),
+			},
+		},
+		{
+			// A healthy cluster leaves the degraded and misplaced stats out
+			// of the pgmap.
+			name: "no degraded or misplaced objects",
+			input: `
+{
+	"pgmap": { "num_pgs": 128, "num_objects": 400 }
+}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_degraded_objects{cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_degraded_ratio{cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_misplaced_objects{cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_misplaced_ratio{cluster="ceph"} 0`),
 			},
 		},
 		{
@@ -221,6 +240,23 @@ func TestClusterHealthCollector(t *testing.T) {
 				regexp.MustCompile(`health_status_interp{cluster="ceph"} 3`),
 			},
 		},
+		{
+			name: "no recovery in progress",
+			input: `
+{
+	"pgmap": {
+		"num_pgs": 52000,
+		"read_bytes_sec": 1024,
+		"write_bytes_sec": 2048
+	}
+}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`recovery_io_bytes{cluster="ceph"} 0\n`),
+				regexp.MustCompile(`recovery_io_keys{cluster="ceph"} 0\n`),
+				regexp.MustCompile(`recovery_io_objects{cluster="ceph"} 0\n`),
+			},
+		},
 		{
 			name: "cluster statistics",
 			input: `
@@ -269,12 +305,13 @@ $ sudo ceph -s
 			name: "pg statistics",
 			input: `
 {
-	"pgmap": { "num_pgs": 52000, "num_objects": 13156 },
+	"pgmap": { "num_pgs": 52000, "num_pools": 12, "num_objects": 13156 },
 	"health": {"summary": [{"severity": "HEALTH_WARN", "summary": "7 pgs undersized"}]}
 }`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`total_pgs{cluster="ceph"} 52000`),
+				regexp.MustCompile(`total_pools{cluster="ceph"} 12`),
 				regexp.MustCompile(`cluster_objects{cluster="ceph"} 13156`),
 			},
 		},
@@ -324,6 +361,9 @@ $ sudo ceph -s
 				regexp.MustCompile(`snaptrim_pgs{cluster="ceph"} 15`),
 				regexp.MustCompile(`snaptrim_wait_pgs{cluster="ceph"} 25`),
 				regexp.MustCompile(`repairing_pgs{cluster="ceph"} 1`),
+				regexp.MustCompile(`ceph_pg_state_count{cluster="ceph",state="active\+clean\+scrubbing"} 2`),
+				regexp.MustCompile(`ceph_pg_state_count{cluster="ceph",state="active\+clean\+scrubbing\+deep"} 5`),
+				regexp.MustCompile(`ceph_pg_state_count{cluster="ceph",state="active\+clean\+snaptrim_wait"} 25`),
 			},
 		},
 		{
@@ -658,6 +698,7 @@ $ sudo ceph -s
 				regexp.MustCompile(`undersized_pgs{cluster="ceph"} 52`),
 				regexp.MustCompile(`stale_pgs{cluster="ceph"} 30`),
 				regexp.MustCompile(`peering_pgs{cluster="ceph"} 10`),
+				regexp.MustCompile(`activating_pgs{cluster="ceph"} 50`),
 				regexp.MustCompile(`scrubbing_pgs{cluster="ceph"} 20`),
 				regexp.MustCompile(`deep_scrubbing_pgs{cluster="ceph"} 10`),
 				regexp.MustCompile(`recovering_pgs{cluster="ceph"} 5`),
@@ -668,6 +709,7 @@ $ sudo ceph -s
 				regexp.MustCompile(`forced_backfill_pgs{cluster="ceph"} 10`),
 				regexp.MustCompile(`down_pgs{cluster="ceph"} 37`),
 				regexp.MustCompile(`incomplete_pgs{cluster="ceph"} 2`),
+				regexp.MustCompile(`ceph_degraded_ratio{cluster="ceph"} 0.213363`),
 				regexp.MustCompile(`recovery_io_bytes{cluster="ceph"} 65536`),
 				regexp.MustCompile(`recovery_io_keys{cluster="ceph"} 25`),
 				regexp.MustCompile(`recovery_io_objects{cluster="ceph"} 140`),
@@ -684,6 +726,7 @@ $ sudo ceph -s
 				regexp.MustCompile(`pg_state{cluster="ceph",state="undersized"} 52`),
 				regexp.MustCompile(`pg_state{cluster="ceph",state="stale"} 30`),
 				regexp.MustCompile(`pg_state{cluster="ceph",state="peering"} 10`),
+				regexp.MustCompile(`pg_state{cluster="ceph",state="activating"} 50`),
 				regexp.MustCompile(`pg_state{cluster="ceph",state="scrubbing"} 20`),
 				regexp.MustCompile(`pg_state{cluster="ceph",state="deep_scrubbing"} 10`),
 				regexp.MustCompile(`pg_state{cluster="ceph",state="recovering"} 5`),
@@ -819,6 +862,10 @@ $ sudo ceph -s
 			conn.On("MonCommand", mock.Anything).Return(
 				[]byte(tt.input), "", nil,
 			)
+			conn.On("MgrCommand", mock.Anything).Return(
+				[]byte(`{"pg_stats": []}`), "", nil,
+			)
+
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
 			e.cc = map[string]versionedCollector{
 				"clusterHealth": NewClusterHealthCollector(e),
@@ -846,3 +893,274 @@ $ sudo ceph -s
 		})
 	}
 }
+
+func TestOSDResourceWarnings(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		input              string
+		version            string
+		reMatch, reUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "resource exhaustion checks",
+			input: `
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"OSD_NEARFULL": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "2 nearfull osd(s)", "count": 2},
+			"detail": [
+				{"message": "osd.2 is near full"},
+				{"message": "osd.7 is near full"}
+			],
+			"muted": false
+		},
+		"OSD_BACKFILLFULL": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 backfillfull osd(s)", "count": 1},
+			"detail": [
+				{"message": "osd.7 is backfill full"}
+			],
+			"muted": false
+		},
+		"BLUEFS_SPILLOVER": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 OSD(s) experiencing BlueFS spillover", "count": 1},
+			"detail": [
+				{"message": "osd.11 spilled over 1.2 GiB metadata from 'db' device (28 GiB used of 30 GiB) to slow device"}
+			],
+			"muted": false
+		},
+		"OSD_DOWN": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 osds down", "count": 1},
+			"detail": [
+				{"message": "osd.3 (root=default,host=node1) is down"}
+			],
+			"muted": false
+		}
+	}
+}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_resource_warning{cluster="ceph",osd="osd.2",resource="disk"} 1`),
+				regexp.MustCompile(`ceph_osd_resource_warning{cluster="ceph",osd="osd.7",resource="disk"} 1`),
+				regexp.MustCompile(`ceph_osd_resource_warning{cluster="ceph",osd="osd.11",resource="db_device"} 1`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_resource_warning{cluster="ceph",osd="osd.3"`),
+			},
+		},
+		{
+			name:    "healthy",
+			input:   `{"status": "HEALTH_OK", "checks": {}}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_resource_warning{`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(tt.version, "{}")
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "health",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return([]byte(tt.input), "", nil)
+			conn.On("MonCommand", mock.Anything).Return(
+				[]byte(`{}`), "", nil,
+			)
+
+			conn.On("MgrCommand", mock.Anything).Return(
+				[]byte(`{"pg_stats": []}`), "", nil,
+			)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"clusterHealth": NewClusterHealthCollector(e),
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		input              string
+		version            string
+		reMatch, reUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "active checks",
+			input: `
+{
+	"status": "HEALTH_ERR",
+	"checks": {
+		"OSD_NEARFULL": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "2 nearfull osd(s)", "count": 2},
+			"detail": [
+				{"message": "osd.2 is near full"},
+				{"message": "osd.7 is near full"}
+			],
+			"muted": false
+		},
+		"PG_DAMAGED": {
+			"severity": "HEALTH_ERR",
+			"summary": {"message": "Possible data damage: 1 pg inconsistent", "count": 1},
+			"detail": [
+				{"message": "pg 2.5 is active+clean+inconsistent, acting [3,1,2]"}
+			],
+			"muted": false
+		},
+		"RECENT_CRASH": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "3 daemons have recently crashed", "count": 3},
+			"detail": [],
+			"muted": true
+		},
+		"MON_CLOCK_SKEW": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "clock skew detected on mon.b, mon.c"},
+			"detail": [
+				{"message": "mon.b clock skew 0.0962s > max 0.05s"},
+				{"message": "mon.c clock skew 0.0707s > max 0.05s"}
+			]
+		}
+	}
+}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_health_check{check="OSD_NEARFULL",cluster="ceph",muted="false",severity="HEALTH_WARN"} 2`),
+				regexp.MustCompile(`ceph_health_check{check="PG_DAMAGED",cluster="ceph",muted="false",severity="HEALTH_ERR"} 1`),
+				regexp.MustCompile(`ceph_health_check{check="RECENT_CRASH",cluster="ceph",muted="true",severity="HEALTH_WARN"} 3`),
+				// Without a count, the detail lines are counted instead.
+				regexp.MustCompile(`ceph_health_check{check="MON_CLOCK_SKEW",cluster="ceph",muted="false",severity="HEALTH_WARN"} 2`),
+				regexp.MustCompile(`ceph_osd_resource_warning{cluster="ceph",osd="osd.2",resource="disk"} 1`),
+			},
+		},
+		{
+			name:    "healthy",
+			input:   `{"status": "HEALTH_OK", "checks": {}}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_health_check{`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(tt.version, "{}")
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "health",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return([]byte(tt.input), "", nil)
+			conn.On("MonCommand", mock.Anything).Return(
+				[]byte(`{}`), "", nil,
+			)
+
+			conn.On("MgrCommand", mock.Anything).Return(
+				[]byte(`{"pg_stats": []}`), "", nil,
+			)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"clusterHealth": NewClusterHealthCollector(e),
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
+
+func TestClusterHealthDegradedObjectsWeighted(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.Anything).Return(
+		[]byte(`{}`), "", nil,
+	)
+	conn.On("MgrCommand", mock.Anything).Return([]byte(`
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "acting": [1, 2, 3], "acting_primary": 1, "stat_sum": {"num_objects": 10, "num_objects_degraded": 0}},
+	{"pgid": "11.1", "state": "active+undersized+degraded", "acting": [1, 2], "acting_primary": 1, "stat_sum": {"num_objects": 4, "num_objects_degraded": 4}},
+	{"pgid": "12.0", "state": "active+undersized+degraded", "acting": [1, 4], "acting_primary": 1, "stat_sum": {"num_objects": 2000000, "num_objects_degraded": 2000000}},
+	{"pgid": "12.1", "state": "active+recovering+degraded", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 1000, "num_objects_degraded": 1500}}
+]}`), "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.cc = map[string]versionedCollector{
+		"clusterHealth": NewClusterHealthCollector(e),
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Regexp(t, regexp.MustCompile(`ceph_degraded_objects_weighted{cluster="ceph"} 2.001504e\+06\n`), string(buf))
+}
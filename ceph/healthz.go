@@ -0,0 +1,43 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// NewHealthzHandler returns a handler answering 200 when the last scrape of
+// every exporter reached its cluster and 503 otherwise, listing the state of
+// each cluster. It only looks at the outcome of the previous scrapes, so
+// probing it never loads the clusters.
+func NewHealthzHandler(exporters ...*Exporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		body := ""
+		for _, exporter := range exporters {
+			state := "ok"
+			if err := exporter.Healthy(); err != nil {
+				state = "unreachable"
+				status = http.StatusServiceUnavailable
+			}
+			body += fmt.Sprintf("%s: %s\n", exporter.Cluster, state)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
@@ -0,0 +1,54 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	healthy := &Exporter{
+		Conn:    setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}"),
+		Cluster: "ceph",
+		Logger:  logrus.New(),
+	}
+
+	unreachable := &MockConn{}
+	unreachable.On("MonCommand", mock.Anything).Return(nil, "", errors.New("timed out"))
+	broken := &Exporter{Conn: unreachable, Cluster: "backup", Logger: logrus.New()}
+
+	healthz := func(exporters ...*Exporter) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		NewHealthzHandler(exporters...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec
+	}
+
+	require.NoError(t, healthy.setCephVersion())
+	rec := healthz(healthy)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ceph: ok\n", rec.Body.String())
+
+	require.Error(t, broken.setCephVersion())
+	rec = healthz(healthy, broken)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "ceph: ok\nbackup: unreachable\n", rec.Body.String())
+}
@@ -0,0 +1,92 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// HTTPAuth restricts the HTTP endpoints of the exporter to the clients
+// presenting either the basic auth credentials or the bearer token, for
+// environments where the metrics can't be exposed unauthenticated. The
+// credentials left empty aren't accepted.
+type HTTPAuth struct {
+	Username    string
+	Password    string
+	BearerToken string
+}
+
+// Validate returns an error when only half of the basic auth credentials
+// is configured: a user without a password would let anyone sending an empty
+// one in, and a password without a user would leave the auth off.
+func (a *HTTPAuth) Validate() error {
+	switch {
+	case a.Username != "" && a.Password == "":
+		return errors.New("basic auth user configured without a password")
+	case a.Username == "" && a.Password != "":
+		return errors.New("basic auth password configured without a user")
+	}
+
+	return nil
+}
+
+// Enabled tells whether any credentials are configured.
+func (a *HTTPAuth) Enabled() bool {
+	return a.Username != "" || a.BearerToken != ""
+}
+
+// Wrap returns a handler serving the requests of the authenticated clients
+// with h and rejecting the others.
+func (a *HTTPAuth) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authenticated(r) {
+			if a.Username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="ceph_exporter"`)
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (a *HTTPAuth) authenticated(r *http.Request) bool {
+	if a.Username != "" && a.Password != "" {
+		if username, password, ok := r.BasicAuth(); ok {
+			return secretEqual(username, a.Username) && secretEqual(password, a.Password)
+		}
+	}
+
+	if a.BearerToken != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			return secretEqual(token, a.BearerToken)
+		}
+	}
+
+	return false
+}
+
+// secretEqual compares the secrets in constant time, hashing them first so
+// that their length doesn't leak either.
+func secretEqual(given, want string) bool {
+	givenSum := sha256.Sum256([]byte(given))
+	wantSum := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(givenSum[:], wantSum[:]) == 1
+}
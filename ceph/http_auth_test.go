@@ -0,0 +1,152 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPAuth(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		auth      HTTPAuth
+		setupReq  func(r *http.Request)
+		status    int
+		challenge bool
+	}{
+		{
+			name:      "basic auth",
+			auth:      HTTPAuth{Username: "prometheus", Password: "s3cret"},
+			setupReq:  func(r *http.Request) { r.SetBasicAuth("prometheus", "s3cret") },
+			status:    http.StatusOK,
+			challenge: false,
+		},
+		{
+			name:      "wrong password",
+			auth:      HTTPAuth{Username: "prometheus", Password: "s3cret"},
+			setupReq:  func(r *http.Request) { r.SetBasicAuth("prometheus", "secret") },
+			status:    http.StatusUnauthorized,
+			challenge: true,
+		},
+		{
+			name:      "no credentials",
+			auth:      HTTPAuth{Username: "prometheus", Password: "s3cret"},
+			setupReq:  func(r *http.Request) {},
+			status:    http.StatusUnauthorized,
+			challenge: true,
+		},
+		{
+			name:     "bearer token",
+			auth:     HTTPAuth{BearerToken: "t0ken"},
+			setupReq: func(r *http.Request) { r.Header.Set("Authorization", "Bearer t0ken") },
+			status:   http.StatusOK,
+		},
+		{
+			name:     "wrong bearer token",
+			auth:     HTTPAuth{BearerToken: "t0ken"},
+			setupReq: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+			status:   http.StatusUnauthorized,
+		},
+		{
+			name:     "basic auth without configured users",
+			auth:     HTTPAuth{BearerToken: "t0ken"},
+			setupReq: func(r *http.Request) { r.SetBasicAuth("", "") },
+			status:   http.StatusUnauthorized,
+		},
+		{
+			name:     "bearer token with basic auth configured too",
+			auth:     HTTPAuth{Username: "prometheus", Password: "s3cret", BearerToken: "t0ken"},
+			setupReq: func(r *http.Request) { r.Header.Set("Authorization", "Bearer t0ken") },
+			status:   http.StatusOK,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, tt.auth.Enabled())
+
+			handler := tt.auth.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			tt.setupReq(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, tt.challenge, rec.Header().Get("WWW-Authenticate") != "")
+		})
+	}
+
+	require.False(t, (&HTTPAuth{}).Enabled())
+}
+
+func TestHTTPAuthValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		auth  HTTPAuth
+		valid bool
+	}{
+		{
+			name:  "basic auth",
+			auth:  HTTPAuth{Username: "prometheus", Password: "s3cret"},
+			valid: true,
+		},
+		{
+			name:  "bearer token",
+			auth:  HTTPAuth{BearerToken: "t0ken"},
+			valid: true,
+		},
+		{
+			name:  "disabled",
+			valid: true,
+		},
+		{
+			name: "user without password",
+			auth: HTTPAuth{Username: "prometheus", BearerToken: "t0ken"},
+		},
+		{
+			name: "password without user",
+			auth: HTTPAuth{Password: "s3cret"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Validate()
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestHTTPAuthEmptyPassword(t *testing.T) {
+	// Even if it slipped through, a user without a password lets nobody in.
+	auth := HTTPAuth{Username: "prometheus", BearerToken: "t0ken"}
+	handler := auth.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.SetBasicAuth("prometheus", "")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -21,23 +21,41 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	cephCmd                      = "/usr/bin/ceph"
 	mdsBackgroundCollectInterval = 5 * time.Minute
+
+	// mdsOldestRequestMaxClients bounds the number of clients the oldest
+	// request age is reported for on each filesystem.
+	mdsOldestRequestMaxClients = 10
 )
 
+// mdsExemplarMaxRunes is the most runes the names and values of the
+// labels of an exemplar may add up to in OpenMetrics.
+const mdsExemplarMaxRunes = 128
+
+// mdsHistoricOpQuantiles are the quantiles of the historic op durations
+// reported for every fs op type.
+var mdsHistoricOpQuantiles = []float64{0.5, 0.9, 0.99}
+
 const (
 	MDSModeDisabled   = 0
 	MDSModeForeground = 1
@@ -46,38 +64,205 @@ const (
 
 type mdsStat struct {
 	FSMap struct {
+		// Standbys are the standby daemons not following any rank, they
+		// have rank -1.
+		Standbys []struct {
+			GID   uint   `json:"gid"`
+			Name  string `json:"name"`
+			Rank  int    `json:"rank"`
+			State string `json:"state"`
+			// JoinFSCID is the filesystem the daemon prefers to take over
+			// a rank of, -1 if none. Older releases don't report it.
+			JoinFSCID *int `json:"join_fscid"`
+		} `json:"standbys"`
 		Filesystems []struct {
+			ID     int `json:"id"`
 			MDSMap struct {
-				FSName string `json:"fs_name"`
-				Info   map[string]struct {
+				FSName       string `json:"fs_name"`
+				MaxMDS       int    `json:"max_mds"`
+				MetadataPool int    `json:"metadata_pool"`
+				DataPools    []int  `json:"data_pools"`
+				Info         map[string]struct {
 					GID   uint   `json:"gid"`
 					Name  string `json:"name"`
 					Rank  int    `json:"rank"`
 					State string `json:"state"`
+					// LaggySince is only set while the MDS is laggy.
+					LaggySince string `json:"laggy_since"`
 				} `json:"info"`
 			} `json:"mdsmap"`
 		} `json:"filesystems"`
 	} `json:"fsmap"`
 }
 
+// cephCLIArgs returns the arguments running the given ceph command as the
+// given user, authenticating with the given keyring unless it's empty.
+func cephCLIArgs(config, user, keyring string, args ...string) []string {
+	cliArgs := []string{"-c", config, "-n", fmt.Sprintf("client.%s", user)}
+	if keyring != "" {
+		cliArgs = append(cliArgs, "--keyring", keyring)
+	}
+
+	return append(cliArgs, args...)
+}
+
+// cephCLIError is the failure of a ceph CLI command, it carries the command
+// and its return code so that they can be logged as structured fields. Its
+// message ends with the error output of the command, which tells why it
+// failed far better than the bare exit status.
+type cephCLIError struct {
+	args       []string
+	returnCode int
+	stderr     string
+	err        error
+}
+
+func (e *cephCLIError) Error() string {
+	if e.stderr == "" {
+		return e.err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", e.err, e.stderr)
+}
+
+func (e *cephCLIError) Unwrap() error {
+	return e.err
+}
+
+// newCephCLIError wraps the error of the ceph CLI run with the given
+// arguments. The return code is -1 if the command didn't run to completion,
+// e.g. because it timed out.
+func newCephCLIError(args []string, err error) *cephCLIError {
+	cliErr := &cephCLIError{args: args, returnCode: -1, err: err}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cliErr.returnCode = exitErr.ExitCode()
+		cliErr.stderr = strings.TrimSpace(string(exitErr.Stderr))
+	}
+
+	return cliErr
+}
+
+// cephCLIAvailable returns an error when the ceph CLI the exec based
+// collectors shell out to is missing, e.g. on rados-only deployments.
+var cephCLIAvailable = func() error {
+	_, err := os.Stat(cephCmd)
+	return err
+}
+
+// runCephCLI runs the ceph CLI with the given arguments.
+func runCephCLI(ctx context.Context, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, cephCmd, args...).Output()
+	if err != nil {
+		return nil, newCephCLIError(args, err)
+	}
+
+	return out, nil
+}
+
+// cephCLIErrorFields returns the command, return code and error output of
+// the ceph CLI command that failed with err, if any.
+func cephCLIErrorFields(err error) logrus.Fields {
+	fields := logrus.Fields{}
+
+	var cliErr *cephCLIError
+	if errors.As(err, &cliErr) {
+		fields["command"] = strings.Join(append([]string{cephCmd}, cliErr.args...), " ")
+		fields["return_code"] = cliErr.returnCode
+		if cliErr.stderr != "" {
+			fields["stderr"] = cliErr.stderr
+		}
+	}
+
+	return fields
+}
+
+// cephCLIUnsupported reports whether err is the reply of a daemon that
+// doesn't know the command it was told to run, which it rejects with
+// EINVAL.
+func cephCLIUnsupported(err error) bool {
+	var cliErr *cephCLIError
+	if errors.As(err, &cliErr) && cliErr.returnCode == int(syscall.EINVAL) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "unrecognized command")
+}
+
 // runMDSStat will run mds stat and get all info from the MDSs within the ceph cluster.
-func runMDSStat(ctx context.Context, config, user string) ([]byte, error) {
-	return exec.CommandContext(ctx, cephCmd, "-c", config, "-n", fmt.Sprintf("client.%s", user), "mds", "stat", "--format", "json").Output()
+func runMDSStat(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "mds", "stat", "--format", "json")...)
 }
 
 // runCephHealthDetail will run health detail and get info specific to MDSs within the ceph cluster.
-func runCephHealthDetail(ctx context.Context, config, user string) ([]byte, error) {
-	return exec.CommandContext(ctx, cephCmd, "-c", config, "-n", fmt.Sprintf("client.%s", user), "health", "detail", "--format", "json").Output()
+func runCephHealthDetail(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "health", "detail", "--format", "json")...)
 }
 
 // runMDSStatus will run status command on the MDS to get it's info.
-func runMDSStatus(ctx context.Context, config, user, mds string) ([]byte, error) {
-	return exec.CommandContext(ctx, cephCmd, "-c", config, "-n", fmt.Sprintf("client.%s", user), "tell", mds, "status").Output()
+func runMDSStatus(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "status")...)
 }
 
 // runBlockedOpsCheck will run blocked ops on MDSs and get any ops that are blocked for that MDS.
-func runBlockedOpsCheck(ctx context.Context, config, user, mds string) ([]byte, error) {
-	return exec.CommandContext(ctx, cephCmd, "-c", config, "-n", fmt.Sprintf("client.%s", user), "tell", mds, "dump_blocked_ops").Output()
+func runBlockedOpsCheck(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "dump_blocked_ops")...)
+}
+
+// runMDSOpsInFlight will dump all the ops in flight on the MDS.
+func runMDSOpsInFlight(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "dump_ops_in_flight")...)
+}
+
+// runMDSMempoolPerfDump will run perf dump on the MDS to get the usage of its memory pools.
+func runMDSMempoolPerfDump(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "perf", "dump", "mempool")...)
+}
+
+// runMDSPerfDump will run perf dump on the MDS to get its request counters.
+func runMDSPerfDump(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "perf", "dump", "mds")...)
+}
+
+// runMDSConfigGet will get the value of the given config option from the MDS.
+func runMDSConfigGet(ctx context.Context, config, user, keyring, mds, option string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "config", "get", option)...)
+}
+
+// runMDSSessionLs will list the client sessions of the MDS.
+func runMDSSessionLs(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "session", "ls")...)
+}
+
+// runOSDBlocklistLs will list the client addresses blocklisted by the OSDs.
+func runOSDBlocklistLs(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "osd", "blocklist", "ls", "--format", "json")...)
+}
+
+// runFSGet will get the MDS map of the given filesystem.
+func runFSGet(ctx context.Context, config, user, keyring, fs string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "fs", "get", fs, "--format", "json")...)
+}
+
+// runCephDF will get the usage of the pools.
+func runCephDF(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "df", "--format", "json")...)
+}
+
+// runMDSDumpInode will dump the given inode from the MDS cache.
+func runMDSDumpInode(ctx context.Context, config, user, keyring, mds, ino string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "dump", "inode", ino)...)
+}
+
+// runMDSHistoricOps will dump the recently completed ops of the MDS.
+func runMDSHistoricOps(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "dump_historic_ops")...)
+}
+
+// runMDSDumpSnaps will dump the snapshots known to the MDS.
+func runMDSDumpSnaps(ctx context.Context, config, user, keyring, mds string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", mds, "dump", "snaps")...)
 }
 
 // MDSCollector collects metrics from the MDS daemons.
@@ -86,7 +271,51 @@ type MDSCollector struct {
 	user       string
 	background bool
 	logger     *logrus.Logger
-	ch         chan prometheus.Metric
+
+	// pending accumulates the metrics of the collection in progress, only
+	// the goroutine running collect touches it.
+	pending []prometheus.Metric
+
+	// metricsMu protects metrics.
+	metricsMu sync.Mutex
+	// metrics holds the metrics of the last completed collection.
+	metrics []prometheus.Metric
+
+	// backgroundOnce starts the background collection on the first scrape.
+	backgroundOnce sync.Once
+
+	// historicOps enables the durations of the historic ops, which takes
+	// one more command per active MDS.
+	historicOps bool
+
+	// exemplars attaches the reqid of a blocked op to the blocked ops
+	// samples.
+	exemplars bool
+
+	// keyring is the keyring the ceph CLI authenticates with, empty means
+	// the one found through the config.
+	keyring string
+
+	// scrapeTime accounts for the time spent running the ceph CLI.
+	scrapeTime *ScrapeTimeCollector
+
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// now returns the current time, state dwell times are measured with it.
+	now func() time.Time
+
+	// statesMu protects states.
+	statesMu sync.Mutex
+	// states tracks the state each MDS daemon was last seen in, keyed by
+	// filesystem and daemon name.
+	states map[mdsKey]*mdsStateTracker
+
+	// forwardsMu protects forwards.
+	forwardsMu sync.Mutex
+	// forwards holds, for each filesystem with a laggy rank, the forwarded
+	// request counters of its MDS daemons when the rank turned laggy.
+	forwards map[string]map[string]float64
 
 	// MDSState reports the state of MDS process running.
 	MDSState *prometheus.Desc
@@ -94,41 +323,310 @@ type MDSCollector struct {
 	// MDSBlockedOPs reports the slow or blocked ops on an MDS.
 	MDSBlockedOps *prometheus.Desc
 
-	runMDSStatFn          func(context.Context, string, string) ([]byte, error)
-	runCephHealthDetailFn func(context.Context, string, string) ([]byte, error)
-	runMDSStatusFn        func(context.Context, string, string, string) ([]byte, error)
-	runBlockedOpsCheckFn  func(context.Context, string, string, string) ([]byte, error)
+	// MDSMaxOpsOnSingleInode reports the highest number of blocked ops
+	// targeting the same inode on an MDS.
+	MDSMaxOpsOnSingleInode *prometheus.Desc
+
+	// MDSInflightOpsByType reports the ops in flight on an active MDS, by
+	// op type.
+	MDSInflightOpsByType *prometheus.Desc
+
+	// MDSClientOldestRequestAge reports the age of the oldest request in
+	// flight of the clients with the oldest requests on a filesystem.
+	MDSClientOldestRequestAge *prometheus.Desc
+
+	// MDSCacheMemoryUsageRatio reports the memory used by the MDS cache
+	// relative to mds_cache_memory_limit.
+	MDSCacheMemoryUsageRatio *prometheus.Desc
+
+	// CephFSBlocklistedClients reports the number of CephFS client sessions
+	// whose address is blocklisted, e.g. after an eviction.
+	CephFSBlocklistedClients *prometheus.Desc
+
+	// MDSRejoinDuration reports how long the MDS spent in the rejoin
+	// state during its last or ongoing recovery.
+	MDSRejoinDuration *prometheus.Desc
+
+	// MDSResolveDuration reports how long the MDS spent in the resolve
+	// state during its last or ongoing recovery.
+	MDSResolveDuration *prometheus.Desc
+
+	// MDSRequestsForwardedToLaggy reports the client requests forwarded by
+	// the MDS daemons of a filesystem while one of its ranks is laggy.
+	MDSRequestsForwardedToLaggy *prometheus.Desc
+
+	// CephFSMaxFileSize reports the largest file the clients may create
+	// on the filesystem.
+	CephFSMaxFileSize *prometheus.Desc
+
+	// CephFSDefaultStripeUnit reports the stripe unit of the root directory
+	// layout, inherited by the files unless a directory overrides it.
+	CephFSDefaultStripeUnit *prometheus.Desc
+
+	// MDSRanksStopping reports the number of ranks of the filesystem being
+	// stopped after max_mds was decreased.
+	MDSRanksStopping *prometheus.Desc
+
+	// MDSMaxMDS reports the number of active ranks the filesystem is
+	// configured with.
+	MDSMaxMDS *prometheus.Desc
+
+	// MDSActiveRanks reports the number of ranks of the filesystem held by
+	// an up:active daemon, fewer than max_mds means a rank failed and no
+	// standby took it over.
+	MDSActiveRanks *prometheus.Desc
+
+	// CephFSSnapshots reports the number of snapshots of the filesystem.
+	CephFSSnapshots *prometheus.Desc
+
+	// CephFSPoolBytesUsed reports the data stored in the metadata and data
+	// pools of each filesystem.
+	CephFSPoolBytesUsed *prometheus.Desc
+
+	// MDSStandbyCount reports the number of standby daemons able to take
+	// over a failed rank of the filesystem.
+	MDSStandbyCount *prometheus.Desc
+
+	// MDSStandbyReplayCount reports the number of standby-replay daemons
+	// following a rank of the filesystem.
+	MDSStandbyReplayCount *prometheus.Desc
+
+	// MDSRankUptime reports how long the active MDS daemon has held its
+	// rank.
+	MDSRankUptime *prometheus.Desc
+
+	// MDSDaemonUptime reports how long the active MDS daemon has been
+	// running.
+	MDSDaemonUptime *prometheus.Desc
+
+	// MDSExports reports the subtrees the active MDS daemon exported to
+	// other ranks.
+	MDSExports *prometheus.Desc
+
+	// MDSExportedInodes reports the inodes of the subtrees the active MDS
+	// daemon exported to other ranks.
+	MDSExportedInodes *prometheus.Desc
+
+	// MDSImports reports the subtrees the active MDS daemon imported from
+	// other ranks.
+	MDSImports *prometheus.Desc
+
+	// MDSImportedInodes reports the inodes of the subtrees the active MDS
+	// daemon imported from other ranks.
+	MDSImportedInodes *prometheus.Desc
+
+	// MDSHistoricOpDuration reports the durations of the client requests
+	// recently completed by the active MDS, by fs op type.
+	MDSHistoricOpDuration *prometheus.Desc
+
+	runMDSStatFn            func(context.Context, string, string, string) ([]byte, error)
+	runCephHealthDetailFn   func(context.Context, string, string, string) ([]byte, error)
+	runMDSStatusFn          func(context.Context, string, string, string, string) ([]byte, error)
+	runBlockedOpsCheckFn    func(context.Context, string, string, string, string) ([]byte, error)
+	runMDSOpsInFlightFn     func(context.Context, string, string, string, string) ([]byte, error)
+	runMDSMempoolPerfDumpFn func(context.Context, string, string, string, string) ([]byte, error)
+	runMDSPerfDumpFn        func(context.Context, string, string, string, string) ([]byte, error)
+	runMDSConfigGetFn       func(context.Context, string, string, string, string, string) ([]byte, error)
+	runMDSSessionLsFn       func(context.Context, string, string, string, string) ([]byte, error)
+	runOSDBlocklistLsFn     func(context.Context, string, string, string) ([]byte, error)
+	runFSGetFn              func(context.Context, string, string, string, string) ([]byte, error)
+	runCephDFFn             func(context.Context, string, string, string) ([]byte, error)
+	runMDSDumpInodeFn       func(context.Context, string, string, string, string, string) ([]byte, error)
+	runMDSDumpSnapsFn       func(context.Context, string, string, string, string) ([]byte, error)
+	runMDSHistoricOpsFn     func(context.Context, string, string, string, string) ([]byte, error)
 }
 
 // NewMDSCollector creates an instance of the MDSCollector and instantiates
 // the individual metrics that we can collect from the MDS daemons.
 func NewMDSCollector(exporter *Exporter, background bool) *MDSCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	mds := &MDSCollector{
-		config:                exporter.Config,
-		user:                  exporter.User,
-		background:            background,
-		logger:                exporter.Logger,
-		ch:                    make(chan prometheus.Metric, 100),
-		runMDSStatFn:          runMDSStat,
-		runCephHealthDetailFn: runCephHealthDetail,
-		runMDSStatusFn:        runMDSStatus,
-		runBlockedOpsCheckFn:  runBlockedOpsCheck,
+		config:                  exporter.Config,
+		user:                    exporter.User,
+		keyring:                 exporter.Keyring,
+		background:              background,
+		historicOps:             exporter.MDSHistoricOps,
+		exemplars:               exporter.MDSExemplars,
+		logger:                  exporter.Logger,
+		scrapeTime:              exporter.scrapeTime,
+		parseErrors:             exporter.parseErrors,
+		now:                     time.Now,
+		states:                  make(map[mdsKey]*mdsStateTracker),
+		forwards:                make(map[string]map[string]float64),
+		runMDSStatFn:            runMDSStat,
+		runCephHealthDetailFn:   runCephHealthDetail,
+		runMDSStatusFn:          runMDSStatus,
+		runBlockedOpsCheckFn:    runBlockedOpsCheck,
+		runMDSOpsInFlightFn:     runMDSOpsInFlight,
+		runMDSMempoolPerfDumpFn: runMDSMempoolPerfDump,
+		runMDSPerfDumpFn:        runMDSPerfDump,
+		runMDSConfigGetFn:       runMDSConfigGet,
+		runMDSSessionLsFn:       runMDSSessionLs,
+		runOSDBlocklistLsFn:     runOSDBlocklistLs,
+		runFSGetFn:              runFSGet,
+		runCephDFFn:             runCephDF,
+		runMDSDumpInodeFn:       runMDSDumpInode,
+		runMDSDumpSnapsFn:       runMDSDumpSnaps,
+		runMDSHistoricOpsFn:     runMDSHistoricOps,
 
 		MDSState: prometheus.NewDesc(
-			fmt.Sprintf("%s_%s", cephNamespace, "mds_daemon_state"),
+			fmt.Sprintf("%s_%s", namespace, "mds_daemon_state"),
 			"MDS Daemon State",
 			[]string{"fs", "name", "rank", "state"},
 			labels,
 		),
 		MDSBlockedOps: prometheus.NewDesc(
-			fmt.Sprintf("%s_%s", cephNamespace, "mds_blocked_ops"),
+			fmt.Sprintf("%s_%s", namespace, "mds_blocked_ops"),
 			"MDS Blocked Ops",
 			[]string{"fs", "name", "state", "optype", "fs_optype", "flag_point", "inode"},
 			labels,
 		),
+		MDSMaxOpsOnSingleInode: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_max_ops_on_single_inode"),
+			"Highest number of blocked ops on the MDS targeting a single inode",
+			[]string{"fs", "name", "inode"},
+			labels,
+		),
+		MDSInflightOpsByType: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_inflight_ops_by_type"),
+			"Ops in flight on the active MDS by op type",
+			[]string{"fs", "name", "optype"},
+			labels,
+		),
+		MDSClientOldestRequestAge: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_client_oldest_request_age_seconds"),
+			"Age of the oldest request in flight of the CephFS client, for the clients with the oldest requests",
+			[]string{"fs", "client"},
+			labels,
+		),
+		MDSCacheMemoryUsageRatio: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_cache_memory_usage_ratio"),
+			"MDS cache memory usage relative to mds_cache_memory_limit",
+			[]string{"fs", "name"},
+			labels,
+		),
+		CephFSBlocklistedClients: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "cephfs_blocklisted_clients"),
+			"CephFS client sessions whose address is blocklisted",
+			[]string{"fs"},
+			labels,
+		),
+		MDSRejoinDuration: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_rejoin_duration_seconds"),
+			"Time spent by the MDS in the rejoin state during its last or ongoing recovery",
+			[]string{"fs", "name"},
+			labels,
+		),
+		MDSResolveDuration: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_resolve_duration_seconds"),
+			"Time spent by the MDS in the resolve state during its last or ongoing recovery",
+			[]string{"fs", "name"},
+			labels,
+		),
+		MDSRequestsForwardedToLaggy: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_requests_forwarded_to_laggy"),
+			"Client requests forwarded by the MDS daemons of the filesystem since one of its ranks turned laggy",
+			[]string{"fs"},
+			labels,
+		),
+		CephFSMaxFileSize: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "cephfs_max_file_size_bytes"),
+			"Maximum size of a file on the CephFS filesystem",
+			[]string{"fs"},
+			labels,
+		),
+		CephFSDefaultStripeUnit: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "cephfs_default_stripe_unit_bytes"),
+			"Stripe unit of the CephFS filesystem root directory layout",
+			[]string{"fs"},
+			labels,
+		),
+		MDSRanksStopping: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_ranks_stopping"),
+			"Number of ranks of the CephFS filesystem in the up:stopping state",
+			[]string{"fs"},
+			labels,
+		),
+		MDSMaxMDS: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_max_mds"),
+			"Number of active ranks the CephFS filesystem is configured with (max_mds)",
+			[]string{"fs"},
+			labels,
+		),
+		MDSActiveRanks: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_active_ranks"),
+			"Number of ranks of the CephFS filesystem held by an up:active MDS daemon",
+			[]string{"fs"},
+			labels,
+		),
+		CephFSSnapshots: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "cephfs_snapshots_total"),
+			"Number of snapshots of the CephFS filesystem",
+			[]string{"fs"},
+			labels,
+		),
+		CephFSPoolBytesUsed: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "cephfs_pool_bytes_used"),
+			"Bytes stored in the metadata and data pools of the CephFS filesystem",
+			[]string{"fs", "pool", "pool_type"},
+			labels,
+		),
+		MDSStandbyCount: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_standby_count"),
+			"Number of standby MDS daemons able to take over a failed rank of the CephFS filesystem",
+			[]string{"fs"},
+			labels,
+		),
+		MDSStandbyReplayCount: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_standby_replay_count"),
+			"Number of standby-replay MDS daemons following a rank of the CephFS filesystem",
+			[]string{"fs"},
+			labels,
+		),
+		MDSRankUptime: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_rank_uptime_seconds"),
+			"Time since the active MDS daemon took over its rank",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSDaemonUptime: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_daemon_uptime_seconds"),
+			"Time since the active MDS daemon started",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSExports: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_exports_total"),
+			"Subtrees the active MDS daemon exported to other ranks since it started",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSExportedInodes: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_exported_inodes_total"),
+			"Inodes of the subtrees the active MDS daemon exported to other ranks since it started",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSImports: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_imports_total"),
+			"Subtrees the active MDS daemon imported from other ranks since it started",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSImportedInodes: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_imported_inodes_total"),
+			"Inodes of the subtrees the active MDS daemon imported from other ranks since it started",
+			[]string{"fs", "name", "rank"},
+			labels,
+		),
+		MDSHistoricOpDuration: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "mds_historic_op_duration_seconds"),
+			"Duration of the client requests in the op history of the active MDS, by fs op type",
+			[]string{"fs", "name", "fs_optype"},
+			labels,
+		),
 	}
 
 	return mds
@@ -141,26 +639,67 @@ func (m *MDSCollector) collectorList() []prometheus.Collector {
 func (m *MDSCollector) descriptorList() []*prometheus.Desc {
 	return []*prometheus.Desc{
 		m.MDSState,
+		m.MDSBlockedOps,
+		m.MDSMaxOpsOnSingleInode,
+		m.MDSInflightOpsByType,
+		m.MDSClientOldestRequestAge,
+		m.MDSCacheMemoryUsageRatio,
+		m.CephFSBlocklistedClients,
+		m.MDSRejoinDuration,
+		m.MDSResolveDuration,
+		m.MDSRequestsForwardedToLaggy,
+		m.CephFSMaxFileSize,
+		m.CephFSDefaultStripeUnit,
+		m.MDSRanksStopping,
+		m.MDSMaxMDS,
+		m.MDSActiveRanks,
+		m.CephFSSnapshots,
+		m.CephFSPoolBytesUsed,
+		m.MDSStandbyCount,
+		m.MDSStandbyReplayCount,
+		m.MDSRankUptime,
+		m.MDSDaemonUptime,
+		m.MDSExports,
+		m.MDSExportedInodes,
+		m.MDSImports,
+		m.MDSImportedInodes,
+		m.MDSHistoricOpDuration,
 	}
 }
 
 func (m *MDSCollector) backgroundCollect() {
-	defer close(m.ch)
 	for {
 		m.logger.WithField("background", m.background).Debug("collecting MDS stats")
 		err := m.collect()
 		if err != nil {
-			m.logger.WithField("background", m.background).WithError(err).Error("error collecting MDS stats")
+			m.logger.WithField("background", m.background).WithFields(cephCLIErrorFields(err)).WithError(err).Error("error collecting MDS stats")
 		}
 		time.Sleep(mdsBackgroundCollectInterval)
 	}
 }
 
+// send adds the metric to the ones of the collection in progress.
+func (m *MDSCollector) send(metric prometheus.Metric) {
+	m.pending = append(m.pending, metric)
+}
+
+// collect runs a collection, the metrics it sends are the ones reported
+// from then on, even if it fails partway.
 func (m *MDSCollector) collect() error {
+	m.pending = nil
+	defer func() {
+		m.metricsMu.Lock()
+		m.metrics = m.pending
+		m.metricsMu.Unlock()
+		m.pending = nil
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
 
-	data, err := m.runMDSStatFn(ctx, m.config, m.user)
+	start := time.Now()
+	data, err := m.runMDSStatFn(ctx, m.config, m.user, m.keyring)
+	m.scrapeTime.observeCLI(start)
 	if err != nil {
 		return fmt.Errorf("failed getting mds stat: %w", err)
 	}
@@ -169,13 +708,49 @@ func (m *MDSCollector) collect() error {
 
 	err = json.Unmarshal(data, ms)
 	if err != nil {
+		m.parseErrors.observe("mds")
 		return fmt.Errorf("failed unmarshalling mds stat json: %w", err)
 	}
 
+	m.trackMDSStates(ms)
+
+	m.collectRequestsForwardedToLaggy(ms)
+
+	// statuses holds the status of the active MDS daemons by daemon name,
+	// for the slow ops collection not to query them again.
+	statuses := make(map[string]*mdsStatus)
+
 	for _, fs := range ms.FSMap.Filesystems {
+		var stopping, standbyReplay float64
+
+		// The standbys not following any rank can take over the ranks of
+		// any filesystem unless they prefer another one.
+		var standby float64
+		for _, info := range ms.FSMap.Standbys {
+			if info.JoinFSCID == nil || *info.JoinFSCID == fsClusterIDNone || *info.JoinFSCID == fs.ID {
+				standby++
+			}
+		}
+
+		// oldestRequests holds the age of the oldest request of each
+		// client across the active ranks of the filesystem.
+		oldestRequests := make(map[string]float64)
+
+		activeRanks := make(map[int]bool)
+
 		for _, info := range fs.MDSMap.Info {
-			select {
-			case m.ch <- prometheus.MustNewConstMetric(
+			switch info.State {
+			case mdsStateActive:
+				activeRanks[info.Rank] = true
+			case mdsStateStopping:
+				stopping++
+			case mdsStateStandby:
+				standby++
+			case mdsStateStandbyReplay:
+				standbyReplay++
+			}
+
+			m.send(prometheus.MustNewConstMetric(
 				m.MDSState,
 				prometheus.GaugeValue,
 				float64(1),
@@ -183,17 +758,398 @@ func (m *MDSCollector) collect() error {
 				info.Name,
 				strconv.Itoa(info.Rank),
 				info.State,
-			):
-			default:
+			))
+
+			m.collectMDSCacheMemoryUsage(fs.MDSMap.FSName, info.Name)
+
+			if info.State == mdsStateActive {
+				m.collectMDSInflightOps(fs.MDSMap.FSName, info.Name, oldestRequests)
+				m.collectMDSUptime(fs.MDSMap.FSName, info.Name, info.Rank, statuses)
+				m.collectMDSMigrations(fs.MDSMap.FSName, info.Name, info.Rank)
+				if m.historicOps {
+					m.collectMDSHistoricOps(fs.MDSMap.FSName, info.Name)
+				}
 			}
 		}
+
+		m.collectClientOldestRequests(fs.MDSMap.FSName, oldestRequests)
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSRanksStopping,
+			prometheus.GaugeValue,
+			stopping,
+			fs.MDSMap.FSName,
+		))
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSMaxMDS,
+			prometheus.GaugeValue,
+			float64(fs.MDSMap.MaxMDS),
+			fs.MDSMap.FSName,
+		))
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSActiveRanks,
+			prometheus.GaugeValue,
+			float64(len(activeRanks)),
+			fs.MDSMap.FSName,
+		))
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSStandbyCount,
+			prometheus.GaugeValue,
+			standby,
+			fs.MDSMap.FSName,
+		))
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSStandbyReplayCount,
+			prometheus.GaugeValue,
+			standbyReplay,
+			fs.MDSMap.FSName,
+		))
 	}
 
-	m.collectMDSSlowOps()
+	m.collectCephFSBlocklistedClients(ms)
+
+	m.collectCephFSLayouts(ms)
+
+	m.collectCephFSSnapshots(ms)
+
+	m.collectCephFSPools(ms)
+
+	m.collectMDSSlowOps(statuses)
 
 	return nil
 }
 
+type mdsKey struct {
+	fs   string
+	name string
+}
+
+// mdsStateTracker records when an MDS daemon entered its current state and
+// how long its last recovery phases lasted.
+type mdsStateTracker struct {
+	state string
+	since time.Time
+
+	// durations holds the duration in seconds of the last completed
+	// recovery phases, keyed by state.
+	durations map[string]float64
+}
+
+const (
+	mdsStateActive  = "up:active"
+	mdsStateResolve = "up:resolve"
+	mdsStateRejoin  = "up:rejoin"
+
+	// mdsStateStopping is the state of the ranks being stopped after
+	// max_mds was decreased.
+	mdsStateStopping = "up:stopping"
+
+	mdsStateStandby       = "up:standby"
+	mdsStateStandbyReplay = "up:standby-replay"
+
+	// fsClusterIDNone is the join_fscid of the standbys without a preferred
+	// filesystem.
+	fsClusterIDNone = -1
+)
+
+// trackMDSStates measures how long the MDS daemons dwell in the resolve and
+// rejoin states of a failover. The states are sampled on every collect,
+// the durations are therefore only as precise as the collect interval.
+func (m *MDSCollector) trackMDSStates(ms *mdsStat) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	now := m.now()
+	seen := make(map[mdsKey]struct{})
+
+	for _, fs := range ms.FSMap.Filesystems {
+		for _, info := range fs.MDSMap.Info {
+			key := mdsKey{fs: fs.MDSMap.FSName, name: info.Name}
+			seen[key] = struct{}{}
+
+			tracker, ok := m.states[key]
+			if !ok {
+				// We don't know when the daemon entered its
+				// current state, assume it just did.
+				tracker = &mdsStateTracker{
+					state:     info.State,
+					since:     now,
+					durations: make(map[string]float64),
+				}
+				m.states[key] = tracker
+			}
+
+			if tracker.state != info.State {
+				switch tracker.state {
+				case mdsStateResolve, mdsStateRejoin:
+					tracker.durations[tracker.state] = now.Sub(tracker.since).Seconds()
+				}
+				tracker.state = info.State
+				tracker.since = now
+			}
+
+			for state, desc := range map[string]*prometheus.Desc{
+				mdsStateResolve: m.MDSResolveDuration,
+				mdsStateRejoin:  m.MDSRejoinDuration,
+			} {
+				duration, ok := tracker.durations[state]
+				if tracker.state == state {
+					duration, ok = now.Sub(tracker.since).Seconds(), true
+				}
+				if !ok {
+					continue
+				}
+
+				m.send(prometheus.MustNewConstMetric(
+					desc,
+					prometheus.GaugeValue,
+					duration,
+					key.fs,
+					key.name,
+				))
+			}
+		}
+	}
+
+	for key := range m.states {
+		if _, ok := seen[key]; !ok {
+			delete(m.states, key)
+		}
+	}
+}
+
+type mdsPerfDump struct {
+	MDS struct {
+		Forward float64 `json:"forward"`
+
+		// The subtree migrations of the MDS balancer.
+		Exported       float64 `json:"exported"`
+		ExportedInodes float64 `json:"exported_inodes"`
+		Imported       float64 `json:"imported"`
+		ImportedInodes float64 `json:"imported_inodes"`
+	} `json:"mds"`
+}
+
+// collectMDSMigrations reports the subtrees the active MDS daemon exported
+// and imported, a steady flow of them on a multi-active filesystem tells the
+// balancer keeps moving them around.
+func (m *MDSCollector) collectMDSMigrations(fsName, name string, rank int) {
+	mdsName := fmt.Sprintf("mds.%s", name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runMDSPerfDumpFn(ctx, m.config, m.user, m.keyring, mdsName)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting perf dump from mds")
+		return
+	}
+
+	pd := &mdsPerfDump{}
+	if err := json.Unmarshal(data, pd); err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds perf dump")
+		return
+	}
+
+	for desc, value := range map[*prometheus.Desc]float64{
+		m.MDSExports:        pd.MDS.Exported,
+		m.MDSExportedInodes: pd.MDS.ExportedInodes,
+		m.MDSImports:        pd.MDS.Imported,
+		m.MDSImportedInodes: pd.MDS.ImportedInodes,
+	} {
+		m.send(prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, fsName, name, strconv.Itoa(rank)))
+	}
+}
+
+// mdsHistoricOps is the op history of an MDS, the ops it completed within
+// mds_op_history_duration, up to mds_op_history_size of them.
+type mdsHistoricOps struct {
+	Ops []struct {
+		Description string  `json:"description"`
+		Duration    float64 `json:"duration"`
+		TypeData    struct {
+			OpType string `json:"op_type"`
+		} `json:"type_data"`
+	} `json:"ops"`
+}
+
+// collectMDSHistoricOps reports the durations of the client requests in the
+// op history of an active MDS as summaries by fs op type, to tell which
+// metadata operations are slow even when they don't get blocked. The count
+// and sum only cover the ops still in the history, they are not cumulative.
+func (m *MDSCollector) collectMDSHistoricOps(fsName, name string) {
+	mdsName := fmt.Sprintf("mds.%s", name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runMDSHistoricOpsFn(ctx, m.config, m.user, m.keyring, mdsName)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting historic ops from mds")
+		return
+	}
+
+	ops := &mdsHistoricOps{}
+	if err := json.Unmarshal(data, ops); err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds historic ops")
+		return
+	}
+
+	durations := make(map[string][]float64)
+	for _, op := range ops.Ops {
+		if op.TypeData.OpType != "client_request" {
+			continue
+		}
+
+		opd, err := extractOpFromDescription(op.Description)
+		if err != nil {
+			m.parseErrors.observe("mds")
+			m.logger.WithField("mds", mdsName).WithError(err).Error("failed parsing historic ops description")
+			continue
+		}
+
+		durations[opd.fsOpType] = append(durations[opd.fsOpType], op.Duration)
+	}
+
+	for fsOpType, ds := range durations {
+		sort.Float64s(ds)
+
+		var sum float64
+		for _, d := range ds {
+			sum += d
+		}
+
+		quantiles := make(map[float64]float64, len(mdsHistoricOpQuantiles))
+		for _, q := range mdsHistoricOpQuantiles {
+			quantiles[q] = nearestRank(ds, q)
+		}
+
+		m.send(prometheus.MustNewConstSummary(
+			m.MDSHistoricOpDuration,
+			uint64(len(ds)),
+			sum,
+			quantiles,
+			fsName,
+			name,
+			fsOpType,
+		))
+	}
+}
+
+// nearestRank returns the q quantile of the sorted values, the smallest
+// value at least a q share of them are lower than or equal to.
+func nearestRank(sorted []float64, q float64) float64 {
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// collectRequestsForwardedToLaggy counts, for each filesystem, the client
+// requests its active MDS daemons forwarded since one of its ranks turned
+// laggy. Requests forwarded to a laggy rank are stuck until it recovers or
+// gets replaced, the count is a good hint of how many of them are.
+func (m *MDSCollector) collectRequestsForwardedToLaggy(ms *mdsStat) {
+	m.forwardsMu.Lock()
+	defer m.forwardsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	seen := make(map[string]struct{})
+
+	for _, fs := range ms.FSMap.Filesystems {
+		fsName := fs.MDSMap.FSName
+		seen[fsName] = struct{}{}
+
+		laggy := false
+		for _, info := range fs.MDSMap.Info {
+			if info.LaggySince != "" {
+				laggy = true
+				break
+			}
+		}
+
+		if !laggy {
+			delete(m.forwards, fsName)
+
+			m.send(prometheus.MustNewConstMetric(
+				m.MDSRequestsForwardedToLaggy,
+				prometheus.GaugeValue,
+				0,
+				fsName,
+			))
+
+			continue
+		}
+
+		baselines, ok := m.forwards[fsName]
+		if !ok {
+			baselines = make(map[string]float64)
+			m.forwards[fsName] = baselines
+		}
+
+		forwarded := float64(0)
+		for _, info := range fs.MDSMap.Info {
+			if info.State != mdsStateActive || info.LaggySince != "" {
+				continue
+			}
+
+			mdsName := fmt.Sprintf("mds.%s", info.Name)
+
+			start := time.Now()
+			data, err := m.runMDSPerfDumpFn(ctx, m.config, m.user, m.keyring, mdsName)
+			m.scrapeTime.observeCLI(start)
+			if err != nil {
+				m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting perf dump from mds")
+				continue
+			}
+
+			pd := &mdsPerfDump{}
+			if err := json.Unmarshal(data, pd); err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds perf dump")
+				continue
+			}
+
+			baseline, ok := baselines[info.Name]
+			if !ok || pd.MDS.Forward < baseline {
+				// First seen since the rank turned laggy, or the
+				// daemon restarted and its counters were reset.
+				baselines[info.Name] = pd.MDS.Forward
+				continue
+			}
+
+			forwarded += pd.MDS.Forward - baseline
+		}
+
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSRequestsForwardedToLaggy,
+			prometheus.GaugeValue,
+			forwarded,
+			fsName,
+		))
+	}
+
+	for fsName := range m.forwards {
+		if _, ok := seen[fsName]; !ok {
+			delete(m.forwards, fsName)
+		}
+	}
+}
+
 // Describe sends the descriptors of each MDSCollector related metrics we have defined
 // to the provided prometheus channel.
 func (m *MDSCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -207,49 +1163,257 @@ func (m *MDSCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 // Collect sends all the collected metrics to the provided prometheus channel.
+// In background mode these are the metrics of the last background collection.
 // It requires the caller to handle synchronization.
-func (m *MDSCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (m *MDSCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	var err error
 	if !m.background {
 		m.logger.WithField("background", m.background).Debug("collecting MDS stats")
-		err := m.collect()
+		err = m.collect()
 		if err != nil {
-			m.logger.WithField("background", m.background).WithError(err).Error("error collecting MDS stats")
+			m.logger.WithField("background", m.background).WithFields(cephCLIErrorFields(err)).WithError(err).Error("error collecting MDS stats")
 		}
 	}
 
 	if m.background {
-		go m.backgroundCollect()
+		m.backgroundOnce.Do(func() {
+			go m.backgroundCollect()
+		})
 	}
 
 	for _, metric := range m.collectorList() {
 		metric.Collect(ch)
 	}
 
-	for {
-		select {
-		case cc, ok := <-m.ch:
-			if ok {
-				ch <- cc
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	for _, metric := range m.metrics {
+		ch <- metric
+	}
+
+	return err
+}
+
+// collectMDSInflightOps counts the ops in flight on an active MDS by op
+// type, e.g. client_request or peer_request, blocked or not. The age of the
+// oldest client request of each client is recorded in oldestRequests.
+func (m *MDSCollector) collectMDSInflightOps(fsName, name string, oldestRequests map[string]float64) {
+	mdsName := fmt.Sprintf("mds.%s", name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runMDSOpsInFlightFn(ctx, m.config, m.user, m.keyring, mdsName)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting ops in flight from mds")
+		return
+	}
+
+	// The ops in flight are dumped in the same format as the blocked ones.
+	ops := &mdsSlowOp{}
+
+	err = json.Unmarshal(data, ops)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds ops in flight")
+		return
+	}
+
+	opTypes := make(map[string]float64)
+	for _, op := range ops.Ops {
+		opTypes[op.TypeData.OpType]++
+
+		if op.TypeData.OpType != "client_request" {
+			continue
+		}
+
+		// Older releases don't dump the client info, the client is only
+		// found in the description then.
+		client := op.TypeData.ClientInfo.Client
+		if client == "" {
+			opd, err := extractOpFromDescription(op.Description)
+			if err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("mds", mdsName).WithError(err).Error("failed parsing ops in flight description")
+				continue
 			}
-		default:
-			return
+			client = fmt.Sprintf("client.%s", opd.clientID)
+		}
+
+		if age, ok := oldestRequests[client]; !ok || op.Age > age {
+			oldestRequests[client] = op.Age
+		}
+	}
+
+	for opType, count := range opTypes {
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSInflightOpsByType,
+			prometheus.GaugeValue,
+			count,
+			fsName,
+			name,
+			opType,
+		))
+	}
+}
+
+// collectClientOldestRequests reports the age of the oldest request of the
+// clients with the oldest requests on the filesystem. Only the first
+// mdsOldestRequestMaxClients are reported, a busy filesystem has thousands
+// of clients with requests in flight and stuck ones stand out anyway.
+func (m *MDSCollector) collectClientOldestRequests(fsName string, oldestRequests map[string]float64) {
+	clients := make([]string, 0, len(oldestRequests))
+	for client := range oldestRequests {
+		clients = append(clients, client)
+	}
+
+	// Break the ties on the client so that the reported clients don't
+	// flap between scrapes.
+	sort.Slice(clients, func(i, j int) bool {
+		if oldestRequests[clients[i]] != oldestRequests[clients[j]] {
+			return oldestRequests[clients[i]] > oldestRequests[clients[j]]
 		}
+		return clients[i] < clients[j]
+	})
+
+	if len(clients) > mdsOldestRequestMaxClients {
+		clients = clients[:mdsOldestRequestMaxClients]
+	}
+
+	for _, client := range clients {
+		m.send(prometheus.MustNewConstMetric(
+			m.MDSClientOldestRequestAge,
+			prometheus.GaugeValue,
+			oldestRequests[client],
+			fsName,
+			client,
+		))
+	}
+}
+
+type mdsMempoolPerfDump struct {
+	Mempool struct {
+		MDSCoBytes float64 `json:"mds_co_bytes"`
+	} `json:"mempool"`
+}
+
+func (m *MDSCollector) collectMDSCacheMemoryUsage(fsName, name string) {
+	mdsName := fmt.Sprintf("mds.%s", name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runMDSMempoolPerfDumpFn(ctx, m.config, m.user, m.keyring, mdsName)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting mempool perf dump from mds")
+		return
+	}
+
+	pd := &mdsMempoolPerfDump{}
+
+	err = json.Unmarshal(data, pd)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds mempool perf dump")
+		return
+	}
+
+	start = time.Now()
+	data, err = m.runMDSConfigGetFn(ctx, m.config, m.user, m.keyring, mdsName, "mds_cache_memory_limit")
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting mds_cache_memory_limit from mds")
+		return
+	}
+
+	// Config values are reported as strings.
+	cfg := map[string]string{}
+
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds_cache_memory_limit")
+		return
+	}
+
+	limit, err := strconv.ParseFloat(cfg["mds_cache_memory_limit"], 64)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithField("mds", mdsName).WithError(err).Error("failed parsing mds_cache_memory_limit")
+		return
+	}
+
+	if limit <= 0 {
+		m.logger.WithField("mds", mdsName).Debug("mds_cache_memory_limit is not set, skipping cache memory usage ratio")
+		return
+	}
+
+	m.send(prometheus.MustNewConstMetric(
+		m.MDSCacheMemoryUsageRatio,
+		prometheus.GaugeValue,
+		pd.Mempool.MDSCoBytes/limit,
+		fsName,
+		name,
+	))
+}
+
+// getMDSStatus returns the status of the MDS daemon.
+func (m *MDSCollector) getMDSStatus(mdsName string) (*mdsStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runMDSStatusFn(ctx, m.config, m.user, m.keyring, mdsName)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		return nil, err
+	}
+
+	mss := &mdsStatus{}
+
+	err = json.Unmarshal(data, mss)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		return nil, fmt.Errorf("failed unmarshalling mds status: %w", err)
 	}
+
+	return mss, nil
 }
 
-type healthDetailCheck struct {
-	Status string `json:"status"`
-	Checks map[string]struct {
-		Severity string `json:"severity"`
-		Summary  struct {
-			Message string `json:"message"`
-			Count   int    `json:"count"`
-		} `json:"summary"`
-		Detail []struct {
-			Message string `json:"message"`
-		} `json:"detail"`
-		Muted bool `json:"muted"`
-	} `json:"checks"`
+// collectMDSUptime reports how long the active MDS daemon has been running
+// and holding its rank, a reset uptime tells the daemon restarted or failed
+// over between two scrapes. The status is recorded in statuses.
+func (m *MDSCollector) collectMDSUptime(fsName, name string, rank int, statuses map[string]*mdsStatus) {
+	mdsName := fmt.Sprintf("mds.%s", name)
+
+	mss, err := m.getMDSStatus(mdsName)
+	if err != nil {
+		m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting status from mds")
+		return
+	}
+	statuses[mdsName] = mss
+
+	m.send(prometheus.MustNewConstMetric(
+		m.MDSRankUptime,
+		prometheus.GaugeValue,
+		mss.RankUptime,
+		fsName,
+		name,
+		strconv.Itoa(rank),
+	))
+
+	m.send(prometheus.MustNewConstMetric(
+		m.MDSDaemonUptime,
+		prometheus.GaugeValue,
+		mss.Uptime,
+		fsName,
+		name,
+		strconv.Itoa(rank),
+	))
 }
 
 type mdsStatus struct {
@@ -315,20 +1479,21 @@ type mdsSlowOp struct {
 	NumBlockedOps int `json:"num_blocked_ops"`
 }
 
-func (m *MDSCollector) collectMDSSlowOps() {
+func (m *MDSCollector) collectMDSSlowOps(statuses map[string]*mdsStatus) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
 
-	data, err := m.runCephHealthDetailFn(ctx, m.config, m.user)
+	start := time.Now()
+	data, err := m.runCephHealthDetailFn(ctx, m.config, m.user, m.keyring)
+	m.scrapeTime.observeCLI(start)
 	if err != nil {
-		m.logger.WithError(err).Error("failed getting health detail")
+		m.logger.WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting health detail")
 		return
 	}
 
-	hc := &healthDetailCheck{}
-
-	err = json.Unmarshal(data, hc)
+	hc, err := parseHealthDetail(data)
 	if err != nil {
+		m.parseErrors.observe("mds")
 		m.logger.WithError(err).Error("failed unmarshalling health detail")
 		return
 	}
@@ -339,42 +1504,48 @@ func (m *MDSCollector) collectMDSSlowOps() {
 		return
 	}
 
+	// The same MDS may be listed in several detail messages, each of them
+	// is only queried once.
+	var mdsNames []string
+	seen := make(map[string]bool)
 	for _, cc := range check.Detail {
-		mdsNameParts := strings.Split(cc.Message, "(")
-		if len(mdsNameParts) != 2 {
+		match := mdsSlowRequestRegex.FindStringSubmatch(cc.Message)
+		if match == nil {
 			m.logger.WithError(
-				errors.New("incorrect part count"),
+				errors.New("no mds name found"),
 			).WithFields(logrus.Fields{
 				"message": cc.Message,
 			}).Error("invalid mds slow request message found, check syntax")
 			continue
 		}
 
-		mdsName := mdsNameParts[0]
-
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-		defer cancel()
-
-		data, err := m.runMDSStatusFn(ctx, m.config, m.user, mdsName)
-		if err != nil {
-			m.logger.WithField("mds", mdsName).WithError(err).Error("failed getting status from mds")
-			return
+		mdsName := match[1]
+		if seen[mdsName] {
+			continue
 		}
+		seen[mdsName] = true
+		mdsNames = append(mdsNames, mdsName)
+	}
 
-		mss := &mdsStatus{}
-
-		err = json.Unmarshal(data, mss)
-		if err != nil {
-			m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds status")
-			return
+	for _, mdsName := range mdsNames {
+		mss, ok := statuses[mdsName]
+		if !ok {
+			var err error
+			mss, err = m.getMDSStatus(mdsName)
+			if err != nil {
+				m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting status from mds")
+				return
+			}
 		}
 
-		ctx, cancel = context.WithTimeout(context.Background(), 1*time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 		defer cancel()
 
-		data, err = m.runBlockedOpsCheckFn(ctx, m.config, m.user, mdsName)
+		start := time.Now()
+		data, err := m.runBlockedOpsCheckFn(ctx, m.config, m.user, m.keyring, mdsName)
+		m.scrapeTime.observeCLI(start)
 		if err != nil {
-			m.logger.WithField("mds", mdsName).WithError(err).Error("failed getting blocked ops from mds")
+			m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting blocked ops from mds")
 			return
 		}
 
@@ -382,24 +1553,34 @@ func (m *MDSCollector) collectMDSSlowOps() {
 
 		err = json.Unmarshal(data, mso)
 		if err != nil {
+			m.parseErrors.observe("mds")
 			m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds blocked ops")
 			return
 		}
 
 		var metricMap sync.Map
 
-		for _, op := range mso.Ops {
+		// oldestOps holds the oldest op of each label set, whose reqid is
+		// the exemplar of its sample.
+		oldestOps := make(map[string]int)
+
+		// inodeOps counts the client requests blocked on each inode.
+		inodeOps := make(map[string]int)
+
+		for i, op := range mso.Ops {
 			var ml mdsLabels
 
 			if op.TypeData.OpType == "client_request" {
 				opd, err := extractOpFromDescription(op.Description)
 				if err != nil {
+					m.parseErrors.observe("mds")
 					m.logger.WithField("mds", mdsName).WithError(err).Error("failed parsing blocked ops description")
 					continue
 				}
 
 				ml.FSOpType = opd.fsOpType
 				ml.Inode = opd.inode
+				inodeOps[opd.inode]++
 			}
 
 			ml.FSName = mss.FsName
@@ -408,9 +1589,14 @@ func (m *MDSCollector) collectMDSSlowOps() {
 			ml.OpType = op.TypeData.OpType
 			ml.FlagPoint = op.TypeData.FlagPoint
 
-			cnt, _ := metricMap.LoadOrStore(ml.Hash(), new(int32))
+			hash := ml.Hash()
+			cnt, _ := metricMap.LoadOrStore(hash, new(int32))
 			v := cnt.(*int32)
 			atomic.AddInt32(v, 1)
+
+			if oldest, ok := oldestOps[hash]; !ok || op.Age > mso.Ops[oldest].Age {
+				oldestOps[hash] = i
+			}
 		}
 
 		metricMap.Range(func(key, value any) bool {
@@ -418,8 +1604,7 @@ func (m *MDSCollector) collectMDSSlowOps() {
 			ml.UnHash(fmt.Sprint(key))
 			v := value.(*int32)
 
-			select {
-			case m.ch <- prometheus.MustNewConstMetric(
+			var metric prometheus.Metric = prometheus.MustNewConstMetric(
 				m.MDSBlockedOps,
 				prometheus.CounterValue,
 				float64(*v),
@@ -430,12 +1615,388 @@ func (m *MDSCollector) collectMDSSlowOps() {
 				ml.FSOpType,
 				ml.FlagPoint,
 				ml.Inode,
-			):
-			default:
+			)
+			if m.exemplars {
+				reqid := mso.Ops[oldestOps[fmt.Sprint(key)]].TypeData.Reqid
+				metric = withReqidExemplar(metric, reqid)
 			}
+			m.send(metric)
 
 			return true
 		})
+
+		if inode, count := maxInodeOps(inodeOps); count > 0 {
+			m.send(prometheus.MustNewConstMetric(
+				m.MDSMaxOpsOnSingleInode,
+				prometheus.GaugeValue,
+				float64(count),
+				mss.FsName,
+				mdsName,
+				inode,
+			))
+		}
+	}
+}
+
+// exemplarMetric is a counter carrying an exemplar. The exemplar is set on
+// the written counter as the const metrics of client_golang can't carry one.
+type exemplarMetric struct {
+	prometheus.Metric
+	exemplar *dto.Exemplar
+}
+
+func (e *exemplarMetric) Write(out *dto.Metric) error {
+	if err := e.Metric.Write(out); err != nil {
+		return err
+	}
+
+	if out.Counter != nil {
+		out.Counter.Exemplar = e.exemplar
+	}
+
+	return nil
+}
+
+// withReqidExemplar attaches the reqid of a blocked op to the counter as an
+// exemplar of value 1, the op it stands for. The metric is returned as is
+// when the reqid is empty or too long for an exemplar.
+func withReqidExemplar(metric prometheus.Metric, reqid string) prometheus.Metric {
+	if reqid == "" || utf8.RuneCountInString("reqid"+reqid) > mdsExemplarMaxRunes {
+		return metric
+	}
+
+	name, value := "reqid", 1.0
+	return &exemplarMetric{
+		Metric: metric,
+		exemplar: &dto.Exemplar{
+			Label: []*dto.LabelPair{{Name: &name, Value: &reqid}},
+			Value: &value,
+		},
+	}
+}
+
+// maxInodeOps returns the inode with the most ops, the lowest one on ties so
+// that the label doesn't flap between scrapes.
+func maxInodeOps(inodeOps map[string]int) (string, int) {
+	var (
+		maxInode string
+		maxCount int
+	)
+	for inode, count := range inodeOps {
+		if count > maxCount || (count == maxCount && inode < maxInode) {
+			maxInode, maxCount = inode, count
+		}
+	}
+
+	return maxInode, maxCount
+}
+
+type osdBlocklistEntry struct {
+	Addr  string `json:"addr"`
+	Until string `json:"until"`
+}
+
+type mdsSession struct {
+	ID     uint64 `json:"id"`
+	Entity struct {
+		Addr struct {
+			Addr  string `json:"addr"`
+			Nonce uint64 `json:"nonce"`
+		} `json:"addr"`
+	} `json:"entity"`
+}
+
+// blocklistAddr normalizes a client address to the "<ip>:<port>/<nonce>"
+// form, without the v1:/v2:/any: type prefix, so that session and blocklist
+// addresses can be compared.
+func blocklistAddr(addr string) string {
+	if i := strings.Index(addr, ":"); i >= 0 {
+		switch addr[:i] {
+		case "v1", "v2", "any":
+			return addr[i+1:]
+		}
+	}
+
+	return addr
+}
+
+// collectCephFSBlocklistedClients counts, for each filesystem, the client
+// sessions held by its active MDSs whose address is in the OSD blocklist.
+func (m *MDSCollector) collectCephFSBlocklistedClients(ms *mdsStat) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runOSDBlocklistLsFn(ctx, m.config, m.user, m.keyring)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting osd blocklist")
+		return
+	}
+
+	entries := []osdBlocklistEntry{}
+
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithError(err).Error("failed unmarshalling osd blocklist")
+		return
+	}
+
+	blocklist := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		blocklist[blocklistAddr(entry.Addr)] = struct{}{}
+	}
+
+	for _, fs := range ms.FSMap.Filesystems {
+		// A client holds a session with every active MDS of the
+		// filesystem it uses, so only count each address once.
+		blocklisted := make(map[string]struct{})
+		for _, info := range fs.MDSMap.Info {
+			if info.State != mdsStateActive {
+				continue
+			}
+
+			mdsName := fmt.Sprintf("mds.%s", info.Name)
+
+			start := time.Now()
+			data, err := m.runMDSSessionLsFn(ctx, m.config, m.user, m.keyring, mdsName)
+			m.scrapeTime.observeCLI(start)
+			if err != nil {
+				m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting sessions from mds")
+				continue
+			}
+
+			sessions := []mdsSession{}
+
+			err = json.Unmarshal(data, &sessions)
+			if err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds sessions")
+				continue
+			}
+
+			for _, session := range sessions {
+				addr := blocklistAddr(fmt.Sprintf("%s/%d", session.Entity.Addr.Addr, session.Entity.Addr.Nonce))
+				if _, ok := blocklist[addr]; ok {
+					blocklisted[addr] = struct{}{}
+				}
+			}
+		}
+
+		m.send(prometheus.MustNewConstMetric(
+			m.CephFSBlocklistedClients,
+			prometheus.GaugeValue,
+			float64(len(blocklisted)),
+			fs.MDSMap.FSName,
+		))
+	}
+}
+
+type fsGet struct {
+	MDSMap struct {
+		FSName      string  `json:"fs_name"`
+		MaxFileSize float64 `json:"max_file_size"`
+	} `json:"mdsmap"`
+}
+
+type mdsInode struct {
+	Layout struct {
+		StripeUnit float64 `json:"stripe_unit"`
+	} `json:"layout"`
+}
+
+// cephFSRootIno is the inode number of the root directory of a filesystem.
+const cephFSRootIno = "1"
+
+// collectCephFSLayouts reports the file size limit of each filesystem and
+// the stripe unit of its root directory, as known by its rank 0 MDS.
+func (m *MDSCollector) collectCephFSLayouts(ms *mdsStat) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	for _, fs := range ms.FSMap.Filesystems {
+		fsName := fs.MDSMap.FSName
+
+		start := time.Now()
+		data, err := m.runFSGetFn(ctx, m.config, m.user, m.keyring, fsName)
+		m.scrapeTime.observeCLI(start)
+		if err != nil {
+			m.logger.WithField("fs", fsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting fs")
+		} else {
+			fg := &fsGet{}
+			if err := json.Unmarshal(data, fg); err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("fs", fsName).WithError(err).Error("failed unmarshalling fs get json")
+			} else {
+				m.send(prometheus.MustNewConstMetric(
+					m.CephFSMaxFileSize,
+					prometheus.GaugeValue,
+					fg.MDSMap.MaxFileSize,
+					fsName,
+				))
+			}
+		}
+
+		for _, info := range fs.MDSMap.Info {
+			// The root directory is authoritative on rank 0.
+			if info.Rank != 0 || info.State != mdsStateActive {
+				continue
+			}
+
+			mdsName := fmt.Sprintf("mds.%s", info.Name)
+
+			start := time.Now()
+			data, err := m.runMDSDumpInodeFn(ctx, m.config, m.user, m.keyring, mdsName, cephFSRootIno)
+			m.scrapeTime.observeCLI(start)
+			if err != nil {
+				m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed dumping root inode from mds")
+				break
+			}
+
+			inode := &mdsInode{}
+			if err := json.Unmarshal(data, inode); err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds inode json")
+				break
+			}
+
+			m.send(prometheus.MustNewConstMetric(
+				m.CephFSDefaultStripeUnit,
+				prometheus.GaugeValue,
+				inode.Layout.StripeUnit,
+				fsName,
+			))
+
+			break
+		}
+	}
+}
+
+type mdsSnaps struct {
+	Snaps []struct {
+		SnapID uint64 `json:"snapid"`
+		Name   string `json:"name"`
+	} `json:"snaps"`
+}
+
+// collectCephFSSnapshots reports the number of snapshots of each filesystem,
+// as known by its rank 0 MDS. Releases whose MDS doesn't know the dump snaps
+// command report 0, any other failure reports nothing.
+func (m *MDSCollector) collectCephFSSnapshots(ms *mdsStat) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	for _, fs := range ms.FSMap.Filesystems {
+		for _, info := range fs.MDSMap.Info {
+			// The snapshot table is served by rank 0.
+			if info.Rank != 0 || info.State != mdsStateActive {
+				continue
+			}
+
+			mdsName := fmt.Sprintf("mds.%s", info.Name)
+			snaps := &mdsSnaps{}
+
+			start := time.Now()
+			data, err := m.runMDSDumpSnapsFn(ctx, m.config, m.user, m.keyring, mdsName)
+			m.scrapeTime.observeCLI(start)
+			if err != nil {
+				if !cephCLIUnsupported(err) {
+					m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed dumping snaps from mds")
+					break
+				}
+				m.logger.WithField("mds", mdsName).WithFields(cephCLIErrorFields(err)).WithError(err).Debug("mds can't dump snaps")
+			} else if err := json.Unmarshal(data, snaps); err != nil {
+				m.parseErrors.observe("mds")
+				m.logger.WithField("mds", mdsName).WithError(err).Error("failed unmarshalling mds snaps json")
+				break
+			}
+
+			m.send(prometheus.MustNewConstMetric(
+				m.CephFSSnapshots,
+				prometheus.GaugeValue,
+				float64(len(snaps.Snaps)),
+				fs.MDSMap.FSName,
+			))
+
+			break
+		}
+	}
+}
+
+// cephDF is the subset of the output of df we care about.
+type cephDF struct {
+	Pools []struct {
+		Name  string `json:"name"`
+		ID    int    `json:"id"`
+		Stats struct {
+			// Stored is only reported since Nautilus, bytes_used was the
+			// stored data before.
+			Stored    *float64 `json:"stored"`
+			BytesUsed float64  `json:"bytes_used"`
+		} `json:"stats"`
+	} `json:"pools"`
+}
+
+// collectCephFSPools reports the usage of the metadata and data pools of
+// each filesystem, looking the pools of the MDS map up in df.
+func (m *MDSCollector) collectCephFSPools(ms *mdsStat) {
+	if len(ms.FSMap.Filesystems) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	data, err := m.runCephDFFn(ctx, m.config, m.user, m.keyring)
+	m.scrapeTime.observeCLI(start)
+	if err != nil {
+		m.logger.WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting ceph df")
+		return
+	}
+
+	df := &cephDF{}
+	if err := json.Unmarshal(data, df); err != nil {
+		m.parseErrors.observe("mds")
+		m.logger.WithError(err).Error("failed unmarshalling ceph df json")
+		return
+	}
+
+	type poolUsage struct {
+		name   string
+		stored float64
+	}
+
+	pools := make(map[int]poolUsage, len(df.Pools))
+	for _, pool := range df.Pools {
+		stored := pool.Stats.BytesUsed
+		if pool.Stats.Stored != nil {
+			stored = *pool.Stats.Stored
+		}
+		pools[pool.ID] = poolUsage{name: pool.Name, stored: stored}
+	}
+
+	send := func(fs string, id int, poolType string) {
+		pool, ok := pools[id]
+		if !ok {
+			m.logger.WithField("fs", fs).WithField("pool_id", id).Debug("pool of filesystem not found in ceph df")
+			return
+		}
+
+		m.send(prometheus.MustNewConstMetric(
+			m.CephFSPoolBytesUsed,
+			prometheus.GaugeValue,
+			pool.stored,
+			fs, pool.name, poolType,
+		))
+	}
+
+	for _, fs := range ms.FSMap.Filesystems {
+		send(fs.MDSMap.FSName, fs.MDSMap.MetadataPool, "metadata")
+		for _, id := range fs.MDSMap.DataPools {
+			send(fs.MDSMap.FSName, id, "data")
+		}
 	}
 }
 
@@ -446,6 +2007,11 @@ type opDesc struct {
 }
 
 var (
+	// mdsSlowRequestRegex extracts the MDS name out of the detail messages
+	// of MDS_SLOW_REQUEST, e.g. "mds.a(mds.0): 2 slow requests are blocked
+	// > 30 secs". The rest of the message may hold more parentheses.
+	mdsSlowRequestRegex = regexp.MustCompile(`^\s*([^\s(]+)\(`)
+
 	descRegex                   = regexp.MustCompile(`client_request\(client\.(?P<clientid>[0-9].+?):(?P<cid>[0-9].+?)\s(?P<fsoptype>\w+)\s.*#(?P<inode>0x[0-9a-fA-F]+|[0-9]+)[^a-zA-Z\d:].*`)
 	errInvalidDescriptionFormat = "invalid op description, unable to parse %q"
 )
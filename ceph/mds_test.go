@@ -17,14 +17,20 @@ package ceph
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os/exec"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
@@ -107,6 +113,83 @@ func TestMDSStats(t *testing.T) {
 				regexp.MustCompile(`ceph_mds_daemon_state{cluster="ceph",fs="cephfs-1",name="MDS-daemonD",rank="2",state="up:standby-replay"} 1`),
 				regexp.MustCompile(`ceph_mds_daemon_state{cluster="ceph",fs="cephfs-2",name="MDS-daemonA",rank="1",state="up:active"} 1`),
 				regexp.MustCompile(`ceph_mds_daemon_state{cluster="ceph",fs="cephfs-2",name="MDS-daemonB",rank="2",state="up:standby-replay"} 1`),
+				regexp.MustCompile(`ceph_mds_ranks_stopping{cluster="ceph",fs="cephfs-1"} 0`),
+				regexp.MustCompile(`ceph_mds_ranks_stopping{cluster="ceph",fs="cephfs-2"} 0`),
+				regexp.MustCompile(`ceph_mds_standby_count{cluster="ceph",fs="cephfs-1"} 0`),
+				regexp.MustCompile(`ceph_mds_standby_replay_count{cluster="ceph",fs="cephfs-1"} 1`),
+				regexp.MustCompile(`ceph_mds_standby_replay_count{cluster="ceph",fs="cephfs-2"} 1`),
+			},
+		},
+		{
+			input: []byte(`
+			{
+				"fsmap": {
+					"filesystems": [
+						{
+							"mdsmap": {
+								"fs_name": "cephfs-1",
+								"max_mds": 1,
+								"info": {
+									"gid_1": {"gid": 1, "name": "MDS-daemonA", "rank": 0, "state": "up:active"},
+									"gid_2": {"gid": 2, "name": "MDS-daemonB", "rank": 1, "state": "up:stopping"},
+									"gid_3": {"gid": 3, "name": "MDS-daemonC", "rank": 2, "state": "up:stopping"}
+								}
+							}
+						}
+					]
+				}
+			}
+`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_daemon_state{cluster="ceph",fs="cephfs-1",name="MDS-daemonB",rank="1",state="up:stopping"} 1`),
+				regexp.MustCompile(`ceph_mds_ranks_stopping{cluster="ceph",fs="cephfs-1"} 2`),
+			},
+		},
+		{
+			input: []byte(`
+			{
+				"fsmap": {
+					"standbys": [
+						{"gid": 4, "name": "MDS-daemonD", "rank": -1, "state": "up:standby", "join_fscid": -1},
+						{"gid": 5, "name": "MDS-daemonE", "rank": -1, "state": "up:standby", "join_fscid": 2},
+						{"gid": 6, "name": "MDS-daemonF", "rank": -1, "state": "up:standby"}
+					],
+					"filesystems": [
+						{
+							"mdsmap": {
+								"fs_name": "cephfs-1",
+								"info": {
+									"gid_1": {"gid": 1, "name": "MDS-daemonA", "rank": 0, "state": "up:active"},
+									"gid_2": {"gid": 2, "name": "MDS-daemonB", "rank": 0, "state": "up:standby-replay"}
+								}
+							},
+							"id": 1
+						},
+						{
+							"mdsmap": {
+								"fs_name": "cephfs-2",
+								"info": {
+									"gid_3": {"gid": 3, "name": "MDS-daemonC", "rank": 0, "state": "up:active"}
+								}
+							},
+							"id": 2
+						}
+					]
+				}
+			}
+`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				// MDS-daemonE prefers to take over the ranks of cephfs-2.
+				regexp.MustCompile(`ceph_mds_standby_count{cluster="ceph",fs="cephfs-1"} 2`),
+				regexp.MustCompile(`ceph_mds_standby_count{cluster="ceph",fs="cephfs-2"} 3`),
+				regexp.MustCompile(`ceph_mds_standby_replay_count{cluster="ceph",fs="cephfs-1"} 1`),
+				regexp.MustCompile(`ceph_mds_standby_replay_count{cluster="ceph",fs="cephfs-2"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// The standbys don't belong to any filesystem.
+				regexp.MustCompile(`ceph_mds_daemon_state{[^}]*rank="-1"`),
 			},
 		},
 	} {
@@ -118,7 +201,7 @@ func TestMDSStats(t *testing.T) {
 				"mds": NewMDSCollector(e, false),
 			}
 
-			e.cc["mds"].(*MDSCollector).runMDSStatFn = func(_ context.Context, cluster, user string) ([]byte, error) {
+			e.cc["mds"].(*MDSCollector).runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				if tt.input != nil {
 					return tt.input, nil
 				}
@@ -157,6 +240,7 @@ func TestMDSBlockedOps(t *testing.T) {
 		blockedOps   []byte
 		mdsStatus    []byte
 		version      string
+		queries      int
 		reMatch      []*regexp.Regexp
 		reUnmatch    []*regexp.Regexp
 	}{
@@ -339,35 +423,224 @@ func TestMDSBlockedOps(t *testing.T) {
 			}
 `),
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			queries: 1,
 			reMatch: []*regexp.Regexp{
 				regexp.MustCompile(`ceph_mds_blocked_ops{cluster="ceph",flag_point="cleaned up request",fs="fsA",fs_optype="rmdir",inode="0x10000000030",name="mds.nodeA",optype="client_request",state="up:active"} 1`),
+				regexp.MustCompile(`ceph_mds_max_ops_on_single_inode{cluster="ceph",fs="fsA",inode="0x10000000030",name="mds.nodeA"} 1`),
+				regexp.MustCompile(`ceph_mds_rank_uptime_seconds{cluster="ceph",fs="cephfs-1",name="MDS-daemonC",rank="1"} 160259.414`),
+				regexp.MustCompile(`ceph_mds_daemon_uptime_seconds{cluster="ceph",fs="cephfs-1",name="MDS-daemonC",rank="1"} 163199.411`),
+			},
+		},
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			healthDetail: []byte(`
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"MDS_SLOW_REQUEST": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 MDSs report slow requests", "count": 1},
+			"detail": [
+				{"message": "mds.nodeA(mds.0): 5 slow requests are blocked > 30 secs"}
+			],
+			"muted": false
+		}
+	}
+}`),
+			blockedOps: []byte(`
+{
+	"ops": [
+		{
+			"description": "client_request(client.20074182:341 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:342 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074183:517 getattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to rdlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074183:518 getattr #0x10000000200 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to rdlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "peer_request(mds.1:42 authpin)",
+			"type_data": {"flag_point": "dispatched", "op_type": "peer_request"}
+		}
+	],
+	"complaint_time": 30,
+	"num_blocked_ops": 5
+}`),
+			mdsStatus: []byte(`{"whoami": 0, "state": "up:active", "fs_name": "fsA"}`),
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			queries:   1,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_blocked_ops{cluster="ceph",flag_point="failed to xlock, waiting",fs="fsA",fs_optype="setattr",inode="0x10000000100",name="mds.nodeA",optype="client_request",state="up:active"} 2`),
+				regexp.MustCompile(`ceph_mds_max_ops_on_single_inode{cluster="ceph",fs="fsA",inode="0x10000000100",name="mds.nodeA"} 3`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_max_ops_on_single_inode{cluster="ceph",fs="fsA",inode="0x10000000200"`),
+			},
+		},
+		{
+			// The same MDS listed in several detail messages is only
+			// queried once.
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			healthDetail: []byte(`
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"MDS_SLOW_REQUEST": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 MDSs report slow requests", "count": 1},
+			"detail": [
+				{"message": "mds.nodeA(mds.0): 2 slow requests are blocked > 30 secs"},
+				{"message": "mds.nodeA(mds.0): 1 slow requests are blocked > 300 secs"}
+			],
+			"muted": false
+		}
+	}
+}`),
+			blockedOps: []byte(`
+{
+	"ops": [
+		{
+			"description": "client_request(client.20074182:341 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:342 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		}
+	],
+	"complaint_time": 30,
+	"num_blocked_ops": 2
+}`),
+			mdsStatus: []byte(`{"whoami": 0, "state": "up:active", "fs_name": "fsA"}`),
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			queries:   1,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_blocked_ops{cluster="ceph",flag_point="failed to xlock, waiting",fs="fsA",fs_optype="setattr",inode="0x10000000100",name="mds.nodeA",optype="client_request",state="up:active"} 2`),
+				regexp.MustCompile(`ceph_mds_max_ops_on_single_inode{cluster="ceph",fs="fsA",inode="0x10000000100",name="mds.nodeA"} 2`),
+			},
+		},
+		{
+			// The messages may hold more parentheses after the MDS name,
+			// those without any MDS name are skipped.
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			healthDetail: []byte(`
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"MDS_SLOW_REQUEST": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 MDSs report slow requests", "count": 1},
+			"detail": [
+				{"message": "mds.nodeA(mds.0): 2 slow requests are blocked > 30 secs (oldest blocked for 45 secs)"},
+				{"message": "slow requests (no mds name)"}
+			],
+			"muted": false
+		}
+	}
+}`),
+			blockedOps: []byte(`
+{
+	"ops": [
+		{
+			"description": "client_request(client.20074182:341 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:342 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request"}
+		}
+	],
+	"complaint_time": 30,
+	"num_blocked_ops": 2
+}`),
+			mdsStatus: []byte(`{"whoami": 0, "state": "up:active", "fs_name": "fsA"}`),
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			queries:   1,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_blocked_ops{cluster="ceph",flag_point="failed to xlock, waiting",fs="fsA",fs_optype="setattr",inode="0x10000000100",name="mds.nodeA",optype="client_request",state="up:active"} 2`),
+				regexp.MustCompile(`ceph_mds_max_ops_on_single_inode{cluster="ceph",fs="fsA",inode="0x10000000100",name="mds.nodeA"} 2`),
 			},
 		},
 	} {
 		func() {
 			conn := setupVersionMocks(tt.version, "{}")
 
+			var blockedOpsQueries int
+			statusQueries := make(map[string]int)
+
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
 			mdsc := NewMDSCollector(e, false)
-			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user string) ([]byte, error) {
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				if tt.healthDetail != nil {
 					return tt.healthDetail, nil
 				}
 				return nil, errors.New("fake error")
 			}
-			mdsc.runBlockedOpsCheckFn = func(_ context.Context, cluster, user, mds string) ([]byte, error) {
+			mdsc.runBlockedOpsCheckFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				blockedOpsQueries++
 				if tt.blockedOps != nil {
 					return tt.blockedOps, nil
 				}
 				return nil, errors.New("fake error")
 			}
-			mdsc.runMDSStatusFn = func(_ context.Context, cluster, user, mds string) ([]byte, error) {
+			mdsc.runMDSStatusFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				statusQueries[mds]++
 				if tt.mdsStatus != nil {
 					return tt.mdsStatus, nil
 				}
 				return nil, errors.New("fake error")
 			}
-			mdsc.runMDSStatFn = func(_ context.Context, cluster, user string) ([]byte, error) {
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				if tt.mdsStat != nil {
 					return tt.mdsStat, nil
 				}
@@ -399,82 +672,1683 @@ func TestMDSBlockedOps(t *testing.T) {
 			for _, re := range tt.reUnmatch {
 				require.False(t, re.Match(buf))
 			}
+
+			require.Equal(t, tt.queries, blockedOpsQueries)
+			for mds, queries := range statusQueries {
+				require.Equal(t, 1, queries, mds)
+			}
 		}()
 	}
 }
 
-func TestExtractOpFromDescription(t *testing.T) {
-	commonErr := "invalid op description, unable to parse"
-	for _, tt := range []struct {
-		input  string
-		errMsg string
-		opd    *opDesc
-	}{
-		{
-			input:  "client_request(client.20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: "",
-			opd: &opDesc{
-				fsOpType: "rmdir",
-				inode:    "0x10000000030",
-				clientID: "20001974182",
-			},
-		},
-		{
-			input:  "client_request(client.20001974182:344151 create #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-03-26T02:11:21.800677+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: "",
-			opd: &opDesc{
-				fsOpType: "create",
-				inode:    "0x10000000030",
-				clientID: "20001974182",
-			},
-		},
-		{
-			input:  "client_request(client.20001974182:344151 getattr AsXsFs #0x10000000030 2024-03-26T02:01:57.036219+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: "",
-			opd: &opDesc{
-				fsOpType: "getattr",
-				inode:    "0x10000000030",
-				clientID: "20001974182",
-			},
-		},
+func TestMDSDescriptorList(t *testing.T) {
+	e := &Exporter{Conn: &MockConn{}, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+
+	described := make(map[*prometheus.Desc]bool)
+	for _, desc := range mdsc.descriptorList() {
+		described[desc] = true
+	}
+
+	// Every descriptor of the collector has to be described.
+	v := reflect.ValueOf(mdsc).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		desc, ok := v.Field(i).Interface().(*prometheus.Desc)
+		if !ok {
+			continue
+		}
+		require.True(t, described[desc], "%s is missing from descriptorList", v.Type().Field(i).Name)
+	}
+}
+
+func TestMDSBlockedOpsExemplars(t *testing.T) {
+	blockedOps := []byte(`
+{
+	"ops": [
 		{
-			input:  "client_request(client.20001974182:344151rmdir#0x10000000030ZZZZ/72a26231-ac24-4f69-9350-8ebc5444c9ea2024-02-13T22:11:00.196767+0000caller_uid=0, caller_gid=0{})",
-			errMsg: commonErr,
-			opd:    nil,
+			"description": "client_request(client.20074182:341 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"age": 45.2,
+			"type_data": {"flag_point": "failed to xlock, waiting", "reqid": "client.20074182:341", "op_type": "client_request"}
 		},
 		{
-			input:  "client_request(client.20001974182:344151 rmdir #0x10000000030ZZZZ/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: commonErr,
-			opd:    nil,
+			"description": "client_request(client.20074182:342 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"age": 50.7,
+			"type_data": {"flag_point": "failed to xlock, waiting", "reqid": "client.20074182:342", "op_type": "client_request"}
 		},
 		{
-			input:  "client_request[client.20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: commonErr,
-			opd:    nil,
-		},
+			"description": "client_request(client.20074182:343 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"age": 31.5,
+			"type_data": {"flag_point": "failed to rdlock, waiting", "reqid": "client.20074182:343", "op_type": "client_request"}
+		}
+	],
+	"complaint_time": 30,
+	"num_blocked_ops": 3
+}`)
+
+	for _, tt := range []struct {
+		name      string
+		exemplars bool
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
 		{
-			input:  "client_request(client.20001974182/344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: commonErr,
-			opd:    nil,
+			name:      "enabled",
+			exemplars: true,
+			reMatch: []*regexp.Regexp{
+				// The oldest op of the label set is the exemplar.
+				regexp.MustCompile(`ceph_mds_blocked_ops{[^}]*flag_point="failed to xlock, waiting"[^}]*} 2(\.0)? # {reqid="client.20074182:342"} 1(\.0)?`),
+				regexp.MustCompile(`ceph_mds_blocked_ops{[^}]*flag_point="failed to rdlock, waiting"[^}]*} 1(\.0)? # {reqid="client.20074182:343"} 1(\.0)?`),
+			},
 		},
 		{
-			input:  "client_request(client|20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
-			errMsg: commonErr,
-			opd:    nil,
+			name: "disabled",
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_blocked_ops{[^}]*flag_point="failed to xlock, waiting"[^}]*} 2(\.0)?\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`reqid`),
+			},
 		},
 	} {
-		opd, err := extractOpFromDescription(tt.input)
-		if tt.errMsg != "" {
-			require.NotNil(t, err)
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), MDSExemplars: tt.exemplars}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"fsmap": {"filesystems": []}}`), nil
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"MDS_SLOW_REQUEST": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "1 MDSs report slow requests", "count": 1},
+			"detail": [
+				{"message": "mds.nodeA(mds.0): 3 slow requests are blocked > 30 secs"}
+			],
+			"muted": false
 		}
-		if tt.errMsg == "" {
+	}
+}`), nil
+			}
+			mdsc.runBlockedOpsCheckFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				return blockedOps, nil
+			}
+			mdsc.runMDSStatusFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				return []byte(`{"whoami": 0, "state": "up:active", "fs_name": "fsA"}`), nil
+			}
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			reg := prometheus.NewRegistry()
+			require.NoError(t, reg.Register(e))
+
+			server := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
 			require.NoError(t, err)
-		}
-		if err != nil {
-			require.ErrorContains(t, err, tt.errMsg)
-		}
-		if tt.opd != nil {
-			require.Equal(t, tt.opd, opd)
-		}
+			req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
+
+func TestWithReqidExemplar(t *testing.T) {
+	desc := prometheus.NewDesc("ceph_test_total", "test", nil, nil)
+	metric := prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 1)
+
+	require.Same(t, metric, withReqidExemplar(metric, ""))
+	require.Same(t, metric, withReqidExemplar(metric, strings.Repeat("x", 124)))
+
+	out := &dto.Metric{}
+	require.NoError(t, withReqidExemplar(metric, strings.Repeat("x", 123)).Write(out))
+	require.Equal(t, strings.Repeat("x", 123), out.GetCounter().GetExemplar().GetLabel()[0].GetValue())
+}
+
+func TestMDSCacheMemoryUsage(t *testing.T) {
+	for _, tt := range []struct {
+		mdsStat   []byte
+		perfDump  map[string][]byte
+		limit     map[string][]byte
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
 	}
+}`),
+			perfDump: map[string][]byte{
+				"mds.nodeA": []byte(`{"mempool": {"mds_co_bytes": 3221225472, "mds_co_items": 1234}}`),
+				"mds.nodeB": []byte(`{"mempool": {"mds_co_bytes": 1073741824, "mds_co_items": 123}}`),
+				"mds.nodeC": []byte(`{"mempool": {"mds_co_bytes": 1073741824, "mds_co_items": 123}}`),
+			},
+			limit: map[string][]byte{
+				"mds.nodeA": []byte(`{"mds_cache_memory_limit": "4294967296"}`),
+				"mds.nodeB": []byte(`{"mds_cache_memory_limit": "4294967296"}`),
+				"mds.nodeC": []byte(`{"mds_cache_memory_limit": "0"}`),
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_cache_memory_usage_ratio{cluster="ceph",fs="fsA",name="nodeA"} 0.75`),
+				regexp.MustCompile(`ceph_mds_cache_memory_usage_ratio{cluster="ceph",fs="fsA",name="nodeB"} 0.25`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_cache_memory_usage_ratio{cluster="ceph",fs="fsA",name="nodeC"}`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.mdsStat != nil {
+					return tt.mdsStat, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				if out, ok := tt.perfDump[mds]; ok {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			mdsc.runMDSConfigGetFn = func(_ context.Context, cluster, user, keyring, mds, option string) ([]byte, error) {
+				if out, ok := tt.limit[mds]; ok && option == "mds_cache_memory_limit" {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestMDSInflightOps(t *testing.T) {
+	for _, tt := range []struct {
+		mdsStat   []byte
+		opsDump   map[string][]byte
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			opsDump: map[string][]byte{
+				"mds.nodeA": []byte(`
+{
+	"ops": [
+		{"description": "client_request(client.4133:17 lookup #0x1/dir 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 0.1, "type_data": {"flag_point": "submit entry: journal_and_reply", "op_type": "client_request"}},
+		{"description": "client_request(client.4133:18 create #0x10000000000/file 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 0.2, "type_data": {"flag_point": "acquired locks", "op_type": "client_request"}},
+		{"description": "client_request(client.4134:21 getattr #0x10000000001 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 35.5, "type_data": {"flag_point": "failed to rdlock, waiting", "op_type": "client_request"}},
+		{"description": "peer_request(mds.1:5 authpin)", "age": 0.3, "type_data": {"flag_point": "dispatched", "op_type": "peer_request"}},
+		{"description": "internal op exportdir:mds.0:1", "age": 1.2, "type_data": {"flag_point": "dispatched", "op_type": "internal_op"}}
+	],
+	"num_ops": 5
+}`),
+				"mds.nodeC": []byte(`{"ops": [], "num_ops": 0}`),
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_inflight_ops_by_type{cluster="ceph",fs="fsA",name="nodeA",optype="client_request"} 3`),
+				regexp.MustCompile(`ceph_mds_inflight_ops_by_type{cluster="ceph",fs="fsA",name="nodeA",optype="peer_request"} 1`),
+				regexp.MustCompile(`ceph_mds_inflight_ops_by_type{cluster="ceph",fs="fsA",name="nodeA",optype="internal_op"} 1`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.4133",cluster="ceph",fs="fsA"} 0.2`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.4134",cluster="ceph",fs="fsA"} 35.5`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_inflight_ops_by_type{cluster="ceph",fs="fsA",name="nodeB"`),
+				regexp.MustCompile(`ceph_mds_inflight_ops_by_type{cluster="ceph",fs="fsA",name="nodeC"`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="mds.1"`),
+			},
+		},
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			opsDump: map[string][]byte{
+				"mds.nodeA": []byte(`
+{
+	"ops": [
+		{"description": "client_request(client.1:7 lookup #0x1/dir 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 5, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.1", "tid": 7}}},
+		{"description": "client_request(client.2:3 create #0x10000000000/file 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 120, "type_data": {"flag_point": "failed to xlock, waiting", "op_type": "client_request", "client_info": {"client": "client.2", "tid": 3}}}
+	],
+	"num_ops": 2
+}`),
+				"mds.nodeC": []byte(`
+{
+	"ops": [
+		{"description": "client_request(client.2:4 getattr #0x10000000001 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 300, "type_data": {"flag_point": "failed to rdlock, waiting", "op_type": "client_request", "client_info": {"client": "client.2", "tid": 4}}},
+		{"description": "client_request(client.10:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 1, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.10", "tid": 1}}},
+		{"description": "client_request(client.11:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 2, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.11", "tid": 1}}},
+		{"description": "client_request(client.12:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 3, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.12", "tid": 1}}},
+		{"description": "client_request(client.13:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 4, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.13", "tid": 1}}},
+		{"description": "client_request(client.14:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 5, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.14", "tid": 1}}},
+		{"description": "client_request(client.15:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 6, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.15", "tid": 1}}},
+		{"description": "client_request(client.16:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 7, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.16", "tid": 1}}},
+		{"description": "client_request(client.17:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 8, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.17", "tid": 1}}},
+		{"description": "client_request(client.18:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 9, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.18", "tid": 1}}},
+		{"description": "client_request(client.19:1 getattr #0x1 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})", "age": 10, "type_data": {"flag_point": "dispatched", "op_type": "client_request", "client_info": {"client": "client.19", "tid": 1}}}
+	],
+	"num_ops": 11
+}`),
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.2",cluster="ceph",fs="fsA"} 300`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.19",cluster="ceph",fs="fsA"} 10`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.1",cluster="ceph",fs="fsA"} 5`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.14",cluster="ceph",fs="fsA"} 5`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.12",cluster="ceph",fs="fsA"} 3`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.2",cluster="ceph",fs="fsA"} 120`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.11"`),
+				regexp.MustCompile(`ceph_mds_client_oldest_request_age_seconds{client="client.10"`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.mdsStat != nil {
+					return tt.mdsStat, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runMDSOpsInFlightFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				if out, ok := tt.opsDump[mds]; ok {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		}()
+	}
+}
+
+func TestCephFSBlocklistedClients(t *testing.T) {
+	for _, tt := range []struct {
+		mdsStat   []byte
+		sessions  map[string][]byte
+		blocklist []byte
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_4": {"gid": 4, "name": "nodeD", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsB"
+				}
+			}
+		]
+	}
+}`),
+			sessions: map[string][]byte{
+				"mds.nodeA": []byte(`
+[
+	{"id": 4305, "entity": {"name": {"type": "client", "num": 4305}, "addr": {"type": "v1", "addr": "10.0.0.1:0", "nonce": 3845912356}}, "state": "open"},
+	{"id": 4306, "entity": {"name": {"type": "client", "num": 4306}, "addr": {"type": "v1", "addr": "10.0.0.2:0", "nonce": 1234}}, "state": "open"}
+]`),
+				"mds.nodeC": []byte(`
+[
+	{"id": 4305, "entity": {"name": {"type": "client", "num": 4305}, "addr": {"type": "v1", "addr": "10.0.0.1:0", "nonce": 3845912356}}, "state": "open"}
+]`),
+				"mds.nodeD": []byte(`
+[
+	{"id": 4400, "entity": {"name": {"type": "client", "num": 4400}, "addr": {"type": "v1", "addr": "10.0.0.3:0", "nonce": 42}}, "state": "open"}
+]`),
+			},
+			blocklist: []byte(`
+[
+	{"addr": "10.0.0.1:0/3845912356", "until": "2024-01-10T13:00:00.000000+0000"},
+	{"addr": "10.0.0.9:0/3026477231", "until": "2024-01-10T13:00:00.000000+0000"}
+]`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_blocklisted_clients{cluster="ceph",fs="fsA"} 1`),
+				regexp.MustCompile(`ceph_cephfs_blocklisted_clients{cluster="ceph",fs="fsB"} 0`),
+			},
+		},
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			blocklist: nil,
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_blocklisted_clients`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return tt.mdsStat, nil
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.blocklist != nil {
+					return tt.blocklist, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			mdsc.runMDSSessionLsFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				if out, ok := tt.sessions[mds]; ok {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), string(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestCephFSLayouts(t *testing.T) {
+	for _, tt := range []struct {
+		mdsStat   []byte
+		fsGet     map[string][]byte
+		inodes    map[string][]byte
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_4": {"gid": 4, "name": "nodeD", "rank": 0, "state": "up:replay"}
+					},
+					"fs_name": "fsB"
+				}
+			}
+		]
+	}
+}`),
+			fsGet: map[string][]byte{
+				"fsA": []byte(`{"mdsmap": {"epoch": 42, "flags": 18, "fs_name": "fsA", "max_file_size": 1099511627776, "max_mds": 2, "in": [0, 1], "up": {"mds_0": 1, "mds_1": 3}}, "id": 1}`),
+				"fsB": []byte(`{"mdsmap": {"epoch": 7, "flags": 18, "fs_name": "fsB", "max_file_size": 17592186044416, "max_mds": 1, "in": [0], "up": {"mds_0": 4}}, "id": 2}`),
+			},
+			inodes: map[string][]byte{
+				"mds.nodeA": []byte(`{"path": "/", "ino": 1, "layout": {"stripe_unit": 4194304, "stripe_count": 1, "object_size": 4194304, "pool_id": 3, "pool_ns": ""}}`),
+				"mds.nodeC": []byte(`{"path": "/", "ino": 1, "layout": {"stripe_unit": 65536, "stripe_count": 1, "object_size": 65536, "pool_id": 3, "pool_ns": ""}}`),
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_max_file_size_bytes{cluster="ceph",fs="fsA"} 1.099511627776e\+12`),
+				regexp.MustCompile(`ceph_cephfs_max_file_size_bytes{cluster="ceph",fs="fsB"} 1.7592186044416e\+13`),
+				regexp.MustCompile(`ceph_cephfs_default_stripe_unit_bytes{cluster="ceph",fs="fsA"} 4.194304e\+06`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// The rank 0 MDS of fsB isn't active yet.
+				regexp.MustCompile(`ceph_cephfs_default_stripe_unit_bytes{cluster="ceph",fs="fsB"}`),
+			},
+		},
+		{
+			mdsStat: []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_max_file_size_bytes{`),
+				regexp.MustCompile(`ceph_cephfs_default_stripe_unit_bytes{`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return tt.mdsStat, nil
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runFSGetFn = func(_ context.Context, cluster, user, keyring, fs string) ([]byte, error) {
+				if out, ok := tt.fsGet[fs]; ok {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			mdsc.runMDSDumpInodeFn = func(_ context.Context, cluster, user, keyring, mds, ino string) ([]byte, error) {
+				if out, ok := tt.inodes[mds]; ok && ino == "1" {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), string(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestCephFSSnapshots(t *testing.T) {
+	mdsStat := []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsB"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_4": {"gid": 4, "name": "nodeD", "rank": 0, "state": "up:replay"}
+					},
+					"fs_name": "fsC"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_5": {"gid": 5, "name": "nodeE", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsD"
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {
+						"gid_6": {"gid": 6, "name": "nodeF", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsE"
+				}
+			}
+		]
+	}
+}`)
+
+	snaps := map[string][]byte{
+		"mds.nodeA": []byte(`
+{
+	"last_created": 3,
+	"last_destroyed": 1,
+	"snaps": [
+		{"snapid": 2, "ino": 1099511627776, "stamp": "2024-05-01T00:00:00.000000+0000", "name": "daily-1", "metadata": {}},
+		{"snapid": 3, "ino": 1099511627776, "stamp": "2024-05-02T00:00:00.000000+0000", "name": "daily-2", "metadata": {}}
+	]
+}`),
+	}
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat, nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runFSGetFn = func(_ context.Context, cluster, user, keyring, fs string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runMDSDumpInodeFn = func(_ context.Context, cluster, user, keyring, mds, ino string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runMDSDumpSnapsFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		if out, ok := snaps[mds]; ok {
+			return out, nil
+		}
+		switch mds {
+		case "mds.nodeE":
+			// Older releases reject the command with EINVAL.
+			return nil, &cephCLIError{returnCode: 22, err: errors.New("exit status 22")}
+		case "mds.nodeF":
+			return nil, &cephCLIError{returnCode: -1, err: context.DeadlineExceeded}
+		}
+		// Older releases don't know the command.
+		return nil, errors.New("unrecognized command")
+	}
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Regexp(t, regexp.MustCompile(`ceph_cephfs_snapshots_total{cluster="ceph",fs="fsA"} 2\n`), string(buf))
+	require.Regexp(t, regexp.MustCompile(`ceph_cephfs_snapshots_total{cluster="ceph",fs="fsB"} 0\n`), string(buf))
+	// The rank 0 MDS of fsC isn't active yet.
+	require.NotRegexp(t, regexp.MustCompile(`ceph_cephfs_snapshots_total{cluster="ceph",fs="fsC"}`), string(buf))
+	require.Regexp(t, regexp.MustCompile(`ceph_cephfs_snapshots_total{cluster="ceph",fs="fsD"} 0\n`), string(buf))
+	// A failure other than an unknown command isn't reported as 0 snapshots.
+	require.NotRegexp(t, regexp.MustCompile(`ceph_cephfs_snapshots_total{cluster="ceph",fs="fsE"}`), string(buf))
+}
+
+func TestCephFSPools(t *testing.T) {
+	mdsStat := []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {},
+					"fs_name": "fsA",
+					"metadata_pool": 1,
+					"data_pools": [2, 3]
+				}
+			},
+			{
+				"mdsmap": {
+					"info": {},
+					"fs_name": "fsB",
+					"metadata_pool": 4,
+					"data_pools": [5]
+				}
+			}
+		]
+	}
+}`)
+
+	for _, tt := range []struct {
+		name     string
+		df       string
+		regexes  []*regexp.Regexp
+		nregexes []*regexp.Regexp
+	}{
+		{
+			name: "nautilus",
+			df: `
+{
+	"pools": [
+		{"name": "fsA_metadata", "id": 1, "stats": {"stored": 1024, "bytes_used": 3072}},
+		{"name": "fsA_data", "id": 2, "stats": {"stored": 4096, "bytes_used": 12288}},
+		{"name": "fsA_data_ec", "id": 3, "stats": {"stored": 8192, "bytes_used": 12288}},
+		{"name": "fsB_metadata", "id": 4, "stats": {"stored": 512, "bytes_used": 1536}},
+		{"name": "rbd", "id": 6, "stats": {"stored": 2048, "bytes_used": 6144}}
+	]
+}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsA",pool="fsA_metadata",pool_type="metadata"} 1024\n`),
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsA",pool="fsA_data",pool_type="data"} 4096\n`),
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsA",pool="fsA_data_ec",pool_type="data"} 8192\n`),
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsB",pool="fsB_metadata",pool_type="metadata"} 512\n`),
+			},
+			nregexes: []*regexp.Regexp{
+				// The data pool of fsB is missing from df.
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsB",pool="[^"]*",pool_type="data"}`),
+				regexp.MustCompile(`pool="rbd"`),
+			},
+		},
+		{
+			name: "luminous",
+			df: `
+{
+	"pools": [
+		{"name": "fsA_metadata", "id": 1, "stats": {"bytes_used": 1024, "raw_bytes_used": 3072}},
+		{"name": "fsB_data", "id": 5, "stats": {"bytes_used": 2048, "raw_bytes_used": 6144}}
+	]
+}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsA",pool="fsA_metadata",pool_type="metadata"} 1024\n`),
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{cluster="ceph",fs="fsB",pool="fsB_data",pool_type="data"} 2048\n`),
+			},
+		},
+		{
+			name: "invalid df",
+			df:   `{"pools": "none"}`,
+			nregexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cephfs_pool_bytes_used{`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return mdsStat, nil
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runFSGetFn = func(_ context.Context, cluster, user, keyring, fs string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runCephDFFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(tt.df), nil
+			}
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.regexes {
+				require.Regexp(t, re, string(buf))
+			}
+			for _, re := range tt.nregexes {
+				require.NotRegexp(t, re, string(buf))
+			}
+		})
+	}
+}
+
+func TestBlocklistAddr(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{"10.0.0.1:0/3845912356", "10.0.0.1:0/3845912356"},
+		{"v1:10.0.0.1:0/3845912356", "10.0.0.1:0/3845912356"},
+		{"any:10.0.0.1:0/3845912356", "10.0.0.1:0/3845912356"},
+		{"[2001:db8::1]:0/42", "[2001:db8::1]:0/42"},
+	} {
+		require.Equal(t, tt.out, blocklistAddr(tt.in))
+	}
+}
+
+func TestMDSRecoveryDurations(t *testing.T) {
+	mdsStat := func(state string) []byte {
+		return []byte(fmt.Sprintf(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": %q},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 1, "state": "up:active"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`, state))
+	}
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+
+	var (
+		state string
+		now   = time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+	)
+	mdsc.now = func() time.Time {
+		return now
+	}
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat(state), nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	for _, step := range []struct {
+		state     string
+		elapsed   time.Duration
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			state: "up:replay",
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_resolve_duration_seconds`),
+				regexp.MustCompile(`ceph_mds_rejoin_duration_seconds`),
+			},
+		},
+		{
+			state:   "up:resolve",
+			elapsed: 10 * time.Second,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_resolve_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_rejoin_duration_seconds`),
+			},
+		},
+		{
+			state:   "up:rejoin",
+			elapsed: 30 * time.Second,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_resolve_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 30`),
+				regexp.MustCompile(`ceph_mds_rejoin_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 0`),
+			},
+		},
+		{
+			state:   "up:rejoin",
+			elapsed: 60 * time.Second,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_resolve_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 30`),
+				regexp.MustCompile(`ceph_mds_rejoin_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 60`),
+			},
+		},
+		{
+			state:   "up:active",
+			elapsed: 60 * time.Second,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_resolve_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 30`),
+				regexp.MustCompile(`ceph_mds_rejoin_duration_seconds{cluster="ceph",fs="fsA",name="nodeA"} 120`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_(resolve|rejoin)_duration_seconds{cluster="ceph",fs="fsA",name="nodeB"}`),
+			},
+		},
+	} {
+		state = step.state
+		now = now.Add(step.elapsed)
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		for _, re := range step.reMatch {
+			require.True(t, re.Match(buf), step.state)
+		}
+
+		for _, re := range step.reUnmatch {
+			require.False(t, re.Match(buf), step.state)
+		}
+	}
+}
+
+func TestMDSRequestsForwardedToLaggy(t *testing.T) {
+	mdsStat := func(laggySince string) []byte {
+		nodeB := `{"gid": 2, "name": "nodeB", "rank": 1, "state": "up:active"}`
+		if laggySince != "" {
+			nodeB = fmt.Sprintf(`{"gid": 2, "name": "nodeB", "rank": 1, "state": "up:active", "laggy_since": %q}`, laggySince)
+		}
+
+		return []byte(fmt.Sprintf(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": %s
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`, nodeB))
+	}
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+
+	var (
+		laggySince string
+		forwards   = map[string]int{}
+	)
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat(laggySince), nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runMDSPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		if mds == "mds.nodeB" {
+			// A laggy MDS doesn't answer.
+			return nil, errors.New("fake error")
+		}
+		return []byte(fmt.Sprintf(`{"mds": {"request": 123456, "reply": 123000, "forward": %d}}`, forwards[mds])), nil
+	}
+
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	for _, step := range []struct {
+		laggySince string
+		forward    int
+		reMatch    []*regexp.Regexp
+	}{
+		{
+			forward: 100,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_requests_forwarded_to_laggy{cluster="ceph",fs="fsA"} 0`),
+			},
+		},
+		{
+			// nodeB turns laggy, the forwards so far don't count.
+			laggySince: "2024-01-10T13:00:00.000000+0000",
+			forward:    150,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_requests_forwarded_to_laggy{cluster="ceph",fs="fsA"} 0`),
+			},
+		},
+		{
+			laggySince: "2024-01-10T13:00:00.000000+0000",
+			forward:    192,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_requests_forwarded_to_laggy{cluster="ceph",fs="fsA"} 42`),
+			},
+		},
+		{
+			// nodeB recovered.
+			forward: 200,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_requests_forwarded_to_laggy{cluster="ceph",fs="fsA"} 0`),
+			},
+		},
+	} {
+		laggySince = step.laggySince
+		forwards["mds.nodeA"] = step.forward
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		for _, re := range step.reMatch {
+			require.True(t, re.Match(buf), string(buf))
+		}
+	}
+}
+
+func TestMDSMigrations(t *testing.T) {
+	mdsStat := []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 1, "state": "up:active"},
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 1, "state": "up:standby-replay"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`)
+
+	perfDumps := map[string][]byte{
+		"mds.nodeA": []byte(`{"mds": {"forward": 0, "exported": 12, "exported_inodes": 34567, "imported": 3, "imported_inodes": 890}}`),
+		"mds.nodeB": []byte(`{"mds": {"forward": 0, "exported": 3, "exported_inodes": 890, "imported": 12, "imported_inodes": 34567}}`),
+	}
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat, nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runMDSPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		if out, ok := perfDumps[mds]; ok {
+			return out, nil
+		}
+		return nil, errors.New("fake error")
+	}
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_mds_exports_total{cluster="ceph",fs="fsA",name="nodeA",rank="0"} 12\n`),
+		regexp.MustCompile(`ceph_mds_exported_inodes_total{cluster="ceph",fs="fsA",name="nodeA",rank="0"} 34567\n`),
+		regexp.MustCompile(`ceph_mds_imports_total{cluster="ceph",fs="fsA",name="nodeA",rank="0"} 3\n`),
+		regexp.MustCompile(`ceph_mds_imported_inodes_total{cluster="ceph",fs="fsA",name="nodeA",rank="0"} 890\n`),
+		regexp.MustCompile(`ceph_mds_exports_total{cluster="ceph",fs="fsA",name="nodeB",rank="1"} 3\n`),
+		regexp.MustCompile(`ceph_mds_imports_total{cluster="ceph",fs="fsA",name="nodeB",rank="1"} 12\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+
+	// Only the active daemons migrate subtrees.
+	require.NotRegexp(t, regexp.MustCompile(`ceph_mds_exports_total{[^}]*name="nodeC"`), string(buf))
+}
+
+func TestMDSActiveRanks(t *testing.T) {
+	mdsStat := []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"max_mds": 2,
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"}
+					},
+					"fs_name": "fsA"
+				}
+			},
+			{
+				"mdsmap": {
+					"max_mds": 1,
+					"info": {
+						"gid_3": {"gid": 3, "name": "nodeC", "rank": 0, "state": "up:active"}
+					},
+					"fs_name": "fsB"
+				}
+			}
+		]
+	}
+}`)
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat, nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		// Rank 1 of fsA failed and no standby took it over.
+		regexp.MustCompile(`ceph_mds_max_mds{cluster="ceph",fs="fsA"} 2\n`),
+		regexp.MustCompile(`ceph_mds_active_ranks{cluster="ceph",fs="fsA"} 1\n`),
+		regexp.MustCompile(`ceph_mds_max_mds{cluster="ceph",fs="fsB"} 1\n`),
+		regexp.MustCompile(`ceph_mds_active_ranks{cluster="ceph",fs="fsB"} 1\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+}
+
+func TestMDSHistoricOps(t *testing.T) {
+	mdsStat := []byte(`
+{
+	"fsmap": {
+		"filesystems": [
+			{
+				"mdsmap": {
+					"info": {
+						"gid_1": {"gid": 1, "name": "nodeA", "rank": 0, "state": "up:active"},
+						"gid_2": {"gid": 2, "name": "nodeB", "rank": 0, "state": "up:standby-replay"}
+					},
+					"fs_name": "fsA"
+				}
+			}
+		]
+	}
+}`)
+
+	historicOps := []byte(`
+{
+	"size": 20,
+	"duration": 600,
+	"ops": [
+		{
+			"description": "client_request(client.20074182:341 setattr #0x10000000100 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			"duration": 0.5,
+			"type_data": {"op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:342 setattr #0x10000000100 2024-02-13T22:11:01.196767+0000 caller_uid=0, caller_gid=0{})",
+			"duration": 1.5,
+			"type_data": {"op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:343 setattr #0x10000000101 2024-02-13T22:11:02.196767+0000 caller_uid=0, caller_gid=0{})",
+			"duration": 4,
+			"type_data": {"op_type": "client_request"}
+		},
+		{
+			"description": "client_request(client.20074182:344 rmdir #0x10000000030/8ebc5444c9ea 2024-02-13T22:11:03.196767+0000 caller_uid=0, caller_gid=0{})",
+			"duration": 0.25,
+			"type_data": {"op_type": "client_request"}
+		},
+		{
+			"description": "peer_request(mds.1:12 authpin)",
+			"duration": 10,
+			"type_data": {"op_type": "peer_request"}
+		}
+	]
+}`)
+
+	for _, tt := range []struct {
+		name        string
+		historicOps bool
+		reMatch     []*regexp.Regexp
+		reUnmatch   []*regexp.Regexp
+	}{
+		{
+			name:        "enabled",
+			historicOps: true,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds{cluster="ceph",fs="fsA",fs_optype="setattr",name="nodeA",quantile="0.5"} 1.5\n`),
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds{cluster="ceph",fs="fsA",fs_optype="setattr",name="nodeA",quantile="0.99"} 4\n`),
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds_sum{cluster="ceph",fs="fsA",fs_optype="setattr",name="nodeA"} 6\n`),
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds_count{cluster="ceph",fs="fsA",fs_optype="setattr",name="nodeA"} 3\n`),
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds_count{cluster="ceph",fs="fsA",fs_optype="rmdir",name="nodeA"} 1\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// Only the client requests have an fs op type.
+				regexp.MustCompile(`fs_optype="authpin"`),
+				// Only the active daemons serve requests.
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds[^ ]*name="nodeB"`),
+			},
+		},
+		{
+			name: "disabled",
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_mds_historic_op_duration_seconds`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), MDSHistoricOps: tt.historicOps}
+			mdsc := NewMDSCollector(e, false)
+			mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return mdsStat, nil
+			}
+			mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+			}
+			mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return nil, errors.New("fake error")
+			}
+			queries := 0
+			mdsc.runMDSHistoricOpsFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+				queries++
+				return historicOps, nil
+			}
+			e.cc = map[string]versionedCollector{
+				"mds": mdsc,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+
+			if !tt.historicOps {
+				require.Zero(t, queries)
+			}
+		})
+	}
+}
+
+func TestNearestRank(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	require.Equal(t, 5.0, nearestRank(sorted, 0.5))
+	require.Equal(t, 9.0, nearestRank(sorted, 0.9))
+	require.Equal(t, 10.0, nearestRank(sorted, 0.99))
+	require.Equal(t, 1.0, nearestRank(sorted, 0))
+	require.Equal(t, 7.0, nearestRank([]float64{7}, 0.5))
+}
+
+func TestExtractOpFromDescription(t *testing.T) {
+	commonErr := "invalid op description, unable to parse"
+	for _, tt := range []struct {
+		input  string
+		errMsg string
+		opd    *opDesc
+	}{
+		{
+			input:  "client_request(client.20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: "",
+			opd: &opDesc{
+				fsOpType: "rmdir",
+				inode:    "0x10000000030",
+				clientID: "20001974182",
+			},
+		},
+		{
+			input:  "client_request(client.20001974182:344151 create #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-03-26T02:11:21.800677+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: "",
+			opd: &opDesc{
+				fsOpType: "create",
+				inode:    "0x10000000030",
+				clientID: "20001974182",
+			},
+		},
+		{
+			input:  "client_request(client.20001974182:344151 getattr AsXsFs #0x10000000030 2024-03-26T02:01:57.036219+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: "",
+			opd: &opDesc{
+				fsOpType: "getattr",
+				inode:    "0x10000000030",
+				clientID: "20001974182",
+			},
+		},
+		{
+			input:  "client_request(client.20001974182:344151rmdir#0x10000000030ZZZZ/72a26231-ac24-4f69-9350-8ebc5444c9ea2024-02-13T22:11:00.196767+0000caller_uid=0, caller_gid=0{})",
+			errMsg: commonErr,
+			opd:    nil,
+		},
+		{
+			input:  "client_request(client.20001974182:344151 rmdir #0x10000000030ZZZZ/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: commonErr,
+			opd:    nil,
+		},
+		{
+			input:  "client_request[client.20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: commonErr,
+			opd:    nil,
+		},
+		{
+			input:  "client_request(client.20001974182/344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: commonErr,
+			opd:    nil,
+		},
+		{
+			input:  "client_request(client|20001974182:344151 rmdir #0x10000000030/72a26231-ac24-4f69-9350-8ebc5444c9ea 2024-02-13T22:11:00.196767+0000 caller_uid=0, caller_gid=0{})",
+			errMsg: commonErr,
+			opd:    nil,
+		},
+	} {
+		opd, err := extractOpFromDescription(tt.input)
+		if tt.errMsg != "" {
+			require.NotNil(t, err)
+		}
+		if tt.errMsg == "" {
+			require.NoError(t, err)
+		}
+		if err != nil {
+			require.ErrorContains(t, err, tt.errMsg)
+		}
+		if tt.opd != nil {
+			require.Equal(t, tt.opd, opd)
+		}
+	}
+}
+
+func TestCephCLIErrorFields(t *testing.T) {
+	_, exitErr := exec.Command("sh", "-c", "echo 'Error ENOENT: problem getting command descriptions from mds.a' >&2; exit 2").Output()
+	require.Error(t, exitErr)
+
+	args := []string{"-c", "/etc/ceph/ceph.conf", "-n", "client.admin", "tell", "mds.a", "status"}
+
+	for _, tt := range []struct {
+		name string
+		err  error
+		msg  string
+		want logrus.Fields
+	}{
+		{
+			name: "exit code",
+			err:  fmt.Errorf("failed getting mds stat: %w", newCephCLIError(args, exitErr)),
+			msg:  "failed getting mds stat: exit status 2: Error ENOENT: problem getting command descriptions from mds.a",
+			want: logrus.Fields{
+				"command":     "/usr/bin/ceph -c /etc/ceph/ceph.conf -n client.admin tell mds.a status",
+				"return_code": 2,
+				"stderr":      "Error ENOENT: problem getting command descriptions from mds.a",
+			},
+		},
+		{
+			name: "killed",
+			err:  newCephCLIError(args, context.DeadlineExceeded),
+			msg:  "context deadline exceeded",
+			want: logrus.Fields{
+				"command":     "/usr/bin/ceph -c /etc/ceph/ceph.conf -n client.admin tell mds.a status",
+				"return_code": -1,
+			},
+		},
+		{
+			name: "not a ceph CLI error",
+			err:  errors.New("fake error"),
+			msg:  "fake error",
+			want: logrus.Fields{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.EqualError(t, tt.err, tt.msg)
+			require.Equal(t, tt.want, cephCLIErrorFields(tt.err))
+		})
+	}
+}
+
+func TestCephCLIArgs(t *testing.T) {
+	require.Equal(t,
+		[]string{"-c", "/etc/ceph/ceph.conf", "-n", "client.admin", "mds", "stat"},
+		cephCLIArgs("/etc/ceph/ceph.conf", "admin", "", "mds", "stat"),
+	)
+	require.Equal(t,
+		[]string{"-c", "/etc/ceph/ceph.conf", "-n", "client.exporter", "--keyring", "/etc/ceph-exporter/keyring", "mds", "stat"},
+		cephCLIArgs("/etc/ceph/ceph.conf", "exporter", "/etc/ceph-exporter/keyring", "mds", "stat"),
+	)
+}
+
+func TestMDSManySeries(t *testing.T) {
+	// More daemons than the metrics that used to fit in the collector's
+	// buffer, none of their series may be dropped.
+	const daemons = 150
+
+	info := make([]string, 0, daemons)
+	for i := 0; i < daemons; i++ {
+		info = append(info, fmt.Sprintf(`"gid_%d": {"gid": %d, "name": "node%d", "rank": %d, "state": "up:standby-replay"}`, i, i, i, i))
+	}
+	mdsStat := []byte(fmt.Sprintf(`{"fsmap": {"filesystems": [{"mdsmap": {"info": {%s}, "fs_name": "fsA"}}]}}`, strings.Join(info, ",")))
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	mdsc := NewMDSCollector(e, false)
+	mdsc.runMDSStatFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return mdsStat, nil
+	}
+	mdsc.runCephHealthDetailFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"status": "HEALTH_OK", "checks": {}}`), nil
+	}
+	mdsc.runMDSMempoolPerfDumpFn = func(_ context.Context, cluster, user, keyring, mds string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runOSDBlocklistLsFn = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	mdsc.runFSGetFn = func(_ context.Context, cluster, user, keyring, fs string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	e.cc = map[string]versionedCollector{
+		"mds": mdsc,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Len(t, regexp.MustCompile(`(?m)^ceph_mds_daemon_state{`).FindAll(buf, -1), daemons)
+	require.Regexp(t, regexp.MustCompile(`ceph_mds_standby_replay_count{cluster="ceph",fs="fsA"} 150\n`), string(buf))
 }
@@ -15,6 +15,7 @@
 package ceph
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/google/go-cmp/cmp"
@@ -49,6 +50,29 @@ func (_m *MockConn) GetPoolStats(_a0 string) (*PoolStat, error) {
 	return r0, r1
 }
 
+// GetPoolStatsContext provides a mock function with given fields: _a0, _a1
+func (_m *MockConn) GetPoolStatsContext(_a0 context.Context, _a1 string) (*PoolStat, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *PoolStat
+	if rf, ok := ret.Get(0).(func(context.Context, string) *PoolStat); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PoolStat)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // MgrCommand provides a mock function with given fields: _a0
 func (_m *MockConn) MgrCommand(_a0 [][]byte) ([]byte, string, error) {
 	ret := _m.Called(_a0)
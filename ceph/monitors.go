@@ -37,6 +37,9 @@ type MonitorCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
 	// ClockSkew shows how far the monitor clocks have skewed from each other. This
 	// is an important metric because the functioning of Ceph's paxos depends on
 	// the clocks being aligned as close to each other as possible.
@@ -49,6 +52,10 @@ type MonitorCollector struct {
 	// metric can imply a significant issue in the cluster if it is not manually changed.
 	NodesinQuorum prometheus.Gauge
 
+	// InQuorum shows whether each monitor of the monmap is part of the quorum,
+	// a monitor dropping out of it is a single failure away from losing quorum.
+	InQuorum *prometheus.GaugeVec
+
 	// CephVersions exposes a view of the `ceph versions` command.
 	CephVersions *prometheus.GaugeVec
 
@@ -59,16 +66,17 @@ type MonitorCollector struct {
 // NewMonitorCollector creates an instance of the MonitorCollector and instantiates
 // the individual metrics that show information about the monitor processes.
 func NewMonitorCollector(exporter *Exporter) *MonitorCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	return &MonitorCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
 
 		ClockSkew: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "monitor_clock_skew_seconds",
 				Help:        "Clock skew the monitor node is incurring",
 				ConstLabels: labels,
@@ -77,7 +85,7 @@ func NewMonitorCollector(exporter *Exporter) *MonitorCollector {
 		),
 		Latency: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "monitor_latency_seconds",
 				Help:        "Latency the monitor node is incurring",
 				ConstLabels: labels,
@@ -86,15 +94,24 @@ func NewMonitorCollector(exporter *Exporter) *MonitorCollector {
 		),
 		NodesinQuorum: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "monitor_quorum_count",
 				Help:        "The total size of the monitor quorum",
 				ConstLabels: labels,
 			},
 		),
+		InQuorum: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "monitor_in_quorum",
+				Help:        "Whether the monitor is part of the quorum",
+				ConstLabels: labels,
+			},
+			[]string{"monitor"},
+		),
 		CephVersions: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "versions",
 				Help:        "Counts of current versioned daemons, parsed from `ceph versions`",
 				ConstLabels: labels,
@@ -103,7 +120,7 @@ func NewMonitorCollector(exporter *Exporter) *MonitorCollector {
 		),
 		CephFeatures: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "features",
 				Help:        "Counts of current client features, parsed from `ceph features`",
 				ConstLabels: labels,
@@ -117,6 +134,7 @@ func (m *MonitorCollector) collectorList() []prometheus.Collector {
 	return []prometheus.Collector{
 		m.ClockSkew,
 		m.Latency,
+		m.InQuorum,
 		m.CephVersions,
 		m.CephFeatures,
 	}
@@ -140,6 +158,16 @@ type cephMonitorStats struct {
 	Quorum []int `json:"quorum"`
 }
 
+type cephQuorumStatus struct {
+	Quorum []int `json:"quorum"`
+	MonMap struct {
+		Mons []struct {
+			Rank int    `json:"rank"`
+			Name string `json:"name"`
+		} `json:"mons"`
+	} `json:"monmap"`
+}
+
 // Note that this is a dict with repeating keys in Luminous
 type cephFeatureGroup struct {
 	Features string `json:"features"`
@@ -163,7 +191,33 @@ func (m *MonitorCollector) collect() error {
 			return err
 		}
 
-		return json.Unmarshal(buf, stats)
+		if err := json.Unmarshal(buf, stats); err != nil {
+			m.parseErrors.observe("mon")
+			return err
+		}
+
+		return nil
+	})
+
+	quorumStatus := &cephQuorumStatus{}
+	eg.Go(func() error {
+		// Ceph quorum status
+		cmd := m.cephQuorumStatusCommand()
+		buf, _, err := m.conn.MonCommand(cmd)
+		if err != nil {
+			m.logger.WithError(err).WithField(
+				"args", string(cmd),
+			).Error("error executing mon command")
+
+			return err
+		}
+
+		if err := json.Unmarshal(buf, quorumStatus); err != nil {
+			m.parseErrors.observe("mon")
+			return err
+		}
+
+		return nil
 	})
 
 	timeStats := &cephTimeSyncStatus{}
@@ -179,7 +233,12 @@ func (m *MonitorCollector) collect() error {
 			return err
 		}
 
-		return json.Unmarshal(buf, timeStats)
+		if err := json.Unmarshal(buf, timeStats); err != nil {
+			m.parseErrors.observe("mon")
+			return err
+		}
+
+		return nil
 	})
 
 	var versions map[string]map[string]float64
@@ -245,6 +304,7 @@ func (m *MonitorCollector) collect() error {
 			}
 
 			if err := json.Unmarshal(grp.Bytes(), &featureGroup); err != nil {
+				m.parseErrors.observe("mon")
 				return err
 			}
 
@@ -255,6 +315,7 @@ func (m *MonitorCollector) collect() error {
 	// Reset daemon specifc metrics; daemons can leave the cluster
 	m.Latency.Reset()
 	m.ClockSkew.Reset()
+	m.InQuorum.Reset()
 	m.CephVersions.Reset()
 	m.CephFeatures.Reset()
 
@@ -274,6 +335,18 @@ func (m *MonitorCollector) collect() error {
 
 	m.NodesinQuorum.Set(float64(len(stats.Quorum)))
 
+	inQuorum := make(map[int]bool, len(quorumStatus.Quorum))
+	for _, rank := range quorumStatus.Quorum {
+		inQuorum[rank] = true
+	}
+	for _, mon := range quorumStatus.MonMap.Mons {
+		if inQuorum[mon.Rank] {
+			m.InQuorum.WithLabelValues(mon.Name).Set(1)
+		} else {
+			m.InQuorum.WithLabelValues(mon.Name).Set(0)
+		}
+	}
+
 	// Ceph versions, one loop for each daemon.
 	// In a consistent cluster, there will only be one iteration (and label set) per daemon.
 	for daemon, vers := range versions {
@@ -312,6 +385,17 @@ func (m *MonitorCollector) cephUsageCommand() []byte {
 	return cmd
 }
 
+func (m *MonitorCollector) cephQuorumStatusCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "quorum_status",
+		"format": "json",
+	})
+	if err != nil {
+		m.logger.WithError(err).Panic("error marshalling ceph quorum_status")
+	}
+	return cmd
+}
+
 func (m *MonitorCollector) cephTimeSyncStatusCommand() []byte {
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "time-sync-status",
@@ -348,11 +432,11 @@ func (m *MonitorCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect extracts the given metrics from the Monitors and sends it to the prometheus
 // channel.
-func (m *MonitorCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (m *MonitorCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
 	m.logger.Debug("collecting ceph monitor metrics")
 	if err := m.collect(); err != nil {
 		m.logger.WithError(err).Error("error collecting ceph monitor metrics")
-		return
+		return err
 	}
 
 	for _, metric := range m.collectorList() {
@@ -362,4 +446,6 @@ func (m *MonitorCollector) Collect(ch chan<- prometheus.Metric, version *Version
 	for _, metric := range m.metricsList() {
 		ch <- metric
 	}
+
+	return nil
 }
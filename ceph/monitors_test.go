@@ -84,6 +84,60 @@ func TestMonitorCollector(t *testing.T) {
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			regexes: []*regexp.Regexp{
 				regexp.MustCompile(`ceph_monitor_quorum_count{cluster="ceph"} 5`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon01"} 1`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon05"} 1`),
+			},
+		},
+		{
+			input: `
+{
+    "fsid": "6C9BF03E-044E-4EEB-9C5F-145A54ECF7DB",
+    "election_epoch": 72,
+    "quorum": [
+        0,
+        1,
+        3
+    ],
+    "quorum_names": [
+        "test-mon01",
+        "test-mon02",
+        "test-mon04"
+    ],
+    "monmap": {
+        "epoch": 12,
+        "fsid": "6C9BF03E-044E-4EEB-9C5F-145A54ECF7DB",
+        "mons": [
+            {
+                "rank": 0,
+                "name": "test-mon01",
+                "addr": "10.123.1.25:6789\/0"
+            },
+            {
+                "rank": 1,
+                "name": "test-mon02",
+                "addr": "10.123.1.26:6789\/0"
+            },
+            {
+                "rank": 2,
+                "name": "test-mon03",
+                "addr": "10.123.2.25:6789\/0"
+            },
+            {
+                "rank": 3,
+                "name": "test-mon04",
+                "addr": "10.123.2.26:6789\/0"
+            }
+        ]
+    }
+}
+`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_monitor_quorum_count{cluster="ceph"} 3`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon01"} 1`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon02"} 1`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon03"} 0`),
+				regexp.MustCompile(`ceph_monitor_in_quorum{cluster="ceph",monitor="test-mon04"} 1`),
 			},
 		},
 	} {
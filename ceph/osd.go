@@ -17,6 +17,7 @@ package ceph
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -36,6 +37,19 @@ const (
 	scrubStateDeepScrubbing = 2
 
 	oldestInactivePGUpdatePeriod = 10 * time.Second
+
+	// deviceHealthUpdatePeriod is how often the device health metrics are
+	// refreshed. The mgr only scrapes the devices once a day by default,
+	// there's no point in asking it for every device on every scrape.
+	deviceHealthUpdatePeriod = time.Hour
+
+	// deviceHealthRetryPeriod is how soon a failed device health refresh
+	// is retried.
+	deviceHealthRetryPeriod = time.Minute
+
+	// deviceHealthConcurrency bounds the device get-health-metrics commands
+	// running at once during a refresh.
+	deviceHealthConcurrency = 4
 )
 
 // OSDCollector displays statistics about OSD in the Ceph cluster.
@@ -45,16 +59,42 @@ type OSDCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
 	// osdScrubCache holds the cache of previous PG scrubs
 	osdScrubCache map[int]int
 
 	// osdLabelsCache holds a cache of osd labels
 	osdLabelsCache map[int64]*cephOSDLabel
 
+	// configOverrideKeys are the config options whose overrides on the
+	// OSDs are counted.
+	configOverrideKeys []string
+
+	// pgDumps shares the PG dump with the other collectors of the scrape.
+	pgDumps *pgDumpCache
+
 	// oldestInactivePGMap keeps track of how long we've known
 	// a PG to not have an active state in it.
 	oldestInactivePGMap map[string]time.Time
 
+	// oldestInactivePGOnce starts tracking the inactive PGs on the first
+	// scrape, the exporters only describing the collector never do.
+	oldestInactivePGOnce sync.Once
+
+	// deviceHealthOnce starts refreshing the device health in the
+	// background on the first scrape.
+	deviceHealthOnce sync.Once
+	// deviceHealthMu protects deviceHealthCache.
+	deviceHealthMu sync.Mutex
+	// deviceHealthCache holds the health of the devices backing the OSDs,
+	// refreshed every deviceHealthUpdatePeriod.
+	deviceHealthCache []cephDeviceHealth
+
+	// now returns the current time, scrub ages are computed relative to it.
+	now func() time.Time
+
 	// CrushWeight is a persistent setting, and it affects how CRUSH assigns data to OSDs.
 	// It displays the CRUSH weight for the OSD
 	CrushWeight *prometheus.GaugeVec
@@ -136,13 +176,65 @@ type OSDCollector struct {
 	// AverageUtil displays average utilization in all OSDs
 	AverageUtil prometheus.Gauge
 
+	// PGCountStdDev displays the standard deviation of the number of
+	// placement groups on the OSDs that are in
+	PGCountStdDev prometheus.Gauge
+
+	// CrushClassTotalBytes displays the total bytes of the OSDs of each
+	// device class
+	CrushClassTotalBytes *prometheus.GaugeVec
+
+	// CrushClassUsedBytes displays the used bytes of the OSDs of each
+	// device class
+	CrushClassUsedBytes *prometheus.GaugeVec
+
+	// CrushClassAvailBytes displays the available bytes of the OSDs of
+	// each device class
+	CrushClassAvailBytes *prometheus.GaugeVec
+
 	// ScrubbingStateDesc depicts if an OSD is being scrubbed
 	// labeled by OSD
 	ScrubbingStateDesc *prometheus.Desc
 
+	// LastScrubAgeDesc displays the time in seconds since the least recently
+	// scrubbed PG an OSD is acting for was last scrubbed
+	LastScrubAgeDesc *prometheus.Desc
+
+	// LastDeepScrubAgeDesc displays the time in seconds since the least recently
+	// deep-scrubbed PG an OSD is acting for was last deep-scrubbed
+	LastDeepScrubAgeDesc *prometheus.Desc
+
 	// PGObjectsRecoveredDesc displays total number of objects recovered in a PG
 	PGObjectsRecoveredDesc *prometheus.Desc
 
+	// RADOSClassAllowedDesc displays whether the OSDs are allowed to load a
+	// RADOS class, RBD and RGW break without theirs
+	RADOSClassAllowedDesc *prometheus.Desc
+
+	// SlowOpsDesc displays whether the OSD is reported by the SLOW_OPS
+	// health check
+	SlowOpsDesc *prometheus.Desc
+
+	// CrushHostOSDsDesc displays the number of OSDs under each host of the
+	// CRUSH tree
+	CrushHostOSDsDesc *prometheus.Desc
+
+	// CrushSingleHostRiskDesc flags the hosts holding too large a share of
+	// the CRUSH weight of their root
+	CrushSingleHostRiskDesc *prometheus.Desc
+
+	// ConfigOverrideDesc displays the number of OSDs running with a value
+	// other than the default for a config option
+	ConfigOverrideDesc *prometheus.Desc
+
+	// DeviceHealthOKDesc displays whether the device backing the OSD passes
+	// its SMART self-assessment
+	DeviceHealthOKDesc *prometheus.Desc
+
+	// DeviceLifeRemainingDesc displays the estimated percentage of life left
+	// on the device backing the OSD
+	DeviceLifeRemainingDesc *prometheus.Desc
+
 	// OSDObjectsBackfilled displays average number of objects backfilled in an OSD
 	OSDObjectsBackfilled *prometheus.CounterVec
 
@@ -156,22 +248,26 @@ type OSDCollector struct {
 // NewOSDCollector creates an instance of the OSDCollector and instantiates the
 // individual metrics that show information about the OSD.
 func NewOSDCollector(exporter *Exporter) *OSDCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 	osdLabels := []string{"osd", "device_class", "host", "rack", "root"}
 	osdMetadataLabels := []string{"osd", "objectstore", "ceph_version_when_created", "created_at"}
 
 	o := &OSDCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
 
 		osdScrubCache:       make(map[int]int),
 		osdLabelsCache:      make(map[int64]*cephOSDLabel),
+		configOverrideKeys:  exporter.OSDConfigOverrideKeys,
 		oldestInactivePGMap: make(map[string]time.Time),
+		now:                 time.Now,
+		pgDumps:             exporter.sharedPGDumps(),
 
 		CrushWeight: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_crush_weight",
 				Help:        "OSD Crush Weight",
 				ConstLabels: labels,
@@ -181,7 +277,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Depth: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_depth",
 				Help:        "OSD Depth",
 				ConstLabels: labels,
@@ -191,7 +287,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Reweight: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_reweight",
 				Help:        "OSD Reweight",
 				ConstLabels: labels,
@@ -201,7 +297,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Bytes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_bytes",
 				Help:        "OSD Total Bytes",
 				ConstLabels: labels,
@@ -211,7 +307,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		UsedBytes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_used_bytes",
 				Help:        "OSD Used Storage in Bytes",
 				ConstLabels: labels,
@@ -221,7 +317,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		AvailBytes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_avail_bytes",
 				Help:        "OSD Available Storage in Bytes",
 				ConstLabels: labels,
@@ -231,7 +327,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Utilization: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_utilization",
 				Help:        "OSD Utilization",
 				ConstLabels: labels,
@@ -241,7 +337,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Variance: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_variance",
 				Help:        "OSD Variance",
 				ConstLabels: labels,
@@ -251,7 +347,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		Pgs: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_pgs",
 				Help:        "OSD Placement Group Count",
 				ConstLabels: labels,
@@ -261,7 +357,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		PgUpmapItemsTotal: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_pg_upmap_items_total",
 				Help:        "OSD PG-Upmap Exception Table Entry Count",
 				ConstLabels: labels,
@@ -270,7 +366,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		TotalBytes: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_total_bytes",
 				Help:        "OSD Total Storage Bytes",
 				ConstLabels: labels,
@@ -278,7 +374,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 		),
 		TotalUsedBytes: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_total_used_bytes",
 				Help:        "OSD Total Used Storage Bytes",
 				ConstLabels: labels,
@@ -287,7 +383,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		TotalAvailBytes: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_total_avail_bytes",
 				Help:        "OSD Total Available Storage Bytes ",
 				ConstLabels: labels,
@@ -296,16 +392,55 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		AverageUtil: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_average_utilization",
 				Help:        "OSD Average Utilization",
 				ConstLabels: labels,
 			},
 		),
 
+		PGCountStdDev: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "osd_pg_count_stddev",
+				Help:        "Standard deviation of the placement group count of the OSDs that are in",
+				ConstLabels: labels,
+			},
+		),
+
+		CrushClassTotalBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "crush_class_total_bytes",
+				Help:        "Total Storage Bytes of the OSDs of the device class",
+				ConstLabels: labels,
+			},
+			[]string{"class"},
+		),
+
+		CrushClassUsedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "crush_class_used_bytes",
+				Help:        "Used Storage Bytes of the OSDs of the device class",
+				ConstLabels: labels,
+			},
+			[]string{"class"},
+		),
+
+		CrushClassAvailBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "crush_class_available_bytes",
+				Help:        "Available Storage Bytes of the OSDs of the device class",
+				ConstLabels: labels,
+			},
+			[]string{"class"},
+		),
+
 		CommitLatency: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_perf_commit_latency_seconds",
 				Help:        "OSD Perf Commit Latency",
 				ConstLabels: labels,
@@ -315,7 +450,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		ApplyLatency: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_perf_apply_latency_seconds",
 				Help:        "OSD Perf Apply Latency",
 				ConstLabels: labels,
@@ -325,7 +460,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDIn: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_in",
 				Help:        "OSD In Status",
 				ConstLabels: labels,
@@ -335,7 +470,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDUp: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_up",
 				Help:        "OSD Up Status",
 				ConstLabels: labels,
@@ -345,7 +480,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDFullRatio: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_full_ratio",
 				Help:        "OSD Full Ratio Value",
 				ConstLabels: labels,
@@ -354,7 +489,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDNearFullRatio: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_near_full_ratio",
 				Help:        "OSD Near Full Ratio Value",
 				ConstLabels: labels,
@@ -363,7 +498,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDBackfillFullRatio: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_backfill_full_ratio",
 				Help:        "OSD Backfill Full Ratio Value",
 				ConstLabels: labels,
@@ -372,7 +507,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDFull: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_full",
 				Help:        "OSD Full Status",
 				ConstLabels: labels,
@@ -382,7 +517,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDNearFull: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_near_full",
 				Help:        "OSD Near Full Status",
 				ConstLabels: labels,
@@ -392,7 +527,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDBackfillFull: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_backfill_full",
 				Help:        "OSD Backfill Full Status",
 				ConstLabels: labels,
@@ -402,7 +537,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OSDMetadata: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_metadata",
 				Help:        "OSD Metadata",
 				ConstLabels: labels,
@@ -411,29 +546,92 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 		),
 
 		OSDDownDesc: prometheus.NewDesc(
-			fmt.Sprintf("%s_osd_down", cephNamespace),
+			fmt.Sprintf("%s_osd_down", namespace),
 			"Number of OSDs down in the cluster",
 			append([]string{"status"}, osdLabels...),
 			labels,
 		),
 
 		ScrubbingStateDesc: prometheus.NewDesc(
-			fmt.Sprintf("%s_osd_scrub_state", cephNamespace),
+			fmt.Sprintf("%s_osd_scrub_state", namespace),
 			"State of OSDs involved in a scrub",
 			osdLabels,
 			labels,
 		),
 
+		LastScrubAgeDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_pg_last_scrub_age_seconds", namespace),
+			"Time since the least recently scrubbed PG of the OSD was last scrubbed",
+			osdLabels,
+			labels,
+		),
+
+		LastDeepScrubAgeDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_pg_last_deep_scrub_age_seconds", namespace),
+			"Time since the least recently deep-scrubbed PG of the OSD was last deep-scrubbed",
+			osdLabels,
+			labels,
+		),
+
 		PGObjectsRecoveredDesc: prometheus.NewDesc(
-			fmt.Sprintf("%s_pg_objects_recovered", cephNamespace),
+			fmt.Sprintf("%s_pg_objects_recovered", namespace),
 			"Number of objects recovered in a PG",
 			[]string{"pgid"},
 			labels,
 		),
 
+		RADOSClassAllowedDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_rados_class_allowed", namespace),
+			"Whether osd_class_load_list allows the OSDs to load the RADOS class, not whether they did",
+			[]string{"class"},
+			labels,
+		),
+
+		SlowOpsDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_slow_ops", namespace),
+			"Whether the OSD is reported as having slow ops by the SLOW_OPS health check",
+			[]string{"osd"},
+			labels,
+		),
+
+		CrushHostOSDsDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_crush_host_failure_domain_osds", namespace),
+			"Number of OSDs under the host in the CRUSH tree",
+			[]string{"host"},
+			labels,
+		),
+
+		CrushSingleHostRiskDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_crush_single_host_risk", namespace),
+			"Whether the host holds more than a third of the CRUSH weight of its root",
+			[]string{"root", "host"},
+			labels,
+		),
+
+		ConfigOverrideDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_config_override", namespace),
+			"Number of OSDs running with a value other than the default for the config option",
+			[]string{"key"},
+			labels,
+		),
+
+		DeviceHealthOKDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_device_health_ok", namespace),
+			"Whether the device backing the OSD passes its SMART self-assessment",
+			[]string{"osd", "device"},
+			labels,
+		),
+
+		DeviceLifeRemainingDesc: prometheus.NewDesc(
+			fmt.Sprintf("%s_osd_device_life_remaining_percent", namespace),
+			"Estimated percentage of life left on the device backing the OSD",
+			[]string{"osd", "device"},
+			labels,
+		),
+
 		OSDObjectsBackfilled: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "osd_objects_backfilled",
 				Help:        "Average number of objects backfilled in an OSD",
 				ConstLabels: labels,
@@ -443,7 +641,7 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 
 		OldestInactivePG: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "pg_oldest_inactive",
 				Help:        "The amount of time in seconds that the oldest PG has been inactive for",
 				ConstLabels: labels,
@@ -451,7 +649,6 @@ func NewOSDCollector(exporter *Exporter) *OSDCollector {
 		),
 	}
 
-	go o.oldestInactivePGLoop()
 	return o
 }
 
@@ -471,6 +668,10 @@ func (o *OSDCollector) collectorList() []prometheus.Collector {
 		o.TotalUsedBytes,
 		o.TotalAvailBytes,
 		o.AverageUtil,
+		o.PGCountStdDev,
+		o.CrushClassTotalBytes,
+		o.CrushClassUsedBytes,
+		o.CrushClassAvailBytes,
 		o.CommitLatency,
 		o.ApplyLatency,
 		o.OSDIn,
@@ -490,6 +691,7 @@ func (o *OSDCollector) collectorList() []prometheus.Collector {
 type cephOSDDF struct {
 	OSDNodes []struct {
 		Name        string      `json:"name"`
+		DeviceClass string      `json:"device_class"`
 		CrushWeight json.Number `json:"crush_weight"`
 		Depth       json.Number `json:"depth"`
 		Reweight    json.Number `json:"reweight"`
@@ -585,6 +787,59 @@ type cephPGDumpBrief struct {
 	} `json:"pg_stats"`
 }
 
+// cephPGDump is the full PG dump, a superset of cephPGDumpBrief.
+type cephPGDump struct {
+	PGStats []struct {
+		PGID               string `json:"pgid"`
+		ActingPrimary      int64  `json:"acting_primary"`
+		Acting             []int  `json:"acting"`
+		State              string `json:"state"`
+		LastScrubStamp     string `json:"last_scrub_stamp"`
+		LastDeepScrubStamp string `json:"last_deep_scrub_stamp"`
+		StatSum            struct {
+			NumObjectsDegraded float64 `json:"num_objects_degraded"`
+			NumOMapKeys        float64 `json:"num_omap_keys"`
+			NumScrubErrors     float64 `json:"num_scrub_errors"`
+		} `json:"stat_sum"`
+	} `json:"pg_stats"`
+}
+
+// cephTimeLayouts are the layouts of the timestamps found in PG stats. Ceph
+// releases up to Nautilus omit the timezone, which is then assumed to be UTC.
+var cephTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999-0700",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999",
+}
+
+// parseCephTime parses a timestamp as emitted in PG stats.
+func parseCephTime(s string) (time.Time, error) {
+	for _, layout := range cephTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
+
+// scrubAge returns the time in seconds elapsed between the given scrub stamp
+// and now. PGs that were never scrubbed have a zero stamp and are reported
+// as not ok.
+func scrubAge(stamp string, now time.Time) (float64, bool) {
+	t, err := parseCephTime(stamp)
+	if err != nil || t.Unix() <= 0 {
+		return 0, false
+	}
+
+	age := now.Sub(t).Seconds()
+	if age < 0 {
+		age = 0
+	}
+
+	return age, true
+}
+
 type cephOSDLabel struct {
 	ID          int64   `json:"id"`
 	Name        string  `json:"name"`
@@ -621,9 +876,14 @@ func (o *OSDCollector) collectOSDDF() error {
 
 	osdDF := &cephOSDDF{}
 	if err := json.Unmarshal(buf, osdDF); err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 
+	// pgCounts holds the placement group count of the OSDs that are in,
+	// the out ones have none and would skew the deviation.
+	var pgCounts []float64
+
 	for _, node := range osdDF.OSDNodes {
 		lb := o.getOSDLabelFromName(node.Name)
 
@@ -669,6 +929,17 @@ func (o *OSDCollector) collectOSDDF() error {
 
 		o.AvailBytes.WithLabelValues(node.Name, lb.DeviceClass, lb.Host, lb.Rack, lb.Root).Set(availKB * 1024)
 
+		// osd df reports the device class since Luminous.
+		class := node.DeviceClass
+		if class == "" {
+			class = lb.DeviceClass
+		}
+		if class != "" {
+			o.CrushClassTotalBytes.WithLabelValues(class).Add(osdKB * 1024)
+			o.CrushClassUsedBytes.WithLabelValues(class).Add(usedKB * 1024)
+			o.CrushClassAvailBytes.WithLabelValues(class).Add(availKB * 1024)
+		}
+
 		util, err := node.Utilization.Float64()
 		if err != nil {
 			return err
@@ -690,8 +961,13 @@ func (o *OSDCollector) collectOSDDF() error {
 
 		o.Pgs.WithLabelValues(node.Name, lb.DeviceClass, lb.Host, lb.Rack, lb.Root).Set(pgs)
 
+		if reweight > 0 {
+			pgCounts = append(pgCounts, pgs)
+		}
 	}
 
+	o.PGCountStdDev.Set(stdDev(pgCounts))
+
 	totalKB, err := osdDF.Summary.TotalKB.Float64()
 	if err != nil {
 		return err
@@ -724,6 +1000,27 @@ func (o *OSDCollector) collectOSDDF() error {
 
 }
 
+// stdDev returns the population standard deviation of values, 0 if there
+// are none.
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+
+	return math.Sqrt(squares / float64(len(values)))
+}
+
 func (o *OSDCollector) collectOSDMetadata() error {
 	cmd := o.cephOSDMetadataCommand()
 	buf, _, err := o.conn.MonCommand(cmd)
@@ -737,6 +1034,7 @@ func (o *OSDCollector) collectOSDMetadata() error {
 
 	var osdMetadata []cephOSDMetadata
 	if err := json.Unmarshal(buf, &osdMetadata); err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 
@@ -760,6 +1058,7 @@ func (o *OSDCollector) collectOSDPerf() error {
 
 	osdPerf := &CephOSDPerfStat{}
 	if err := json.Unmarshal(buf, osdPerf); err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 
@@ -863,12 +1162,272 @@ func (o *OSDCollector) buildOSDLabelCache() error {
 
 	cache, err := buildOSDLabels(data)
 	if err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 	o.osdLabelsCache = cache
 	return nil
 }
 
+// collectSlowOps reports the OSDs named by the SLOW_OPS health check. The
+// check only carries the total number of slow ops, which can't be split per
+// daemon, and names at most 10 daemons. Every OSD in the CRUSH map that isn't
+// named reports 0.
+func (o *OSDCollector) collectSlowOps(ch chan<- prometheus.Metric) error {
+	cmd := o.cephHealthDetailCommand()
+	buf, _, err := o.conn.MonCommand(cmd)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return err
+	}
+
+	hc, err := parseHealthDetail(buf)
+	if err != nil {
+		o.parseErrors.observe("osd")
+		return err
+	}
+
+	slow := make(map[string]float64)
+	for _, label := range o.osdLabelsCache {
+		slow[label.Name] = 0
+	}
+
+	if check, ok := hc.Checks["SLOW_OPS"]; ok {
+		messages := []string{check.Summary.Message}
+		for _, detail := range check.Detail {
+			messages = append(messages, detail.Message)
+		}
+
+		for _, message := range messages {
+			for _, osd := range osdDetailRegex.FindAllString(message, -1) {
+				slow[osd] = 1
+			}
+		}
+	}
+
+	for osd, v := range slow {
+		ch <- prometheus.MustNewConstMetric(o.SlowOpsDesc, prometheus.GaugeValue, v, osd)
+	}
+
+	return nil
+}
+
+// crushSingleHostRiskRatio is the share of the CRUSH weight of a root above
+// which a host is flagged. With the usual 3 replicas spread across hosts, a
+// host holding more than a third of the weight can't be filled evenly and
+// takes a disproportionate amount of data down with it.
+const crushSingleHostRiskRatio = 1.0 / 3
+
+// collectCrushHostDistribution reports how the OSDs and their CRUSH weight
+// are spread across the hosts, based on the OSD label cache.
+func (o *OSDCollector) collectCrushHostDistribution(ch chan<- prometheus.Metric) {
+	type crushHost struct {
+		root, host string
+	}
+
+	osds := make(map[string]float64)
+	hostWeights := make(map[crushHost]float64)
+	rootWeights := make(map[string]float64)
+	for _, label := range o.osdLabelsCache {
+		if label.Host == "" {
+			continue
+		}
+
+		osds[label.Host]++
+		hostWeights[crushHost{label.Root, label.Host}] += label.CrushWeight
+		rootWeights[label.Root] += label.CrushWeight
+	}
+
+	for host, count := range osds {
+		ch <- prometheus.MustNewConstMetric(o.CrushHostOSDsDesc, prometheus.GaugeValue, count, host)
+	}
+
+	for h, weight := range hostWeights {
+		var risk float64
+		if total := rootWeights[h.root]; total > 0 && weight/total > crushSingleHostRiskRatio {
+			risk = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(o.CrushSingleHostRiskDesc, prometheus.GaugeValue, risk, h.root, h.host)
+	}
+}
+
+type cephDevice struct {
+	DevID     string   `json:"devid"`
+	Daemons   []string `json:"daemons"`
+	WearLevel *float64 `json:"wear_level"`
+}
+
+// cephDeviceSMART is the part of the smartctl output stored by the mgr
+// that we care about.
+type cephDeviceSMART struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	NVMeHealth *struct {
+		PercentageUsed *float64 `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+type cephDeviceHealth struct {
+	devID   string
+	daemons []string
+
+	// healthOK and lifeRemaining are nil when the device doesn't report
+	// them, e.g. virtual disks without SMART support.
+	healthOK      *float64
+	lifeRemaining *float64
+}
+
+// getDeviceHealth fetches the latest health metrics the mgr scraped for
+// the device, and derives the life remaining from them or from the wear
+// level reported by device ls.
+func (o *OSDCollector) getDeviceHealth(device cephDevice) (cephDeviceHealth, error) {
+	health := cephDeviceHealth{devID: device.DevID, daemons: device.Daemons}
+	if device.WearLevel != nil {
+		life := (1 - *device.WearLevel) * 100
+		health.lifeRemaining = &life
+	}
+
+	args := o.cephDeviceHealthMetricsCommand(device.DevID)
+	buf, _, err := o.conn.MgrCommand(args)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(bytes.Join(args, []byte(","))),
+		).Error("error executing mgr command")
+
+		return health, err
+	}
+
+	// The metrics are keyed by the time they were scraped at, in a format
+	// that sorts chronologically.
+	metrics := make(map[string]cephDeviceSMART)
+	if err := json.Unmarshal(buf, &metrics); err != nil {
+		o.parseErrors.observe("osd")
+		return health, err
+	}
+
+	var latest string
+	for stamp := range metrics {
+		if stamp > latest {
+			latest = stamp
+		}
+	}
+
+	smart, ok := metrics[latest]
+	if !ok {
+		return health, nil
+	}
+
+	if smart.SmartStatus != nil {
+		var v float64
+		if smart.SmartStatus.Passed {
+			v = 1
+		}
+		health.healthOK = &v
+	}
+
+	if health.lifeRemaining == nil && smart.NVMeHealth != nil && smart.NVMeHealth.PercentageUsed != nil {
+		life := 100 - *smart.NVMeHealth.PercentageUsed
+		if life < 0 {
+			life = 0
+		}
+		health.lifeRemaining = &life
+	}
+
+	return health, nil
+}
+
+func (o *OSDCollector) refreshDeviceHealth() error {
+	args := o.cephDeviceLsCommand()
+	buf, _, err := o.conn.MgrCommand(args)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(bytes.Join(args, []byte(","))),
+		).Error("error executing mgr command")
+
+		return err
+	}
+
+	var devices []cephDevice
+	if err := json.Unmarshal(buf, &devices); err != nil {
+		o.parseErrors.observe("osd")
+		return err
+	}
+
+	cache := make([]cephDeviceHealth, len(devices))
+	sem := make(chan struct{}, deviceHealthConcurrency)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, device cephDevice) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			health, err := o.getDeviceHealth(device)
+			if err != nil {
+				o.logger.WithError(err).WithField("device", device.DevID).Warning("failed to get device health metrics")
+			}
+			cache[i] = health
+		}(i, device)
+	}
+	wg.Wait()
+
+	o.deviceHealthMu.Lock()
+	o.deviceHealthCache = cache
+	o.deviceHealthMu.Unlock()
+	return nil
+}
+
+// deviceHealthLoop refreshes the device health every
+// deviceHealthUpdatePeriod, outside of the scrapes as it takes a command per
+// device.
+func (o *OSDCollector) deviceHealthLoop() {
+	for {
+		if err := o.refreshDeviceHealth(); err != nil {
+			o.logger.WithError(err).Warning("failed to refresh the device health")
+			time.Sleep(deviceHealthRetryPeriod)
+			continue
+		}
+
+		time.Sleep(deviceHealthUpdatePeriod)
+	}
+}
+
+// collectDeviceHealth reports the health of the devices backing the OSDs as
+// of the last background refresh, nothing until the first one completes.
+// Devices that don't report SMART data are skipped.
+func (o *OSDCollector) collectDeviceHealth(ch chan<- prometheus.Metric) {
+	o.deviceHealthOnce.Do(func() {
+		go o.deviceHealthLoop()
+	})
+
+	o.deviceHealthMu.Lock()
+	cache := o.deviceHealthCache
+	o.deviceHealthMu.Unlock()
+
+	for _, health := range cache {
+		for _, daemon := range health.daemons {
+			if !strings.HasPrefix(daemon, "osd.") {
+				continue
+			}
+
+			if health.healthOK != nil {
+				ch <- prometheus.MustNewConstMetric(o.DeviceHealthOKDesc, prometheus.GaugeValue, *health.healthOK, daemon, health.devID)
+			}
+			if health.lifeRemaining != nil {
+				ch <- prometheus.MustNewConstMetric(o.DeviceLifeRemainingDesc, prometheus.GaugeValue, *health.lifeRemaining, daemon, health.devID)
+			}
+		}
+	}
+}
+
 func (o *OSDCollector) getOSDLabelFromID(id int64) *cephOSDLabel {
 	if label, ok := o.osdLabelsCache[id]; ok {
 		return label
@@ -899,6 +1458,7 @@ func (o *OSDCollector) collectOSDTreeDown(ch chan<- prometheus.Metric) error {
 
 	osdDown := &cephOSDTreeDown{}
 	if err := json.Unmarshal(buff, osdDown); err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 
@@ -923,6 +1483,118 @@ func (o *OSDCollector) collectOSDTreeDown(ch chan<- prometheus.Metric) error {
 	return nil
 }
 
+// radosClasses are the RADOS classes the OSDs load by default, besides the
+// hello example class. RBD, RGW and CephFS rely on them.
+var radosClasses = []string{
+	"2pc_queue",
+	"cas",
+	"cephfs",
+	"cmpomap",
+	"fifo",
+	"journal",
+	"lock",
+	"log",
+	"numops",
+	"otp",
+	"queue",
+	"rbd",
+	"refcount",
+	"rgw",
+	"rgw_gc",
+	"timeindex",
+	"user",
+	"version",
+}
+
+// collectRADOSClasses reports which RADOS classes the OSDs are allowed to
+// load according to osd_class_load_list. A class missing from it fails to
+// load, breaking every call to its methods. Whether an allowed class did load
+// isn't reported by any command, the OSDs only log the failures.
+func (o *OSDCollector) collectRADOSClasses(ch chan<- prometheus.Metric) error {
+	cmd := o.cephConfigGetCommand("osd", "osd_class_load_list")
+	buff, _, err := o.conn.MonCommand(cmd)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return err
+	}
+
+	allowed := make(map[string]bool)
+	for _, class := range strings.FieldsFunc(string(buff), func(r rune) bool {
+		return strings.ContainsRune(";, \t\n", r)
+	}) {
+		allowed[class] = true
+	}
+
+	for _, class := range radosClasses {
+		available := 0.0
+		if allowed["*"] || allowed[class] {
+			available = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(o.RADOSClassAllowedDesc, prometheus.GaugeValue, available, class)
+	}
+
+	return nil
+}
+
+type cephConfigShowEntry struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// collectConfigOverrides counts, for each of the configured options, the
+// OSDs whose running value doesn't come from the defaults. This catches the
+// overrides from the config database, ceph.conf and injectargs alike.
+func (o *OSDCollector) collectConfigOverrides(ch chan<- prometheus.Metric) error {
+	if len(o.configOverrideKeys) == 0 {
+		return nil
+	}
+
+	overrides := make(map[string]float64)
+	for _, key := range o.configOverrideKeys {
+		overrides[key] = 0
+	}
+
+	var errs []error
+	for _, label := range o.osdLabelsCache {
+		args := o.cephConfigShowCommand(label.Name)
+		buf, _, err := o.conn.MgrCommand(args)
+		if err != nil {
+			// Down OSDs don't report their config, skip them.
+			o.logger.WithError(err).WithField(
+				"args", string(bytes.Join(args, []byte(","))),
+			).Debug("error executing mgr command")
+
+			continue
+		}
+
+		// config show only lists the options that aren't set to
+		// their default.
+		var entries []cephConfigShowEntry
+		if err := json.Unmarshal(buf, &entries); err != nil {
+			o.parseErrors.observe("osd")
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, entry := range entries {
+			if _, ok := overrides[entry.Name]; ok && entry.Source != "default" {
+				overrides[entry.Name]++
+			}
+		}
+	}
+
+	for key, count := range overrides {
+		ch <- prometheus.MustNewConstMetric(o.ConfigOverrideDesc, prometheus.GaugeValue, count, key)
+	}
+
+	return errors.Join(errs...)
+}
+
 func (o *OSDCollector) collectOSDDump() error {
 	cmd := o.cephOSDDump()
 	buff, _, err := o.conn.MonCommand(cmd)
@@ -936,6 +1608,7 @@ func (o *OSDCollector) collectOSDDump() error {
 
 	osdDump := cephOSDDump{}
 	if err := json.Unmarshal(buff, &osdDump); err != nil {
+		o.parseErrors.observe("osd")
 		return err
 	}
 
@@ -998,7 +1671,7 @@ func (o *OSDCollector) collectOSDDump() error {
 }
 
 func (o *OSDCollector) performPGDumpBrief() (*cephPGDumpBrief, error) {
-	args := o.cephPGDumpCommand()
+	args := o.cephPGDumpCommand("pgs_brief")
 	buf, _, err := o.conn.MgrCommand(args)
 	if err != nil {
 		o.logger.WithError(err).WithField(
@@ -1010,18 +1683,103 @@ func (o *OSDCollector) performPGDumpBrief() (*cephPGDumpBrief, error) {
 
 	pgDumpBrief := cephPGDumpBrief{}
 	if err := json.Unmarshal(buf, &pgDumpBrief); err != nil {
+		o.parseErrors.observe("osd")
 		return nil, err
 	}
 
 	return &pgDumpBrief, nil
 }
 
+// pgDumpCache shares the full PG dump, the most expensive mgr command, among
+// the collectors of a scrape.
+type pgDumpCache struct {
+	conn        Conn
+	logger      *logrus.Logger
+	parseErrors *ParseErrorsCollector
+
+	mu      sync.Mutex
+	fetched bool
+	dump    *cephPGDump
+	err     error
+}
+
+// newPGDumpCache returns a pgDumpCache fetching the PG dump through the
+// connection of the exporter.
+func newPGDumpCache(exporter *Exporter) *pgDumpCache {
+	return &pgDumpCache{
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+	}
+}
+
+// get returns the PG dump of the scrape, fetching it on the first call. A
+// dump that can't be parsed counts as a parse error of the given collector,
+// the first one to ask for it.
+func (c *pgDumpCache) get(collector string) (*cephPGDump, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetched {
+		c.dump, c.err = c.fetch(collector)
+		c.fetched = true
+	}
+
+	return c.dump, c.err
+}
+
+func (c *pgDumpCache) fetch(collector string) (*cephPGDump, error) {
+	args := c.cephPGDumpCommand()
+	buf, _, err := c.conn.MgrCommand(args)
+	if err != nil {
+		c.logger.WithError(err).WithField(
+			"args", string(bytes.Join(args, []byte(","))),
+		).Error("error executing mgr command")
+
+		return nil, err
+	}
+
+	pgDump := cephPGDump{}
+	if err := json.Unmarshal(buf, &pgDump); err != nil {
+		c.parseErrors.observe(collector)
+		return nil, err
+	}
+
+	return &pgDump, nil
+}
+
+func (c *pgDumpCache) cephPGDumpCommand() [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix":       "pg dump",
+		"dumpcontents": []string{"pgs"},
+		"format":       jsonFormat,
+	})
+	if err != nil {
+		c.logger.WithError(err).Panic("error marshalling ceph pg dump")
+	}
+	return [][]byte{cmd}
+}
+
+// reset drops the PG dump, the next scrape fetches it again.
+func (c *pgDumpCache) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetched, c.dump, c.err = false, nil, nil
+}
+
 func (o *OSDCollector) collectOSDScrubState(ch chan<- prometheus.Metric) error {
-	pgDumpBrief, err := o.performPGDumpBrief()
+	pgDump, err := o.pgDumps.get("osd")
 	if err != nil {
 		return err
 	}
 
+	now := o.now()
+	scrubAges := make(map[int]float64)
+	deepScrubAges := make(map[int]float64)
+
 	// need to reset the PG scrub state since the scrub might have ended within
 	// the last prom scrape interval.
 	// This forces us to report scrub state on all previously discovered OSDs We
@@ -1031,7 +1789,7 @@ func (o *OSDCollector) collectOSDScrubState(ch chan<- prometheus.Metric) error {
 		o.osdScrubCache[i] = scrubStateIdle
 	}
 
-	for _, pg := range pgDumpBrief.PGStats {
+	for _, pg := range pgDump.PGStats {
 		if strings.Contains(pg.State, "scrubbing") {
 			scrubState := scrubStateScrubbing
 			if strings.Contains(pg.State, "deep") {
@@ -1042,6 +1800,48 @@ func (o *OSDCollector) collectOSDScrubState(ch chan<- prometheus.Metric) error {
 				o.osdScrubCache[osd] = scrubState
 			}
 		}
+
+		if age, ok := scrubAge(pg.LastScrubStamp, now); ok {
+			for _, osd := range pg.Acting {
+				if age >= scrubAges[osd] {
+					scrubAges[osd] = age
+				}
+			}
+		}
+
+		if age, ok := scrubAge(pg.LastDeepScrubStamp, now); ok {
+			for _, osd := range pg.Acting {
+				if age >= deepScrubAges[osd] {
+					deepScrubAges[osd] = age
+				}
+			}
+		}
+	}
+
+	for i, v := range scrubAges {
+		lb := o.getOSDLabelFromID(int64(i))
+		ch <- prometheus.MustNewConstMetric(
+			o.LastScrubAgeDesc,
+			prometheus.GaugeValue,
+			v,
+			fmt.Sprintf(osdLabelFormat, i),
+			lb.DeviceClass,
+			lb.Host,
+			lb.Rack,
+			lb.Root)
+	}
+
+	for i, v := range deepScrubAges {
+		lb := o.getOSDLabelFromID(int64(i))
+		ch <- prometheus.MustNewConstMetric(
+			o.LastDeepScrubAgeDesc,
+			prometheus.GaugeValue,
+			v,
+			fmt.Sprintf(osdLabelFormat, i),
+			lb.DeviceClass,
+			lb.Host,
+			lb.Rack,
+			lb.Root)
 	}
 
 	for i, v := range o.osdScrubCache {
@@ -1071,6 +1871,31 @@ func (o *OSDCollector) cephOSDDump() []byte {
 	return cmd
 }
 
+// cephConfigGetCommand gets the value of a config option as plain text.
+func (o *OSDCollector) cephConfigGetCommand(who, key string) []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "config get",
+		"who":    who,
+		"key":    key,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph config get")
+	}
+	return cmd
+}
+
+func (o *OSDCollector) cephConfigShowCommand(who string) [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "config show",
+		"who":    who,
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph config show")
+	}
+	return [][]byte{cmd}
+}
+
 func (o *OSDCollector) cephOSDDFCommand() [][]byte {
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "osd df",
@@ -1104,6 +1929,18 @@ func (o *OSDCollector) cephOSDMetadataCommand() []byte {
 	return cmd
 }
 
+func (o *OSDCollector) cephHealthDetailCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "health",
+		"detail": "detail",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph health detail")
+	}
+	return cmd
+}
+
 func (o *OSDCollector) cephOSDTreeCommand(states ...string) []byte {
 	req := map[string]interface{}{
 		"prefix": "osd tree",
@@ -1120,10 +1957,10 @@ func (o *OSDCollector) cephOSDTreeCommand(states ...string) []byte {
 	return cmd
 }
 
-func (o *OSDCollector) cephPGDumpCommand() [][]byte {
+func (o *OSDCollector) cephPGDumpCommand(contents string) [][]byte {
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix":       "pg dump",
-		"dumpcontents": []string{"pgs_brief"},
+		"dumpcontents": []string{contents},
 		"format":       jsonFormat,
 	})
 	if err != nil {
@@ -1132,6 +1969,29 @@ func (o *OSDCollector) cephPGDumpCommand() [][]byte {
 	return [][]byte{cmd}
 }
 
+func (o *OSDCollector) cephDeviceLsCommand() [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "device ls",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph device ls")
+	}
+	return [][]byte{cmd}
+}
+
+func (o *OSDCollector) cephDeviceHealthMetricsCommand(devID string) [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "device get-health-metrics",
+		"devid":  devID,
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph device get-health-metrics")
+	}
+	return [][]byte{cmd}
+}
+
 func (o *OSDCollector) oldestInactivePGLoop() {
 	for {
 		pgDumpBrief, err := o.performPGDumpBrief()
@@ -1182,12 +2042,25 @@ func (o *OSDCollector) Describe(ch chan<- *prometheus.Desc) {
 	}
 	ch <- o.OSDDownDesc
 	ch <- o.ScrubbingStateDesc
+	ch <- o.LastScrubAgeDesc
+	ch <- o.LastDeepScrubAgeDesc
 	ch <- o.PGObjectsRecoveredDesc
+	ch <- o.RADOSClassAllowedDesc
+	ch <- o.SlowOpsDesc
+	ch <- o.CrushHostOSDsDesc
+	ch <- o.CrushSingleHostRiskDesc
+	ch <- o.ConfigOverrideDesc
+	ch <- o.DeviceHealthOKDesc
+	ch <- o.DeviceLifeRemainingDesc
 }
 
 // Collect sends all the collected metrics to the provided Prometheus channel.
 // It requires the caller to handle synchronization.
-func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	o.oldestInactivePGOnce.Do(func() {
+		go o.oldestInactivePGLoop()
+	})
+
 	// Reset daemon specific metrics; daemons can leave the cluster
 	o.CrushWeight.Reset()
 	o.Depth.Reset()
@@ -1198,12 +2071,17 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 	o.Utilization.Reset()
 	o.Variance.Reset()
 	o.Pgs.Reset()
+	o.CrushClassTotalBytes.Reset()
+	o.CrushClassUsedBytes.Reset()
+	o.CrushClassAvailBytes.Reset()
 	o.CommitLatency.Reset()
 	o.ApplyLatency.Reset()
 	o.OSDIn.Reset()
 	o.OSDUp.Reset()
 	o.OSDMetadata.Reset()
-	o.buildOSDLabelCache()
+	errs := &collectErrors{}
+	errs.add(o.buildOSDLabelCache())
+	o.collectCrushHostDistribution(ch)
 
 	localWg := &sync.WaitGroup{}
 
@@ -1212,6 +2090,7 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDPerf(); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD perf metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1220,6 +2099,7 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDMetadata(); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD metadata metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1228,6 +2108,7 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDDump(); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD dump metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1236,6 +2117,7 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDDF(); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD df metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1244,6 +2126,7 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDTreeDown(ch); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD tree down metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1252,6 +2135,36 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 		defer localWg.Done()
 		if err := o.collectOSDScrubState(ch); err != nil {
 			o.logger.WithError(err).Error("error collecting OSD scrub metrics")
+			errs.add(err)
+		}
+	}()
+
+	localWg.Add(1)
+	go func() {
+		defer localWg.Done()
+		if err := o.collectSlowOps(ch); err != nil {
+			o.logger.WithError(err).Error("error collecting OSD slow ops metrics")
+			errs.add(err)
+		}
+	}()
+
+	localWg.Add(1)
+	go func() {
+		defer localWg.Done()
+		if err := o.collectRADOSClasses(ch); err != nil {
+			o.logger.WithError(err).Error("error collecting RADOS class metrics")
+			errs.add(err)
+		}
+	}()
+
+	o.collectDeviceHealth(ch)
+
+	localWg.Add(1)
+	go func() {
+		defer localWg.Done()
+		if err := o.collectConfigOverrides(ch); err != nil {
+			o.logger.WithError(err).Error("error collecting OSD config override metrics")
+			errs.add(err)
 		}
 	}()
 
@@ -1260,4 +2173,6 @@ func (o *OSDCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
 	for _, metric := range o.collectorList() {
 		metric.Collect(ch)
 	}
+
+	return errs.err()
 }
@@ -0,0 +1,323 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// osdLatencyCommandTimeout bounds the duration of every command sent
+	// to an OSD.
+	osdLatencyCommandTimeout = 30 * time.Second
+
+	// osdLatencyConcurrency is how many OSDs are asked for their perf
+	// counters at once.
+	osdLatencyConcurrency = 16
+)
+
+// runOSDPerfDump will dump the OSD perf counters of the OSD.
+func runOSDPerfDump(ctx context.Context, config, user, keyring, osd string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", osd, "perf", "dump", "osd")...)
+}
+
+// runOSDPerfHistogramDump will dump the OSD perf histograms of the OSD.
+func runOSDPerfHistogramDump(ctx context.Context, config, user, keyring, osd string) ([]byte, error) {
+	return runCephCLI(ctx, cephCLIArgs(config, user, keyring, "tell", osd, "perf", "histogram", "dump", "osd")...)
+}
+
+// OSDLatencyCollector reports the op latencies of the OSDs as histograms,
+// from the perf counters of every up OSD. It shells out to the ceph CLI
+// twice per OSD, which is why it is disabled by default.
+type OSDLatencyCollector struct {
+	conn    Conn
+	config  string
+	user    string
+	keyring string
+	logger  *logrus.Logger
+
+	// scrapeTime accounts for the time spent running the ceph CLI.
+	scrapeTime *ScrapeTimeCollector
+
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// ReadLatency reports the latency of the client reads served by the
+	// OSD.
+	ReadLatency *prometheus.Desc
+
+	// WriteLatency reports the latency of the client writes served by the
+	// OSD.
+	WriteLatency *prometheus.Desc
+
+	runOSDPerfDumpFn          func(context.Context, string, string, string, string) ([]byte, error)
+	runOSDPerfHistogramDumpFn func(context.Context, string, string, string, string) ([]byte, error)
+}
+
+// NewOSDLatencyCollector creates a new OSDLatencyCollector.
+func NewOSDLatencyCollector(exporter *Exporter) *OSDLatencyCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &OSDLatencyCollector{
+		conn:        exporter.Conn,
+		config:      exporter.Config,
+		user:        exporter.User,
+		keyring:     exporter.Keyring,
+		logger:      exporter.Logger,
+		scrapeTime:  exporter.scrapeTime,
+		parseErrors: exporter.parseErrors,
+
+		ReadLatency: prometheus.NewDesc(fmt.Sprintf("%s_osd_op_r_latency_seconds", namespace), "Latency of the client reads served by the OSD",
+			[]string{"osd"}, labels,
+		),
+		WriteLatency: prometheus.NewDesc(fmt.Sprintf("%s_osd_op_w_latency_seconds", namespace), "Latency of the client writes served by the OSD",
+			[]string{"osd"}, labels,
+		),
+
+		runOSDPerfDumpFn:          runOSDPerfDump,
+		runOSDPerfHistogramDumpFn: runOSDPerfHistogramDump,
+	}
+}
+
+// cephLatencyCounter is a perf counter of type time average. The sum is
+// in seconds, releases that don't report it only have the average.
+type cephLatencyCounter struct {
+	AvgCount float64  `json:"avgcount"`
+	Sum      *float64 `json:"sum"`
+	AvgTime  *float64 `json:"avgtime"`
+}
+
+// sum returns the total time accounted for by the counter in seconds.
+func (c cephLatencyCounter) sum() float64 {
+	switch {
+	case c.Sum != nil:
+		return *c.Sum
+	case c.AvgTime != nil:
+		return *c.AvgTime * c.AvgCount
+	default:
+		return 0
+	}
+}
+
+type cephOSDPerfDump struct {
+	OSD struct {
+		OpRLatency cephLatencyCounter `json:"op_r_latency"`
+		OpWLatency cephLatencyCounter `json:"op_w_latency"`
+	} `json:"osd"`
+}
+
+// cephPerfHistogram is a 2D perf histogram, values[x][y] counts the events
+// that fell in the bucket x of the first axis and y of the second one.
+type cephPerfHistogram struct {
+	Axes []struct {
+		Name      string  `json:"name"`
+		Min       float64 `json:"min"`
+		QuantSize float64 `json:"quant_size"`
+		Buckets   int     `json:"buckets"`
+		ScaleType string  `json:"scale_type"`
+	} `json:"axes"`
+	Values [][]uint64 `json:"values"`
+}
+
+type cephOSDPerfHistogramDump struct {
+	OSD struct {
+		OpRLatency *cephPerfHistogram `json:"op_r_latency_out_bytes_histogram"`
+		OpWLatency *cephPerfHistogram `json:"op_w_latency_in_bytes_histogram"`
+	} `json:"osd"`
+}
+
+// latencyBuckets returns the cumulative counts of the latency axis, summed
+// over the request sizes, keyed by their upper bound in seconds, along with
+// the total count.
+//
+// The first bucket of an axis counts the values below min, the last one the
+// values past the previous buckets. In between, the buckets are quant_size
+// wide on a linear scale, while on a log2 scale the first one is quant_size
+// wide and every next one twice as wide as the previous.
+func (h *cephPerfHistogram) latencyBuckets() (map[float64]uint64, uint64, error) {
+	if len(h.Axes) == 0 {
+		return nil, 0, fmt.Errorf("histogram has no axes")
+	}
+
+	axis := h.Axes[0]
+	if len(h.Values) != axis.Buckets {
+		return nil, 0, fmt.Errorf("histogram has %d latency buckets, expected %d", len(h.Values), axis.Buckets)
+	}
+
+	buckets := make(map[float64]uint64, axis.Buckets)
+	var count uint64
+	for i, row := range h.Values {
+		for _, v := range row {
+			count += v
+		}
+
+		if i == axis.Buckets-1 {
+			break
+		}
+
+		upperBound := axis.Min
+		switch {
+		case i == 0:
+		case axis.ScaleType == "log2":
+			upperBound += axis.QuantSize * math.Pow(2, float64(i-1))
+		default:
+			upperBound += axis.QuantSize * float64(i)
+		}
+
+		// The latencies are in nanoseconds, even though the axis is
+		// named "Latency (usec)".
+		buckets[upperBound/1e9] = count
+	}
+
+	return buckets, count, nil
+}
+
+func (o *OSDLatencyCollector) cephOSDDumpCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "osd dump",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph osd dump")
+	}
+	return cmd
+}
+
+// upOSDs returns the names of the OSDs that are up, the others would only
+// make the commands time out.
+func (o *OSDLatencyCollector) upOSDs() ([]string, error) {
+	cmd := o.cephOSDDumpCommand()
+	buf, _, err := o.conn.MonCommand(cmd)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return nil, err
+	}
+
+	osdDump := cephOSDDump{}
+	if err := json.Unmarshal(buf, &osdDump); err != nil {
+		o.parseErrors.observe("osdLatency")
+		return nil, err
+	}
+
+	var osds []string
+	for _, osd := range osdDump.OSDs {
+		if up, err := osd.Up.Int64(); err != nil || up != 1 {
+			continue
+		}
+		osds = append(osds, fmt.Sprintf(osdLabelFormat, osd.OSD.String()))
+	}
+
+	return osds, nil
+}
+
+// collectOSD sends the latency histograms of the given OSD. The buckets
+// come from the perf histograms, the sum from the perf counters. Releases
+// without perf histograms get histograms without buckets.
+func (o *OSDLatencyCollector) collectOSD(ch chan<- prometheus.Metric, osd string) {
+	ctx, cancel := context.WithTimeout(context.Background(), osdLatencyCommandTimeout)
+	defer cancel()
+
+	start := time.Now()
+	data, err := o.runOSDPerfDumpFn(ctx, o.config, o.user, o.keyring, osd)
+	o.scrapeTime.observeCLI(start)
+	if err != nil {
+		o.logger.WithField("osd", osd).WithFields(cephCLIErrorFields(err)).WithError(err).Error("failed getting perf dump from osd")
+		return
+	}
+
+	perfDump := &cephOSDPerfDump{}
+	if err := json.Unmarshal(data, perfDump); err != nil {
+		o.parseErrors.observe("osdLatency")
+		o.logger.WithField("osd", osd).WithError(err).Error("failed unmarshalling osd perf dump")
+		return
+	}
+
+	histDump := &cephOSDPerfHistogramDump{}
+
+	start = time.Now()
+	data, err = o.runOSDPerfHistogramDumpFn(ctx, o.config, o.user, o.keyring, osd)
+	o.scrapeTime.observeCLI(start)
+	if err != nil {
+		o.logger.WithField("osd", osd).WithFields(cephCLIErrorFields(err)).WithError(err).Debug("failed getting perf histogram dump from osd")
+	} else if err := json.Unmarshal(data, histDump); err != nil {
+		o.parseErrors.observe("osdLatency")
+		o.logger.WithField("osd", osd).WithError(err).Error("failed unmarshalling osd perf histogram dump")
+	}
+
+	for _, latency := range []struct {
+		desc      *prometheus.Desc
+		counter   cephLatencyCounter
+		histogram *cephPerfHistogram
+	}{
+		{o.ReadLatency, perfDump.OSD.OpRLatency, histDump.OSD.OpRLatency},
+		{o.WriteLatency, perfDump.OSD.OpWLatency, histDump.OSD.OpWLatency},
+	} {
+		count, buckets := uint64(latency.counter.AvgCount), map[float64]uint64(nil)
+		if latency.histogram != nil {
+			b, c, err := latency.histogram.latencyBuckets()
+			if err != nil {
+				o.parseErrors.observe("osdLatency")
+				o.logger.WithField("osd", osd).WithError(err).Error("failed parsing osd perf histogram")
+			} else {
+				count, buckets = c, b
+			}
+		}
+
+		ch <- prometheus.MustNewConstHistogram(latency.desc, count, latency.counter.sum(), buckets, osd)
+	}
+}
+
+// Describe sends the descriptors of the OSD latency metrics to the provided
+// channel.
+func (o *OSDLatencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- o.ReadLatency
+	ch <- o.WriteLatency
+}
+
+// Collect sends the OSD latency metrics to the provided channel.
+func (o *OSDLatencyCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	osds, err := o.upOSDs()
+	if err != nil {
+		o.logger.WithError(err).Error("error collecting OSD latency metrics")
+		return err
+	}
+
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, osdLatencyConcurrency)
+	for _, osd := range osds {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(osd string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			o.collectOSD(ch, osd)
+		}(osd)
+	}
+	wg.Wait()
+
+	return nil
+}
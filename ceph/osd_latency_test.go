@@ -0,0 +1,192 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSDLatencyCollector(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		perfDump       map[string]string
+		histogramDump  map[string]string
+		regexes        []*regexp.Regexp
+		reMatchUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "perf histograms",
+			perfDump: map[string]string{
+				"osd.0": `{"osd":{"op_r_latency":{"avgcount":10,"sum":0.0015,"avgtime":0.00015},"op_w_latency":{"avgcount":6,"sum":0.0006,"avgtime":0.0001}}}`,
+				"osd.1": `{"osd":{"op_r_latency":{"avgcount":0,"sum":0,"avgtime":0},"op_w_latency":{"avgcount":0,"sum":0,"avgtime":0}}}`,
+			},
+			histogramDump: map[string]string{
+				"osd.0": `
+{
+	"osd": {
+		"op_r_latency_out_bytes_histogram": {
+			"axes": [
+				{"name": "Latency (usec)", "min": 0, "quant_size": 100000, "buckets": 4, "scale_type": "log2"},
+				{"name": "Request size (bytes)", "min": 0, "quant_size": 512, "buckets": 2, "scale_type": "log2"}
+			],
+			"values": [[0, 0], [5, 1], [2, 0], [1, 1]]
+		},
+		"op_w_latency_in_bytes_histogram": {
+			"axes": [
+				{"name": "Latency (usec)", "min": 0, "quant_size": 50000, "buckets": 3, "scale_type": "linear"},
+				{"name": "Request size (bytes)", "min": 0, "quant_size": 512, "buckets": 1, "scale_type": "log2"}
+			],
+			"values": [[1], [2], [3]]
+		}
+	}
+}`,
+				"osd.1": `{"osd":{}}`,
+			},
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="0"} 0`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="0.0001"} 6`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="0.0002"} 8`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="\+Inf"} 10`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_sum{cluster="ceph",osd="osd.0"} 0.0015`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_count{cluster="ceph",osd="osd.0"} 10`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="0"} 1`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="5e-05"} 3`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="\+Inf"} 6`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_sum{cluster="ceph",osd="osd.0"} 0.0006`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_count{cluster="ceph",osd="osd.1"} 0`),
+			},
+			reMatchUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`osd="osd.2"`),
+			},
+		},
+		{
+			name: "no perf histograms and no sum",
+			perfDump: map[string]string{
+				"osd.0": `{"osd":{"op_r_latency":{"avgcount":4,"avgtime":0.25},"op_w_latency":{"avgcount":2,"avgtime":0.5}}}`,
+				"osd.1": `{"osd":{"op_r_latency":{"avgcount":0,"avgtime":0},"op_w_latency":{"avgcount":0,"avgtime":0}}}`,
+			},
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="\+Inf"} 4`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_sum{cluster="ceph",osd="osd.0"} 1`),
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_count{cluster="ceph",osd="osd.0"} 4`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_sum{cluster="ceph",osd="osd.0"} 1`),
+				regexp.MustCompile(`ceph_osd_op_w_latency_seconds_count{cluster="ceph",osd="osd.0"} 2`),
+			},
+			reMatchUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_bucket{cluster="ceph",osd="osd.0",le="0`),
+			},
+		},
+		{
+			name: "mismatched perf histogram",
+			perfDump: map[string]string{
+				"osd.0": `{"osd":{"op_r_latency":{"avgcount":4,"sum":1,"avgtime":0.25},"op_w_latency":{"avgcount":0,"sum":0,"avgtime":0}}}`,
+				"osd.1": `{"osd":{"op_r_latency":{"avgcount":0,"sum":0,"avgtime":0},"op_w_latency":{"avgcount":0,"sum":0,"avgtime":0}}}`,
+			},
+			histogramDump: map[string]string{
+				"osd.0": `{"osd":{"op_r_latency_out_bytes_histogram":{"axes":[{"min":0,"quant_size":100000,"buckets":32,"scale_type":"log2"}],"values":[[1]]}}}`,
+			},
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_op_r_latency_seconds_count{cluster="ceph",osd="osd.0"} 4`),
+				regexp.MustCompile(`ceph_collector_parse_errors_total{cluster="ceph",collector="osdLatency"} [12]\n`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "osd dump",
+					"format": "json",
+				})
+			})).Return([]byte(`
+{
+	"osds": [
+		{"osd": 0, "up": 1, "in": 1},
+		{"osd": 1, "up": 1, "in": 1},
+		{"osd": 2, "up": 0, "in": 0}
+	]
+}`), "", nil)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.parseErrors = NewParseErrorsCollector(e)
+			osdLatency := NewOSDLatencyCollector(e)
+			osdLatency.runOSDPerfDumpFn = func(_ context.Context, cluster, user, keyring, osd string) ([]byte, error) {
+				if out, ok := tt.perfDump[osd]; ok {
+					return []byte(out), nil
+				}
+				return nil, errors.New("unexpected osd")
+			}
+			osdLatency.runOSDPerfHistogramDumpFn = func(_ context.Context, cluster, user, keyring, osd string) ([]byte, error) {
+				if out, ok := tt.histogramDump[osd]; ok {
+					return []byte(out), nil
+				}
+				return nil, errors.New("unrecognized command")
+			}
+			e.cc = map[string]versionedCollector{
+				"parseErrors": e.parseErrors,
+				"osdLatency":  osdLatency,
+			}
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			scrape := func() []byte {
+				resp, err := http.Get(server.URL)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				buf, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				return buf
+			}
+
+			// The parse errors of a scrape may only be reported by the next
+			// one, see TestParseErrorsCollector.
+			scrape()
+			buf := scrape()
+
+			for _, re := range tt.regexes {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reMatchUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
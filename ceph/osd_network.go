@@ -0,0 +1,146 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// OSDNetworkCollector reports the heartbeat ping times between the OSDs,
+// as aggregated by the active mgr. It reports every pair of OSDs that ping
+// each other, which is why it is disabled by default.
+type OSDNetworkCollector struct {
+	conn   Conn
+	logger *logrus.Logger
+
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// HeartbeatPing reports the average heartbeat ping time over the last
+	// minute from an OSD to one of its peers.
+	HeartbeatPing *prometheus.Desc
+
+	// HeartbeatMaxPing reports the highest average heartbeat ping time
+	// over the last minute from an OSD to any of its peers.
+	HeartbeatMaxPing *prometheus.Desc
+}
+
+// NewOSDNetworkCollector creates a new OSDNetworkCollector.
+func NewOSDNetworkCollector(exporter *Exporter) *OSDNetworkCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &OSDNetworkCollector{
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+
+		HeartbeatPing: prometheus.NewDesc(fmt.Sprintf("%s_osd_heartbeat_ping_seconds", namespace), "Average heartbeat ping time over the last minute from the OSD to its peer",
+			[]string{"from_osd", "to_osd", "interface"}, labels,
+		),
+		HeartbeatMaxPing: prometheus.NewDesc(fmt.Sprintf("%s_osd_heartbeat_max_ping_seconds", namespace), "Highest average heartbeat ping time over the last minute from the OSD to any of its peers",
+			[]string{"osd", "interface"}, labels,
+		),
+	}
+}
+
+// cephOSDNetwork is the output of dump_osd_network, the ping times are in
+// milliseconds.
+type cephOSDNetwork struct {
+	Entries []struct {
+		Stale     bool   `json:"stale"`
+		FromOSD   int    `json:"from osd"`
+		ToOSD     int    `json:"to osd"`
+		Interface string `json:"interface"`
+		Average   struct {
+			OneMin float64 `json:"1min"`
+		} `json:"average"`
+	} `json:"entries"`
+}
+
+func (o *OSDNetworkCollector) cephDumpOSDNetworkCommand() [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "dump_osd_network",
+		// A threshold of 0 dumps every ping time, not only the slow
+		// ones.
+		"value":  0,
+		"format": jsonFormat,
+	})
+	if err != nil {
+		o.logger.WithError(err).Panic("error marshalling ceph dump_osd_network")
+	}
+	return [][]byte{cmd}
+}
+
+// Describe sends the descriptors of the OSD network metrics to the provided
+// channel.
+func (o *OSDNetworkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- o.HeartbeatPing
+	ch <- o.HeartbeatMaxPing
+}
+
+// Collect sends the OSD network metrics to the provided channel. The stale
+// ping times, from OSDs that stopped reporting them, are left out.
+func (o *OSDNetworkCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	args := o.cephDumpOSDNetworkCommand()
+	buf, _, err := o.conn.MgrCommand(args)
+	if err != nil {
+		o.logger.WithError(err).WithField(
+			"args", string(bytes.Join(args, []byte(","))),
+		).Error("error executing mgr command")
+
+		return err
+	}
+
+	network := cephOSDNetwork{}
+	if err := json.Unmarshal(buf, &network); err != nil {
+		o.parseErrors.observe("osdNetwork")
+		return err
+	}
+
+	type osdInterface struct {
+		osd   string
+		iface string
+	}
+
+	maxPings := make(map[osdInterface]float64)
+	for _, entry := range network.Entries {
+		if entry.Stale {
+			continue
+		}
+
+		from := fmt.Sprintf(osdLabelFormat, entry.FromOSD)
+		to := fmt.Sprintf(osdLabelFormat, entry.ToOSD)
+		ping := entry.Average.OneMin / 1000
+
+		ch <- prometheus.MustNewConstMetric(o.HeartbeatPing, prometheus.GaugeValue, ping, from, to, entry.Interface)
+
+		key := osdInterface{osd: from, iface: entry.Interface}
+		if maxPing, ok := maxPings[key]; !ok || ping > maxPing {
+			maxPings[key] = ping
+		}
+	}
+
+	for key, maxPing := range maxPings {
+		ch <- prometheus.MustNewConstMetric(o.HeartbeatMaxPing, prometheus.GaugeValue, maxPing, key.osd, key.iface)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSDNetworkCollector(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		input          string
+		err            error
+		regexes        []*regexp.Regexp
+		reMatchUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "ping times",
+			input: `
+{
+	"threshold": 0,
+	"entries": [
+		{"last update": "Mon May 13 11:35:14 2024", "stale": false, "from osd": 0, "to osd": 1, "interface": "back", "average": {"1min": 1.5, "5min": 1.2, "15min": 1.1}, "min": {"1min": 0.8, "5min": 0.7, "15min": 0.7}, "max": {"1min": 2.5, "5min": 2.5, "15min": 2.5}, "last": 1.4},
+		{"last update": "Mon May 13 11:35:14 2024", "stale": false, "from osd": 0, "to osd": 2, "interface": "back", "average": {"1min": 1250, "5min": 600, "15min": 200}, "min": {"1min": 900, "5min": 0.7, "15min": 0.7}, "max": {"1min": 1800, "5min": 1800, "15min": 1800}, "last": 1300},
+		{"last update": "Mon May 13 11:35:14 2024", "stale": false, "from osd": 0, "to osd": 2, "interface": "front", "average": {"1min": 0.9, "5min": 0.9, "15min": 0.9}, "min": {"1min": 0.8, "5min": 0.8, "15min": 0.8}, "max": {"1min": 1, "5min": 1, "15min": 1}, "last": 0.9},
+		{"last update": "Mon May 13 11:20:14 2024", "stale": true, "from osd": 3, "to osd": 0, "interface": "back", "average": {"1min": 40000, "5min": 40000, "15min": 40000}, "min": {"1min": 0, "5min": 0, "15min": 0}, "max": {"1min": 0, "5min": 0, "15min": 0}, "last": 0}
+	]
+}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_heartbeat_ping_seconds{cluster="ceph",from_osd="osd.0",interface="back",to_osd="osd.1"} 0.0015\n`),
+				regexp.MustCompile(`ceph_osd_heartbeat_ping_seconds{cluster="ceph",from_osd="osd.0",interface="back",to_osd="osd.2"} 1.25\n`),
+				regexp.MustCompile(`ceph_osd_heartbeat_ping_seconds{cluster="ceph",from_osd="osd.0",interface="front",to_osd="osd.2"} 0.0009\n`),
+				regexp.MustCompile(`ceph_osd_heartbeat_max_ping_seconds{cluster="ceph",interface="back",osd="osd.0"} 1.25\n`),
+				regexp.MustCompile(`ceph_osd_heartbeat_max_ping_seconds{cluster="ceph",interface="front",osd="osd.0"} 0.0009\n`),
+			},
+			reMatchUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`osd="osd.3"`),
+				regexp.MustCompile(`from_osd="osd.3"`),
+			},
+		},
+		{
+			name:  "unsupported",
+			input: ``,
+			err:   errors.New("unrecognized command"),
+			reMatchUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_heartbeat_ping_seconds{`),
+				regexp.MustCompile(`ceph_osd_heartbeat_max_ping_seconds{`),
+			},
+		},
+		{
+			name:  "invalid reply",
+			input: `{"entries": {}}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_collector_parse_errors_total{cluster="ceph",collector="osdNetwork"} [12]\n`),
+			},
+			reMatchUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_osd_heartbeat_ping_seconds{`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			conn.On("MgrCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([][]byte)[0], &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "dump_osd_network",
+					"value":  0.0,
+					"format": "json",
+				})
+			})).Return([]byte(tt.input), "", tt.err)
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.parseErrors = NewParseErrorsCollector(e)
+			e.cc = map[string]versionedCollector{
+				"parseErrors": e.parseErrors,
+				"osdNetwork":  NewOSDNetworkCollector(e),
+			}
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			scrape := func() []byte {
+				resp, err := http.Get(server.URL)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				buf, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				return buf
+			}
+
+			// The parse errors of a scrape may only be reported by the next
+			// one, see TestParseErrorsCollector.
+			scrape()
+			buf := scrape()
+
+			for _, re := range tt.regexes {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reMatchUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
@@ -15,12 +15,15 @@
 package ceph
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
@@ -354,8 +357,31 @@ func TestOSDLabelBuilder(t *testing.T) {
 	require.Equalf(t, "hdd", osd.DeviceClass, "expect to be an HDD")
 }
 
+func TestParseCephTime(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		expected time.Time
+	}{
+		{"2024-01-10T12:34:56.789012+0000", time.Date(2024, 1, 10, 12, 34, 56, 789012000, time.UTC)},
+		{"2024-01-10T12:34:56.789012+0200", time.Date(2024, 1, 10, 10, 34, 56, 789012000, time.UTC)},
+		{"2024-01-10T12:34:56.789012Z", time.Date(2024, 1, 10, 12, 34, 56, 789012000, time.UTC)},
+		{"2024-01-10 12:34:56.789012", time.Date(2024, 1, 10, 12, 34, 56, 789012000, time.UTC)},
+	} {
+		parsed, err := parseCephTime(tt.input)
+		require.NoError(t, err)
+		require.True(t, tt.expected.Equal(parsed), "expected %s, got %s", tt.expected, parsed)
+	}
+
+	_, err := parseCephTime("0.000000")
+	require.Error(t, err)
+}
+
 func TestOSDCollector(t *testing.T) {
 	reMatch := []*regexp.Regexp{
+		regexp.MustCompile(`ceph_osd_slow_ops{cluster="ceph",osd="osd.0"} 0`),
+		regexp.MustCompile(`ceph_osd_slow_ops{cluster="ceph",osd="osd.1"} 1`),
+		regexp.MustCompile(`ceph_osd_slow_ops{cluster="ceph",osd="osd.2"} 0`),
+		regexp.MustCompile(`ceph_osd_slow_ops{cluster="ceph",osd="osd.3"} 1`),
 		regexp.MustCompile(`ceph_osd_crush_weight{cluster="ceph",device_class="hdd",host="prod-data01-block01",osd="osd.0",rack="A8R1",root="default"} 0.010391`),
 		regexp.MustCompile(`ceph_osd_crush_weight{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.1",rack="A8R1",root="default"} 0.010391`),
 		regexp.MustCompile(`ceph_osd_crush_weight{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.2",rack="A8R1",root="default"} 0.010391`),
@@ -406,6 +432,13 @@ func TestOSDCollector(t *testing.T) {
 		regexp.MustCompile(`ceph_osd_total_used_bytes{cluster="ceph"} 1.5849472e`),
 		regexp.MustCompile(`ceph_osd_total_avail_bytes{cluster="ceph"} 4.5513199616e`),
 		regexp.MustCompile(`ceph_osd_average_utilization{cluster="ceph"} 0.347031`),
+		regexp.MustCompile(`ceph_osd_pg_count_stddev{cluster="ceph"} 59.021182`),
+		regexp.MustCompile(`ceph_crush_class_total_bytes{class="hdd",cluster="ceph"} 1.1417923584e\+10`),
+		regexp.MustCompile(`ceph_crush_class_total_bytes{class="ssd",cluster="ceph"} 3.4253770752e\+10`),
+		regexp.MustCompile(`ceph_crush_class_used_bytes{class="hdd",cluster="ceph"} 4.1750528e\+07`),
+		regexp.MustCompile(`ceph_crush_class_used_bytes{class="ssd",cluster="ceph"} 1.16744192e\+08`),
+		regexp.MustCompile(`ceph_crush_class_available_bytes{class="hdd",cluster="ceph"} 1.1376173056e\+10`),
+		regexp.MustCompile(`ceph_crush_class_available_bytes{class="ssd",cluster="ceph"} 3.413702656e\+10`),
 		regexp.MustCompile(`ceph_osd_near_full_ratio{cluster="ceph"} 0.7`),
 		regexp.MustCompile(`ceph_osd_backfill_full_ratio{cluster="ceph"} 0.8`),
 		regexp.MustCompile(`ceph_osd_full_ratio{cluster="ceph"} 0.9`),
@@ -448,6 +481,17 @@ func TestOSDCollector(t *testing.T) {
 		regexp.MustCompile(`ceph_osd_scrub_state{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.21",rack="A8R1",root="default"} 2`),
 		regexp.MustCompile(`ceph_osd_scrub_state{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.22",rack="A8R1",root="default"} 2`),
 		regexp.MustCompile(`ceph_osd_scrub_state{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.23",rack="A8R1",root="default"} 2`),
+
+		regexp.MustCompile(`ceph_osd_pg_last_scrub_age_seconds{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.1",rack="A8R1",root="default"} 3600`),
+		regexp.MustCompile(`ceph_osd_pg_last_scrub_age_seconds{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.10",rack="A8R1",root="default"} 7200`),
+		regexp.MustCompile(`ceph_osd_pg_last_scrub_age_seconds{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.20",rack="A8R1",root="default"} 10800`),
+		regexp.MustCompile(`ceph_osd_pg_last_deep_scrub_age_seconds{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.1",rack="A8R1",root="default"} 781200`),
+		regexp.MustCompile(`ceph_osd_pg_last_deep_scrub_age_seconds{cluster="ceph",device_class="ssd",host="prod-data01-block01",osd="osd.10",rack="A8R1",root="default"} 867600`),
+
+		regexp.MustCompile(`ceph_osd_rados_class_allowed{class="rbd",cluster="ceph"} 1`),
+		regexp.MustCompile(`ceph_osd_rados_class_allowed{class="rgw",cluster="ceph"} 1`),
+		regexp.MustCompile(`ceph_osd_rados_class_allowed{class="rgw_gc",cluster="ceph"} 0`),
+		regexp.MustCompile(`ceph_osd_rados_class_allowed{class="2pc_queue",cluster="ceph"} 0`),
 	}
 
 	for _, tt := range []struct {
@@ -493,6 +537,44 @@ func TestOSDCollector(t *testing.T) {
 		func() {
 			conn := setupVersionMocks(tt.version, "{}")
 
+			// rgw_gc and 2pc_queue were left out of the load list.
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "config get",
+					"who":    "osd",
+					"key":    "osd_class_load_list",
+				})
+			})).Return([]byte("cephfs hello journal lock log numops otp rbd refcount rgw timeindex user version cas cmpomap queue fifo\n"), "", nil)
+
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "health",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return([]byte(`
+{
+	"status": "HEALTH_WARN",
+	"checks": {
+		"SLOW_OPS": {
+			"severity": "HEALTH_WARN",
+			"summary": {"message": "12 slow ops, oldest one blocked for 41 sec, daemons [osd.1,osd.3,mon.a] have slow ops.", "count": 12},
+			"detail": [],
+			"muted": false
+		}
+	}
+}`), "", nil)
+
 			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
 				v := map[string]interface{}{}
 
@@ -685,6 +767,10 @@ func TestOSDCollector(t *testing.T) {
 					"prefix":       "pg dump",
 					"dumpcontents": []interface{}{"pgs_brief"},
 					"format":       "json",
+				}) || cmp.Equal(v, map[string]interface{}{
+					"prefix":       "pg dump",
+					"dumpcontents": []interface{}{"pgs"},
+					"format":       "json",
 				})
 			})).Return([]byte(`
 {
@@ -699,7 +785,9 @@ func TestOSDCollector(t *testing.T) {
 			],
 			"acting_primary": 1,
 			"pgid": "81.1fff",
-			"state": "active+clean"
+			"state": "active+clean",
+			"last_scrub_stamp": "2024-01-10T12:00:00.000000+0000",
+			"last_deep_scrub_stamp": "2024-01-01T12:00:00.000000+0000"
 		},
 		{
 			"acting": [
@@ -710,7 +798,9 @@ func TestOSDCollector(t *testing.T) {
 			],
 			"acting_primary": 10,
 			"pgid": "82.1fff",
-			"state": "active+clean+scrubbing"
+			"state": "active+clean+scrubbing",
+			"last_scrub_stamp": "2024-01-10T11:00:00.000000+0000",
+			"last_deep_scrub_stamp": "2023-12-31T12:00:00.000000+0000"
 			},
 		{
 			"acting": [
@@ -721,7 +811,9 @@ func TestOSDCollector(t *testing.T) {
 			],
 			"acting_primary": 20,
 			"pgid": "83.1fff",
-			"state": "active+clean+scrubbing+deep"
+			"state": "active+clean+scrubbing+deep",
+			"last_scrub_stamp": "2024-01-10 10:00:00.000000",
+			"last_deep_scrub_stamp": "0.000000"
 		}
 	]
 }`), "", nil)
@@ -1021,9 +1113,29 @@ func TestOSDCollector(t *testing.T) {
     }
 }`), "", nil)
 
+			conn.On("MgrCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				uv, ok := in.([][]byte)
+				require.True(t, ok)
+				require.Len(t, uv, 1)
+
+				err := json.Unmarshal(uv[0], &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "device ls",
+					"format": "json",
+				})
+			})).Return([]byte(`[]`), "", nil)
+
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			osd := NewOSDCollector(e)
+			osd.now = func() time.Time {
+				return time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+			}
 			e.cc = map[string]versionedCollector{
-				"osd": NewOSDCollector(e),
+				"osd": osd,
 			}
 			err := prometheus.Register(e)
 			require.NoError(t, err)
@@ -1045,3 +1157,303 @@ func TestOSDCollector(t *testing.T) {
 		}()
 	}
 }
+
+func TestCrushHostDistribution(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "osd tree",
+			"format": "json",
+		})
+	})).Return([]byte(`
+{
+	"nodes": [
+		{"id": -1, "name": "default", "type": "root", "children": [-2, -3, -4]},
+		{"id": -2, "name": "big-host", "type": "host", "children": [0, 1, 2]},
+		{"id": -3, "name": "small-host-a", "type": "host", "children": [3]},
+		{"id": -4, "name": "small-host-b", "type": "host", "children": [4]},
+		{"id": 0, "name": "osd.0", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 1, "name": "osd.1", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 2, "name": "osd.2", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 3, "name": "osd.3", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 4, "name": "osd.4", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0}
+	],
+	"stray": []
+}`), "", nil)
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+	conn.On("MgrCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.cc = map[string]versionedCollector{
+		"osd": NewOSDCollector(e),
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_crush_host_failure_domain_osds{cluster="ceph",host="big-host"} 3`),
+		regexp.MustCompile(`ceph_crush_host_failure_domain_osds{cluster="ceph",host="small-host-a"} 1`),
+		regexp.MustCompile(`ceph_crush_host_failure_domain_osds{cluster="ceph",host="small-host-b"} 1`),
+		regexp.MustCompile(`ceph_crush_single_host_risk{cluster="ceph",host="big-host",root="default"} 1`),
+		regexp.MustCompile(`ceph_crush_single_host_risk{cluster="ceph",host="small-host-a",root="default"} 0`),
+		regexp.MustCompile(`ceph_crush_single_host_risk{cluster="ceph",host="small-host-b",root="default"} 0`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+}
+
+func TestOSDDeviceHealth(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	mgrCommand := func(cmd map[string]interface{}) interface{} {
+		return mock.MatchedBy(func(in interface{}) bool {
+			v := map[string]interface{}{}
+
+			uv, ok := in.([][]byte)
+			require.True(t, ok)
+			require.Len(t, uv, 1)
+
+			err := json.Unmarshal(uv[0], &v)
+			require.NoError(t, err)
+
+			return cmp.Equal(v, cmd)
+		})
+	}
+
+	conn.On("MgrCommand", mgrCommand(map[string]interface{}{
+		"prefix": "device ls",
+		"format": "json",
+	})).Return([]byte(`
+[
+	{"devid": "ATA_HDD_1", "location": [{"host": "host-a", "dev": "sda"}], "daemons": ["osd.0"]},
+	{"devid": "NVME_SSD_2", "location": [{"host": "host-a", "dev": "nvme0n1"}], "daemons": ["osd.1", "osd.2", "mon.a"]},
+	{"devid": "VIRTUAL_DISK_3", "location": [{"host": "host-b", "dev": "vda"}], "daemons": ["osd.3"]},
+	{"devid": "WORN_SSD_4", "location": [{"host": "host-b", "dev": "sdb"}], "daemons": ["osd.4"], "wear_level": 0.25}
+]`), "", nil)
+	conn.On("MgrCommand", mgrCommand(map[string]interface{}{
+		"prefix": "device get-health-metrics",
+		"devid":  "ATA_HDD_1",
+		"format": "json",
+	})).Return([]byte(`
+{
+	"20240109-000000": {"smart_status": {"passed": true}},
+	"20240110-000000": {"smart_status": {"passed": false}}
+}`), "", nil)
+	conn.On("MgrCommand", mgrCommand(map[string]interface{}{
+		"prefix": "device get-health-metrics",
+		"devid":  "NVME_SSD_2",
+		"format": "json",
+	})).Return([]byte(`
+{
+	"20240110-000000": {
+		"smart_status": {"passed": true},
+		"nvme_smart_health_information_log": {"percentage_used": 12}
+	}
+}`), "", nil)
+	conn.On("MgrCommand", mgrCommand(map[string]interface{}{
+		"prefix": "device get-health-metrics",
+		"devid":  "VIRTUAL_DISK_3",
+		"format": "json",
+	})).Return([]byte(`{}`), "", nil)
+	conn.On("MgrCommand", mgrCommand(map[string]interface{}{
+		"prefix": "device get-health-metrics",
+		"devid":  "WORN_SSD_4",
+		"format": "json",
+	})).Return([]byte(`
+{
+	"20240110-000000": {"smart_status": {"passed": true}}
+}`), "", nil)
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+	conn.On("MgrCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.cc = map[string]versionedCollector{
+		"osd": NewOSDCollector(e),
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	// The device health is refreshed in the background, the first scrapes
+	// may not have it yet.
+	var buf []byte
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return bytes.Contains(buf, []byte("ceph_osd_device_health_ok"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_osd_device_health_ok{cluster="ceph",device="ATA_HDD_1",osd="osd.0"} 0`),
+		regexp.MustCompile(`ceph_osd_device_health_ok{cluster="ceph",device="NVME_SSD_2",osd="osd.1"} 1`),
+		regexp.MustCompile(`ceph_osd_device_health_ok{cluster="ceph",device="NVME_SSD_2",osd="osd.2"} 1`),
+		regexp.MustCompile(`ceph_osd_device_health_ok{cluster="ceph",device="WORN_SSD_4",osd="osd.4"} 1`),
+		regexp.MustCompile(`ceph_osd_device_life_remaining_percent{cluster="ceph",device="NVME_SSD_2",osd="osd.1"} 88`),
+		regexp.MustCompile(`ceph_osd_device_life_remaining_percent{cluster="ceph",device="NVME_SSD_2",osd="osd.2"} 88`),
+		regexp.MustCompile(`ceph_osd_device_life_remaining_percent{cluster="ceph",device="WORN_SSD_4",osd="osd.4"} 75`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_osd_device_life_remaining_percent{cluster="ceph",device="ATA_HDD_1"`),
+		regexp.MustCompile(`ceph_osd_device_[a-z_]+{cluster="ceph",device="VIRTUAL_DISK_3"`),
+		regexp.MustCompile(`osd="mon.a"`),
+	} {
+		require.False(t, re.Match(buf), re.String())
+	}
+}
+
+func TestOSDConfigOverride(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "osd tree",
+			"format": "json",
+		})
+	})).Return([]byte(`
+{
+	"nodes": [
+		{"id": -1, "name": "default", "type": "root", "children": [-2]},
+		{"id": -2, "name": "host-a", "type": "host", "children": [0, 1, 2]},
+		{"id": 0, "name": "osd.0", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 1, "name": "osd.1", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "up", "reweight": 1.0},
+		{"id": 2, "name": "osd.2", "type": "osd", "device_class": "hdd", "crush_weight": 1.0, "status": "down", "reweight": 1.0}
+	],
+	"stray": []
+}`), "", nil)
+
+	configShow := func(who string) interface{} {
+		return mock.MatchedBy(func(in interface{}) bool {
+			v := map[string]interface{}{}
+
+			uv, ok := in.([][]byte)
+			require.True(t, ok)
+			require.Len(t, uv, 1)
+
+			err := json.Unmarshal(uv[0], &v)
+			require.NoError(t, err)
+
+			return cmp.Equal(v, map[string]interface{}{
+				"prefix": "config show",
+				"who":    who,
+				"format": "json",
+			})
+		})
+	}
+
+	conn.On("MgrCommand", configShow("osd.0")).Return([]byte(`
+[
+	{"name": "osd_op_num_threads_per_shard", "value": "4", "source": "mon"},
+	{"name": "osd_max_backfills", "value": "1", "source": "default"}
+]`), "", nil)
+	conn.On("MgrCommand", configShow("osd.1")).Return([]byte(`
+[
+	{"name": "osd_max_backfills", "value": "1", "source": "default"}
+]`), "", nil)
+	conn.On("MgrCommand", configShow("osd.2")).Return([]byte(""), "", errors.New("osd.2 is down"))
+	conn.On("MonCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+	conn.On("MgrCommand", mock.Anything).Return([]byte(""), "", errors.New("not implemented"))
+
+	e := &Exporter{
+		Conn:    conn,
+		Cluster: "ceph",
+		Logger:  logrus.New(),
+
+		OSDConfigOverrideKeys: []string{"osd_op_num_threads_per_shard", "osd_max_backfills"},
+	}
+	e.cc = map[string]versionedCollector{
+		"osd": NewOSDCollector(e),
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_osd_config_override{cluster="ceph",key="osd_op_num_threads_per_shard"} 1`),
+		regexp.MustCompile(`ceph_osd_config_override{cluster="ceph",key="osd_max_backfills"} 0`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+}
+
+func TestPGDumpCache(t *testing.T) {
+	var pgDumps int
+	conn := &MockConn{}
+	conn.On("MgrCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([][]byte)[0], &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix":       "pg dump",
+			"dumpcontents": []interface{}{"pgs"},
+			"format":       "json",
+		})
+	})).Return(func([][]byte) []byte {
+		pgDumps++
+		return []byte(`{"pg_stats": [{"pgid": "1.0", "acting": [0, 1], "state": "active+clean", "last_scrub_stamp": "2024-01-01T00:00:00.000000+0000"}]}`)
+	}, "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	osd := NewOSDCollector(e)
+	poolUsage := NewPoolUsageCollector(e)
+
+	ch := make(chan prometheus.Metric, 16)
+	go func() {
+		for range ch {
+		}
+	}()
+	defer close(ch)
+
+	// The collectors of a scrape share the PG dump.
+	for scrape := 1; scrape <= 2; scrape++ {
+		require.NoError(t, osd.collectOSDScrubState(ch))
+		stats, err := poolUsage.getPGStats()
+		require.NoError(t, err)
+		require.Equal(t, float64(1), stats.total[1])
+		require.Equal(t, scrape, pgDumps)
+
+		e.pgDumps.reset()
+	}
+}
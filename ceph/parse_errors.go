@@ -0,0 +1,70 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ParseErrorsCollector counts the replies the collectors failed to make sense
+// of. A sudden increase after an upgrade usually means that the format of a
+// command output changed under us.
+type ParseErrorsCollector struct {
+	// ParseErrors counts the failures to unmarshal or parse a reply,
+	// labelled by the collector that issued the command.
+	ParseErrors *prometheus.CounterVec
+}
+
+// NewParseErrorsCollector creates a new ParseErrorsCollector. It should be
+// created before the other collectors so that they can report to it.
+func NewParseErrorsCollector(exporter *Exporter) *ParseErrorsCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &ParseErrorsCollector{
+		ParseErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "collector_parse_errors_total",
+				Help:        "Number of command replies a collector failed to unmarshal or parse",
+				ConstLabels: labels,
+			},
+			[]string{"collector"},
+		),
+	}
+}
+
+// observe records a parse failure in the given collector. It is a no-op on
+// a nil receiver, so that collectors built without an exporter-wide
+// ParseErrorsCollector don't have to care.
+func (p *ParseErrorsCollector) observe(collector string) {
+	if p == nil {
+		return
+	}
+
+	p.ParseErrors.WithLabelValues(collector).Inc()
+}
+
+// Describe sends the descriptors of the parse error metrics to the provided
+// channel.
+func (p *ParseErrorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	p.ParseErrors.Describe(ch)
+}
+
+// Collect sends the parse error metrics to the provided channel.
+func (p *ParseErrorsCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	p.ParseErrors.Collect(ch)
+	return nil
+}
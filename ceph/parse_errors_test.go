@@ -0,0 +1,79 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrorsCollector(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).Return([]byte(`{"stats": {"total_bytes": "lots"}}`), "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.parseErrors = NewParseErrorsCollector(e)
+	e.cc = map[string]versionedCollector{
+		"parseErrors":  e.parseErrors,
+		"clusterUsage": NewClusterUsageCollector(e),
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return buf
+	}
+
+	// The collectors run concurrently, so the counter may be sent before or
+	// after the failure of the same scrape is accounted for. The second
+	// scrape is guaranteed to include the failure of the first one.
+	scrape()
+	buf := scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_collector_parse_errors_total{cluster="ceph",collector="clusterUsage"} [12]\n`), string(buf))
+}
@@ -17,6 +17,7 @@ package ceph
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"strconv"
 	"sync"
@@ -30,12 +31,27 @@ const (
 	poolErasure    = 3
 )
 
+// The default recommended range of PG replicas per OSD, overridden by
+// PGPerOSDMin and PGPerOSDMax. Ceph targets 100 (mon_target_pg_per_osd), used
+// to warn below 30 (mon_pg_warn_min_per_osd) and refuses to create PGs above
+// 250 (mon_max_pg_per_osd).
+const (
+	defaultMinPGPerOSD = 30
+	defaultMaxPGPerOSD = 250
+)
+
 // PoolInfoCollector gives information about each pool that exists in a given
 // ceph cluster.
 type PoolInfoCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// minPGPerOSD and maxPGPerOSD bound the recommended PGPerOSDRatio.
+	minPGPerOSD, maxPGPerOSD int
+
 	// PGNum contains the count of PGs allotted to a particular pool.
 	PGNum *prometheus.GaugeVec
 
@@ -62,25 +78,49 @@ type PoolInfoCollector struct {
 
 	// ExpansionFactor Contains a float >= 1 that defines the EC or replication multiplier of a pool
 	ExpansionFactor *prometheus.GaugeVec
+
+	// PGNumTotal contains the count of PGs allotted to all the pools.
+	PGNumTotal prometheus.Gauge
+
+	// PGPerOSDRatio contains the average count of PG replicas (or chunks)
+	// held by each in OSD.
+	PGPerOSDRatio prometheus.Gauge
+
+	// PGCountHealthy shows whether PGPerOSDRatio is within the recommended
+	// range.
+	PGCountHealthy prometheus.Gauge
 }
 
 // NewPoolInfoCollector displays information about each pool in the cluster.
 func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 	var (
-		subSystem  = "pool"
-		poolLabels = []string{"pool", "profile", "root"}
+		subSystem      = "pool"
+		poolLabels     = []string{"pool", "profile", "root"}
+		poolSizeLabels = []string{"pool", "profile", "root", "crush_rule"}
 	)
 
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	minPGPerOSD := exporter.PGPerOSDMin
+	if minPGPerOSD == 0 {
+		minPGPerOSD = defaultMinPGPerOSD
+	}
+	maxPGPerOSD := exporter.PGPerOSDMax
+	if maxPGPerOSD == 0 {
+		maxPGPerOSD = defaultMaxPGPerOSD
+	}
 
 	return &PoolInfoCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+		minPGPerOSD: minPGPerOSD,
+		maxPGPerOSD: maxPGPerOSD,
 
 		PGNum: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "pg_num",
 				Help:        "The total count of PGs alotted to a pool",
@@ -90,7 +130,7 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 		),
 		PlacementPGNum: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "pgp_num",
 				Help:        "The total count of PGs alotted to a pool and used for placements",
@@ -100,27 +140,27 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 		),
 		MinSize: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "min_size",
 				Help:        "Minimum number of copies or chunks of an object that need to be present for active I/O",
 				ConstLabels: labels,
 			},
-			poolLabels,
+			poolSizeLabels,
 		),
 		ActualSize: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "size",
 				Help:        "Total copies or chunks of an object that need to be present for a healthy cluster",
 				ConstLabels: labels,
 			},
-			poolLabels,
+			poolSizeLabels,
 		),
 		QuotaMaxBytes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "quota_max_bytes",
 				Help:        "Maximum amount of bytes of data allowed in a pool",
@@ -130,7 +170,7 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 		),
 		QuotaMaxObjects: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "quota_max_objects",
 				Help:        "Maximum amount of RADOS objects allowed in a pool",
@@ -140,7 +180,7 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 		),
 		StripeWidth: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "stripe_width",
 				Help:        "Stripe width of a RADOS object in a pool",
@@ -150,7 +190,7 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 		),
 		ExpansionFactor: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Subsystem:   subSystem,
 				Name:        "expansion_factor",
 				Help:        "Data expansion multiplier for a pool",
@@ -158,6 +198,33 @@ func NewPoolInfoCollector(exporter *Exporter) *PoolInfoCollector {
 			},
 			poolLabels,
 		),
+		PGNumTotal: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "cluster",
+				Name:        "pg_num_total",
+				Help:        "The total count of PGs alotted to all the pools",
+				ConstLabels: labels,
+			},
+		),
+		PGPerOSDRatio: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "cluster",
+				Name:        "pg_per_osd_ratio",
+				Help:        "Average count of PG replicas or chunks held by each in OSD",
+				ConstLabels: labels,
+			},
+		),
+		PGCountHealthy: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "cluster",
+				Name:        "pg_count_healthy",
+				Help:        fmt.Sprintf("Whether the count of PGs per OSD is within the recommended range of %d to %d (0/1)", minPGPerOSD, maxPGPerOSD),
+				ConstLabels: labels,
+			},
+		),
 	}
 }
 
@@ -171,6 +238,7 @@ func (p *PoolInfoCollector) collectorList() []prometheus.Collector {
 		p.QuotaMaxObjects,
 		p.StripeWidth,
 		p.ExpansionFactor,
+		p.PGNumTotal,
 	}
 }
 
@@ -192,10 +260,10 @@ type cephPoolInfo struct {
 	Pools []poolInfo
 }
 
-func (p *PoolInfoCollector) collect() error {
+func (p *PoolInfoCollector) collect(ch chan<- prometheus.Metric) error {
 	var buf []byte
 	var err error
-	var ruleToRootMappings map[int64]string
+	var ruleToRootMappings, ruleToNameMappings map[int64]string
 	wg := &sync.WaitGroup{}
 
 	wg.Add(1)
@@ -214,7 +282,7 @@ func (p *PoolInfoCollector) collect() error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		ruleToRootMappings = p.getCrushRuleToRootMappings()
+		ruleToRootMappings, ruleToNameMappings = p.getCrushRuleMappings()
 	}()
 
 	wg.Wait()
@@ -224,6 +292,7 @@ func (p *PoolInfoCollector) collect() error {
 
 	stats := &cephPoolInfo{}
 	if err := json.Unmarshal(buf, &stats.Pools); err != nil {
+		p.parseErrors.observe("poolInfo")
 		return err
 	}
 
@@ -244,17 +313,92 @@ func (p *PoolInfoCollector) collect() error {
 		labelValues := []string{pool.Name, pool.Profile, ruleToRootMappings[pool.CrushRule]}
 		p.PGNum.WithLabelValues(labelValues...).Set(pool.PGNum)
 		p.PlacementPGNum.WithLabelValues(labelValues...).Set(pool.PlacementPGNum)
-		p.MinSize.WithLabelValues(labelValues...).Set(pool.MinSize)
-		p.ActualSize.WithLabelValues(labelValues...).Set(pool.ActualSize)
+		sizeLabelValues := append(labelValues, ruleToNameMappings[pool.CrushRule])
+		p.MinSize.WithLabelValues(sizeLabelValues...).Set(pool.MinSize)
+		p.ActualSize.WithLabelValues(sizeLabelValues...).Set(pool.ActualSize)
 		p.QuotaMaxBytes.WithLabelValues(labelValues...).Set(pool.QuotaMaxBytes)
 		p.QuotaMaxObjects.WithLabelValues(labelValues...).Set(pool.QuotaMaxObjects)
 		p.StripeWidth.WithLabelValues(labelValues...).Set(pool.StripeWidth)
 		p.ExpansionFactor.WithLabelValues(labelValues...).Set(p.getExpansionFactor(pool))
 	}
 
+	var pgNum, pgReplicas float64
+	for _, pool := range stats.Pools {
+		pgNum += pool.PGNum
+		pgReplicas += pool.PGNum * pool.ActualSize
+	}
+	p.PGNumTotal.Set(pgNum)
+
+	for _, metric := range p.collectorList() {
+		metric.Collect(ch)
+	}
+
+	return p.collectPGPerOSD(ch, pgReplicas)
+}
+
+// collectPGPerOSD computes how the PG replicas are spread across the in OSDs.
+// It fails if the number of in OSDs can't be read, after the pool metrics are
+// sent.
+func (p *PoolInfoCollector) collectPGPerOSD(ch chan<- prometheus.Metric, pgReplicas float64) error {
+	numInOSDs, err := p.getNumInOSDs()
+	if err != nil {
+		return fmt.Errorf("error getting the number of in osds: %w", err)
+	}
+
+	if numInOSDs == 0 {
+		return nil
+	}
+
+	// These are only sent when the number of in OSDs is known, which is why
+	// they aren't part of the collector list.
+	ratio := pgReplicas / numInOSDs
+	p.PGPerOSDRatio.Set(ratio)
+
+	healthy := 0.0
+	if ratio >= float64(p.minPGPerOSD) && ratio <= float64(p.maxPGPerOSD) {
+		healthy = 1
+	}
+	p.PGCountHealthy.Set(healthy)
+
+	p.PGPerOSDRatio.Collect(ch)
+	p.PGCountHealthy.Collect(ch)
+
 	return nil
 }
 
+func (p *PoolInfoCollector) getNumInOSDs() (float64, error) {
+	cmd := p.cephOSDStatCommand()
+	buf, _, err := p.conn.MonCommand(cmd)
+	if err != nil {
+		p.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return 0, err
+	}
+
+	stat := struct {
+		NumInOSDs float64 `json:"num_in_osds"`
+	}{}
+	if err := json.Unmarshal(buf, &stat); err != nil {
+		p.parseErrors.observe("poolInfo")
+		return 0, err
+	}
+
+	return stat.NumInOSDs, nil
+}
+
+func (p *PoolInfoCollector) cephOSDStatCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "osd stat",
+		"format": "json",
+	})
+	if err != nil {
+		p.logger.WithError(err).Panic("error marshalling ceph osd stat")
+	}
+	return cmd
+}
+
 func (p *PoolInfoCollector) cephInfoCommand() []byte {
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "osd pool ls",
@@ -273,20 +417,21 @@ func (p *PoolInfoCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range p.collectorList() {
 		metric.Describe(ch)
 	}
+
+	p.PGPerOSDRatio.Describe(ch)
+	p.PGCountHealthy.Describe(ch)
 }
 
 // Collect extracts the current values of all the metrics and sends them to the
 // prometheus channel.
-func (p *PoolInfoCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (p *PoolInfoCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
 	p.logger.Debug("collecting pool metrics")
-	if err := p.collect(); err != nil {
+	if err := p.collect(ch); err != nil {
 		p.logger.WithError(err).Error("error collecting pool metrics")
-		return
+		return err
 	}
 
-	for _, metric := range p.collectorList() {
-		metric.Collect(ch)
-	}
+	return nil
 }
 
 func (p *PoolInfoCollector) getExpansionFactor(pool poolInfo) float64 {
@@ -323,6 +468,7 @@ func (p *PoolInfoCollector) getECExpansionFactor(pool poolInfo) (float64, error)
 	ecStats := ecInfo{}
 	err = json.Unmarshal(buf, &ecStats)
 	if err != nil {
+		p.parseErrors.observe("poolInfo")
 		return -1, err
 	}
 
@@ -338,8 +484,11 @@ func (p *PoolInfoCollector) getECExpansionFactor(pool poolInfo) (float64, error)
 	return roundedExpansion, nil
 }
 
-func (p *PoolInfoCollector) getCrushRuleToRootMappings() map[int64]string {
+// getCrushRuleMappings returns the crush root and the name of every crush
+// rule, keyed by rule ID.
+func (p *PoolInfoCollector) getCrushRuleMappings() (map[int64]string, map[int64]string) {
 	mappings := make(map[int64]string)
+	names := make(map[int64]string)
 
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "osd crush rule dump",
@@ -355,12 +504,13 @@ func (p *PoolInfoCollector) getCrushRuleToRootMappings() map[int64]string {
 			"args", string(cmd),
 		).Error("error executing mon command")
 
-		return mappings
+		return mappings, names
 	}
 
 	var rules []struct {
-		RuleID int64 `json:"rule_id"`
-		Steps  []struct {
+		RuleID   int64  `json:"rule_id"`
+		RuleName string `json:"rule_name"`
+		Steps    []struct {
 			ItemName string `json:"item_name"`
 			Op       string `json:"op"`
 		} `json:"steps"`
@@ -368,12 +518,14 @@ func (p *PoolInfoCollector) getCrushRuleToRootMappings() map[int64]string {
 
 	err = json.Unmarshal(buf, &rules)
 	if err != nil {
+		p.parseErrors.observe("poolInfo")
 		p.logger.WithError(err).Error("error unmarshalling crush rules")
 
-		return mappings
+		return mappings, names
 	}
 
 	for _, rule := range rules {
+		names[rule.RuleID] = rule.RuleName
 		if len(rule.Steps) == 0 {
 			continue
 		}
@@ -388,5 +540,5 @@ func (p *PoolInfoCollector) getCrushRuleToRootMappings() map[int64]string {
 		}
 	}
 
-	return mappings
+	return mappings, names
 }
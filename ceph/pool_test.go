@@ -33,14 +33,17 @@ import (
 
 func TestPoolInfoCollector(t *testing.T) {
 	for _, tt := range []struct {
-		version            string
-		reMatch, reUnmatch []*regexp.Regexp
+		version                  string
+		osdStat                  string
+		minPGPerOSD, maxPGPerOSD int
+		reMatch, reUnmatch       []*regexp.Regexp
 	}{
 		{
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			osdStat: `{"epoch": 1234, "num_osds": 420, "num_up_osds": 410, "osd_up_since": 1704891600, "num_in_osds": 400, "osd_in_since": 1704891600, "num_remapped_pgs": 0}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_size{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 6`),
-				regexp.MustCompile(`pool_min_size{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 4`),
+				regexp.MustCompile(`pool_size{cluster="ceph",crush_rule="another-rule",pool="rbd",profile="ec-4-2",root="non-default-root"} 6`),
+				regexp.MustCompile(`pool_min_size{cluster="ceph",crush_rule="another-rule",pool="rbd",profile="ec-4-2",root="non-default-root"} 4`),
 				regexp.MustCompile(`pool_pg_num{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 8192`),
 				regexp.MustCompile(`pool_pgp_num{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 8192`),
 				regexp.MustCompile(`pool_quota_max_bytes{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 1024`),
@@ -48,17 +51,67 @@ func TestPoolInfoCollector(t *testing.T) {
 				regexp.MustCompile(`pool_stripe_width{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 4096`),
 				regexp.MustCompile(`pool_expansion_factor{cluster="ceph",pool="rbd",profile="ec-4-2",root="non-default-root"} 1.5`),
 
-				regexp.MustCompile(`pool_size{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 3`),
-				regexp.MustCompile(`pool_min_size{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 2`),
+				regexp.MustCompile(`pool_size{cluster="ceph",crush_rule="replicated_rule",pool="rbd",profile="replicated-ruleset",root="default"} 3`),
+				regexp.MustCompile(`pool_min_size{cluster="ceph",crush_rule="replicated_rule",pool="rbd",profile="replicated-ruleset",root="default"} 2`),
 				regexp.MustCompile(`pool_pg_num{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 16384`),
 				regexp.MustCompile(`pool_pgp_num{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 16384`),
 				regexp.MustCompile(`pool_quota_max_bytes{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 512`),
 				regexp.MustCompile(`pool_quota_max_objects{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 1024`),
 				regexp.MustCompile(`pool_stripe_width{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 4096`),
 				regexp.MustCompile(`pool_expansion_factor{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 3`),
+
+				regexp.MustCompile(`ceph_cluster_pg_num_total{cluster="ceph"} 24576`),
+				regexp.MustCompile(`ceph_cluster_pg_per_osd_ratio{cluster="ceph"} 245.76`),
+				regexp.MustCompile(`ceph_cluster_pg_count_healthy{cluster="ceph"} 1`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			// Too many PGs for the number of OSDs.
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			osdStat: `{"epoch": 1234, "num_osds": 120, "num_up_osds": 120, "osd_up_since": 1704891600, "num_in_osds": 96, "osd_in_since": 1704891600, "num_remapped_pgs": 0}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cluster_pg_num_total{cluster="ceph"} 24576`),
+				regexp.MustCompile(`ceph_cluster_pg_per_osd_ratio{cluster="ceph"} 1024`),
+				regexp.MustCompile(`ceph_cluster_pg_count_healthy{cluster="ceph"} 0`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
+		{
+			// Too few PGs for the number of OSDs.
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			osdStat: `{"epoch": 1234, "num_osds": 4096, "num_up_osds": 4096, "osd_up_since": 1704891600, "num_in_osds": 4096, "osd_in_since": 1704891600, "num_remapped_pgs": 0}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cluster_pg_per_osd_ratio{cluster="ceph"} 24`),
+				regexp.MustCompile(`ceph_cluster_pg_count_healthy{cluster="ceph"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			// Too many PGs for the default range, not for the configured one.
+			version:     `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			osdStat:     `{"epoch": 1234, "num_osds": 120, "num_up_osds": 120, "osd_up_since": 1704891600, "num_in_osds": 96, "osd_in_since": 1704891600, "num_remapped_pgs": 0}`,
+			minPGPerOSD: 100,
+			maxPGPerOSD: 2048,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cluster_pg_per_osd_ratio{cluster="ceph"} 1024`),
+				regexp.MustCompile(`ceph_cluster_pg_count_healthy{cluster="ceph"} 1`),
+				regexp.MustCompile(`recommended range of 100 to 2048`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			// Unknown number of OSDs, the pool metrics are still sent.
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cluster_pg_num_total{cluster="ceph"} 24576`),
+				regexp.MustCompile(`pool_pg_num{cluster="ceph",pool="rbd",profile="replicated-ruleset",root="default"} 16384`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_cluster_pg_per_osd_ratio`),
+				regexp.MustCompile(`ceph_cluster_pg_count_healthy`),
+			},
+		},
 	} {
 		func() {
 			conn := setupVersionMocks(tt.version, "{}")
@@ -145,6 +198,20 @@ func TestPoolInfoCollector(t *testing.T) {
 ]`,
 			), "", nil)
 
+			if tt.osdStat != "" {
+				conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+					v := map[string]interface{}{}
+
+					err := json.Unmarshal(in.([]byte), &v)
+					require.NoError(t, err)
+
+					return cmp.Equal(v, map[string]interface{}{
+						"prefix": "osd stat",
+						"format": "json",
+					})
+				})).Return([]byte(tt.osdStat), "", nil)
+			}
+
 			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
 				v := map[string]interface{}{}
 
@@ -183,15 +250,17 @@ func TestPoolInfoCollector(t *testing.T) {
 				})
 			})).Return([]byte(""), "", fmt.Errorf("unknown erasure code profile"))
 
-			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), PGPerOSDMin: tt.minPGPerOSD, PGPerOSDMax: tt.maxPGPerOSD}
 			e.cc = map[string]versionedCollector{
 				"poolInfo": NewPoolInfoCollector(e),
 			}
-			err := prometheus.Register(e)
+			// The help of ceph_cluster_pg_count_healthy depends on the
+			// configured range, the default registry would reject it.
+			reg := prometheus.NewRegistry()
+			err := reg.Register(e)
 			require.NoError(t, err)
-			defer prometheus.Unregister(e)
 
-			server := httptest.NewServer(promhttp.Handler())
+			server := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
 			defer server.Close()
 
 			resp, err := http.Get(server.URL)
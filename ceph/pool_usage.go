@@ -15,19 +15,96 @@
 package ceph
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
+const radosCmd = "/usr/bin/rados"
+
+// inconsistentObjectsTimeout bounds the time spent listing the inconsistent
+// objects of all the pools in a collection, a cluster with many inconsistent
+// PGs would otherwise stall the scrape running the rados CLI for each of them.
+const inconsistentObjectsTimeout = 30 * time.Second
+
+// radosCLIAvailable returns an error when the rados CLI the inconsistent
+// objects are listed through is missing.
+var radosCLIAvailable = func() error {
+	_, err := os.Stat(radosCmd)
+	return err
+}
+
+// runListInconsistentObj lists the objects the last scrub of the PG found
+// inconsistent.
+func runListInconsistentObj(ctx context.Context, config, user, keyring, pgid string) ([]byte, error) {
+	args := cephCLIArgs(config, user, keyring, "list-inconsistent-obj", pgid, "--format", "json")
+	out, err := exec.CommandContext(ctx, radosCmd, args...).Output()
+	if err != nil {
+		return nil, newCephCLIError(args, err)
+	}
+
+	return out, nil
+}
+
 // PoolUsageCollector displays statistics about each pool in the Ceph cluster.
 type PoolUsageCollector struct {
 	conn   Conn
 	logger *logrus.Logger
 
+	// pgDumps shares the PG dump with the other collectors of the scrape.
+	pgDumps *pgDumpCache
+
+	// config, user and keyring are passed to the rados CLI listing the
+	// inconsistent objects.
+	config  string
+	user    string
+	keyring string
+
+	// scrapeTime accounts for the time spent running the rados CLI.
+	scrapeTime *ScrapeTimeCollector
+
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	// now returns the current time, scrub ages are computed relative to it.
+	now func() time.Time
+
+	// ioSamples holds the read and write counters of each pool at the
+	// previous collection, keyed by pool ID, to derive their rates.
+	ioSamples map[int]poolIOSample
+
+	// apps holds the applications of the pools as last read, used when they
+	// can't be read.
+	apps map[string]string
+
+	// opsRates enables the read and write op rates.
+	opsRates bool
+
+	// poolFilter restricts the pools to collect stats from, nil means all.
+	poolFilter *regexp.Regexp
+
+	// scrapeTimeout bounds the time spent getting the stats of the pools
+	// from rados, zero means no limit.
+	scrapeTimeout time.Duration
+
+	// inconsistentObjects enables listing the inconsistent objects of the
+	// inconsistent PGs through the rados CLI.
+	inconsistentObjects bool
+
+	runListInconsistentObjFn func(context.Context, string, string, string, string) ([]byte, error)
+
 	// UsedBytes tracks the amount of bytes currently allocated for the pool. This
 	// does not factor in the overcommitment made for individual images.
 	UsedBytes *prometheus.Desc
@@ -65,6 +142,92 @@ type PoolUsageCollector struct {
 
 	// WriteBytes tracks the write throughput made for the images within each pool.
 	WriteBytes *prometheus.Desc
+
+	// ReadBytesRate tracks the bytes read per second from each pool since
+	// the previous collection.
+	ReadBytesRate *prometheus.Desc
+
+	// WriteBytesRate tracks the bytes written per second to each pool since
+	// the previous collection.
+	WriteBytesRate *prometheus.Desc
+
+	// ReadOpsRate tracks the read I/O calls per second made to each pool
+	// since the previous collection.
+	ReadOpsRate *prometheus.Desc
+
+	// WriteOpsRate tracks the write I/O calls per second made to each pool
+	// since the previous collection.
+	WriteOpsRate *prometheus.Desc
+
+	// ReadWriteRatio tracks the no. of read I/O calls per write I/O call made
+	// since the pool was created, it characterizes the pool workload.
+	ReadWriteRatio *prometheus.Desc
+
+	// QuotaBytes tracks the maximum no. of bytes allowed in each pool, 0 means
+	// unlimited.
+	QuotaBytes *prometheus.Desc
+
+	// QuotaObjects tracks the maximum no. of objects allowed in each pool, 0
+	// means unlimited.
+	QuotaObjects *prometheus.Desc
+
+	// QuotaBytesUsedPercent tracks the share of the bytes quota of each pool
+	// in use, only for the pools with a bytes quota.
+	QuotaBytesUsedPercent *prometheus.Desc
+
+	// QuotaObjectsUsedPercent tracks the share of the objects quota of each
+	// pool in use, only for the pools with an objects quota.
+	QuotaObjectsUsedPercent *prometheus.Desc
+
+	// CompressBytesUsed tracks the amount of bytes allocated for compressed
+	// data within each pool.
+	CompressBytesUsed *prometheus.Desc
+
+	// CompressUnderBytes tracks the amount of data that was compressed within
+	// each pool, before compression.
+	CompressUnderBytes *prometheus.Desc
+
+	// PGs tracks the no. of PGs within each pool.
+	PGs *prometheus.Desc
+
+	// ActiveCleanPGs tracks the no. of PGs within each pool that are both
+	// active and clean.
+	ActiveCleanPGs *prometheus.Desc
+
+	// PGState tracks the no. of PGs within each pool in a given state.
+	PGState *prometheus.Desc
+
+	// RemappedPGs tracks the no. of PGs within each pool that are remapped,
+	// e.g. because one of their OSDs has been marked out.
+	RemappedPGs *prometheus.Desc
+
+	// MaxScrubAge tracks the time in seconds since the least recently
+	// scrubbed PG within each pool was last scrubbed.
+	MaxScrubAge *prometheus.Desc
+
+	// OMapBytesUsed tracks the raw capacity used by the omap data within
+	// each pool, only for the pools with omap data.
+	OMapBytesUsed *prometheus.Desc
+
+	// ScrubErrors tracks the no. of scrub errors within the pool according to
+	// the PG stats.
+	ScrubErrors *prometheus.Desc
+
+	// InconsistentObjects tracks the no. of objects the last scrub of the
+	// inconsistent PGs of the pool found inconsistent.
+	InconsistentObjects *prometheus.Desc
+
+	// OMapKeys tracks the no. of omap keys within each pool according to
+	// the PG stats, only for the pools with omap data.
+	OMapKeys *prometheus.Desc
+
+	// PGAutoscaleMode tracks the PG autoscaler mode of each pool: 0 when
+	// off, 1 when it only warns and 2 when it changes pg_num itself.
+	PGAutoscaleMode *prometheus.Desc
+
+	// TargetSizeRatio tracks the share of the cluster capacity each pool
+	// is expected to use, as set for the PG autoscaler.
+	TargetSizeRatio *prometheus.Desc
 }
 
 // NewPoolUsageCollector creates a new instance of PoolUsageCollector and returns
@@ -72,47 +235,137 @@ type PoolUsageCollector struct {
 func NewPoolUsageCollector(exporter *Exporter) *PoolUsageCollector {
 	var (
 		subSystem = "pool"
-		poolLabel = []string{"pool"}
+		poolLabel = []string{"pool", "application"}
 	)
 
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	// The offline exporters don't run the collectors, only describe them.
+	inconsistentObjects := exporter.PoolInconsistentObjects
+	if inconsistentObjects && !exporter.offline {
+		if err := radosCLIAvailable(); err != nil {
+			exporter.Logger.WithError(err).Warn("inconsistent objects of the pools disabled, the rados CLI is missing")
+			inconsistentObjects = false
+		}
+	}
 
 	return &PoolUsageCollector{
-		conn:   exporter.Conn,
-		logger: exporter.Logger,
+		conn:        exporter.Conn,
+		logger:      exporter.Logger,
+		config:      exporter.Config,
+		user:        exporter.User,
+		keyring:     exporter.Keyring,
+		scrapeTime:  exporter.scrapeTime,
+		parseErrors: exporter.parseErrors,
+		now:         time.Now,
+		pgDumps:     exporter.sharedPGDumps(),
+		ioSamples:   make(map[int]poolIOSample),
+		opsRates:    exporter.PoolOpsRates,
+
+		poolFilter:    exporter.PoolFilter,
+		scrapeTimeout: exporter.ScrapeTimeout,
+
+		inconsistentObjects:      inconsistentObjects,
+		runListInconsistentObjFn: runListInconsistentObj,
 
-		UsedBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_used_bytes", cephNamespace, subSystem), "Capacity of the pool that is currently under use",
+		UsedBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_used_bytes", namespace, subSystem), "Capacity of the pool that is currently under use",
 			poolLabel, labels,
 		),
-		RawUsedBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_raw_used_bytes", cephNamespace, subSystem), "Raw capacity of the pool that is currently under use, this factors in the size",
+		RawUsedBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_raw_used_bytes", namespace, subSystem), "Raw capacity of the pool that is currently under use, this factors in the size",
 			poolLabel, labels,
 		),
-		MaxAvail: prometheus.NewDesc(fmt.Sprintf("%s_%s_available_bytes", cephNamespace, subSystem), "Free space for the pool",
+		MaxAvail: prometheus.NewDesc(fmt.Sprintf("%s_%s_available_bytes", namespace, subSystem), "Free space for the pool",
 			poolLabel, labels,
 		),
-		PercentUsed: prometheus.NewDesc(fmt.Sprintf("%s_%s_percent_used", cephNamespace, subSystem), "Percentage of the capacity available to this pool that is used by this pool",
+		PercentUsed: prometheus.NewDesc(fmt.Sprintf("%s_%s_percent_used", namespace, subSystem), "Percentage of the capacity available to this pool that is used by this pool",
 			poolLabel, labels,
 		),
-		Objects: prometheus.NewDesc(fmt.Sprintf("%s_%s_objects_total", cephNamespace, subSystem), "Total no. of objects allocated within the pool",
+		Objects: prometheus.NewDesc(fmt.Sprintf("%s_%s_objects_total", namespace, subSystem), "Total no. of objects allocated within the pool",
 			poolLabel, labels,
 		),
-		DirtyObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_dirty_objects_total", cephNamespace, subSystem), "Total no. of dirty objects in a cache-tier pool",
+		DirtyObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_dirty_objects_total", namespace, subSystem), "Total no. of dirty objects in a cache-tier pool",
 			poolLabel, labels,
 		),
-		UnfoundObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_unfound_objects_total", cephNamespace, subSystem), "Total no. of unfound objects for the pool",
+		UnfoundObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_unfound_objects_total", namespace, subSystem), "Total no. of unfound objects for the pool",
 			poolLabel, labels,
 		),
-		ReadIO: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_total", cephNamespace, subSystem), "Total read I/O calls for the pool",
+		ReadIO: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_total", namespace, subSystem), "Total read I/O calls for the pool",
 			poolLabel, labels,
 		),
-		ReadBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_bytes_total", cephNamespace, subSystem), "Total read throughput for the pool",
+		ReadBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_bytes_total", namespace, subSystem), "Total read throughput for the pool",
 			poolLabel, labels,
 		),
-		WriteIO: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_total", cephNamespace, subSystem), "Total write I/O calls for the pool",
+		WriteIO: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_total", namespace, subSystem), "Total write I/O calls for the pool",
 			poolLabel, labels,
 		),
-		WriteBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_bytes_total", cephNamespace, subSystem), "Total write throughput for the pool",
+		WriteBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_bytes_total", namespace, subSystem), "Total write throughput for the pool",
+			poolLabel, labels,
+		),
+		ReadBytesRate: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_bytes_per_sec", namespace, subSystem), "Bytes read per second from the pool since the previous collection",
+			poolLabel, labels,
+		),
+		WriteBytesRate: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_bytes_per_sec", namespace, subSystem), "Bytes written per second to the pool since the previous collection",
+			poolLabel, labels,
+		),
+		ReadOpsRate: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_ops_per_sec", namespace, subSystem), "Read I/O calls per second made to the pool since the previous collection",
+			poolLabel, labels,
+		),
+		WriteOpsRate: prometheus.NewDesc(fmt.Sprintf("%s_%s_write_ops_per_sec", namespace, subSystem), "Write I/O calls per second made to the pool since the previous collection",
+			poolLabel, labels,
+		),
+		ReadWriteRatio: prometheus.NewDesc(fmt.Sprintf("%s_%s_read_write_ratio", namespace, subSystem), "Ratio of read to write I/O calls for the pool",
+			poolLabel, labels,
+		),
+		QuotaBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_quota_bytes", namespace, subSystem), "Maximum no. of bytes allowed in the pool, 0 means unlimited",
+			poolLabel, labels,
+		),
+		QuotaObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_quota_objects", namespace, subSystem), "Maximum no. of objects allowed in the pool, 0 means unlimited",
+			poolLabel, labels,
+		),
+		QuotaBytesUsedPercent: prometheus.NewDesc(fmt.Sprintf("%s_%s_quota_bytes_used_percent", namespace, subSystem), "Percentage of the bytes quota of the pool in use",
+			poolLabel, labels,
+		),
+		QuotaObjectsUsedPercent: prometheus.NewDesc(fmt.Sprintf("%s_%s_quota_objects_used_percent", namespace, subSystem), "Percentage of the objects quota of the pool in use",
+			poolLabel, labels,
+		),
+		CompressBytesUsed: prometheus.NewDesc(fmt.Sprintf("%s_%s_compress_bytes_used", namespace, subSystem), "Bytes allocated for compressed data within the pool",
+			poolLabel, labels,
+		),
+		CompressUnderBytes: prometheus.NewDesc(fmt.Sprintf("%s_%s_compress_under_bytes", namespace, subSystem), "Bytes of data within the pool that were compressed, before compression",
+			poolLabel, labels,
+		),
+		PGs: prometheus.NewDesc(fmt.Sprintf("%s_%s_pgs", namespace, subSystem), "No. of PGs within the pool",
+			poolLabel, labels,
+		),
+		ActiveCleanPGs: prometheus.NewDesc(fmt.Sprintf("%s_%s_active_clean_pgs", namespace, subSystem), "No. of active+clean PGs within the pool",
+			poolLabel, labels,
+		),
+		PGState: prometheus.NewDesc(fmt.Sprintf("%s_%s_pg_state", namespace, subSystem), "State of PGs within the pool",
+			[]string{"pool", "application", "state"}, labels,
+		),
+		RemappedPGs: prometheus.NewDesc(fmt.Sprintf("%s_%s_remapped_pgs", namespace, subSystem), "No. of PGs within the pool that are remapped",
+			poolLabel, labels,
+		),
+		MaxScrubAge: prometheus.NewDesc(fmt.Sprintf("%s_%s_max_scrub_age_seconds", namespace, subSystem), "Time since the least recently scrubbed PG within the pool was last scrubbed",
+			poolLabel, labels,
+		),
+		OMapBytesUsed: prometheus.NewDesc(fmt.Sprintf("%s_%s_omap_bytes_used", namespace, subSystem), "Raw capacity used by the omap data within the pool",
+			poolLabel, labels,
+		),
+		ScrubErrors: prometheus.NewDesc(fmt.Sprintf("%s_%s_scrub_errors", namespace, subSystem), "No. of scrub errors within the pool according to the PG stats",
+			poolLabel, labels,
+		),
+		InconsistentObjects: prometheus.NewDesc(fmt.Sprintf("%s_%s_inconsistent_objects", namespace, subSystem), "No. of objects found inconsistent by the last scrub of the inconsistent PGs within the pool",
+			poolLabel, labels,
+		),
+		OMapKeys: prometheus.NewDesc(fmt.Sprintf("%s_%s_omap_keys", namespace, subSystem), "No. of omap keys within the pool according to the PG stats",
+			poolLabel, labels,
+		),
+		PGAutoscaleMode: prometheus.NewDesc(fmt.Sprintf("%s_%s_pg_autoscale_mode", namespace, subSystem), "PG autoscaler mode of the pool: 0 off, 1 warn, 2 on",
+			poolLabel, labels,
+		),
+		TargetSizeRatio: prometheus.NewDesc(fmt.Sprintf("%s_%s_target_size_ratio", namespace, subSystem), "Share of the cluster capacity the pool is expected to use, as set for the PG autoscaler",
 			poolLabel, labels,
 		),
 	}
@@ -124,6 +377,7 @@ type cephPoolStats struct {
 		ID    int    `json:"id"`
 		Stats struct {
 			BytesUsed    float64 `json:"bytes_used"`
+			RawBytesUsed float64 `json:"raw_bytes_used"`
 			StoredRaw    float64 `json:"stored_raw"`
 			Stored       float64 `json:"stored"`
 			MaxAvail     float64 `json:"max_avail"`
@@ -134,11 +388,61 @@ type cephPoolStats struct {
 			ReadBytes    float64 `json:"rd_bytes"`
 			WriteIO      float64 `json:"wr"`
 			WriteBytes   float64 `json:"wr_bytes"`
+			QuotaBytes   float64 `json:"quota_bytes"`
+			QuotaObjects float64 `json:"quota_objects"`
+
+			CompressBytesUsed  float64 `json:"compress_bytes_used"`
+			CompressUnderBytes float64 `json:"compress_under_bytes"`
+
+			// OMapBytesUsed is only reported since Nautilus.
+			OMapBytesUsed *float64 `json:"omap_bytes_used"`
 		} `json:"stats"`
 	} `json:"pools"`
 }
 
-func (p *PoolUsageCollector) collect(ch chan<- prometheus.Metric) error {
+// poolIOSample holds the read and write counters of a pool at a given time.
+type poolIOSample struct {
+	at         time.Time
+	readBytes  float64
+	writeBytes float64
+	readOps    float64
+	writeOps   float64
+}
+
+// byteRate returns the per second rate of a counter between two samples.
+// It returns false when the counter went backwards, e.g. because the pool
+// was recreated with the same ID, or no time elapsed.
+func byteRate(prev, cur float64, elapsed time.Duration) (float64, bool) {
+	if cur < prev || elapsed <= 0 {
+		return 0, false
+	}
+
+	return (cur - prev) / elapsed.Seconds(), true
+}
+
+// opsRate returns the per second rate of an op counter between two samples.
+// Unlike byteRate, it reports a counter that went backwards, e.g. after an
+// OSD restart, as no ops rather than leaving the rate out.
+func opsRate(prev, cur float64, elapsed time.Duration) (float64, bool) {
+	if elapsed <= 0 {
+		return 0, false
+	}
+	if cur < prev {
+		return 0, true
+	}
+
+	return byteRate(prev, cur, elapsed)
+}
+
+func (p *PoolUsageCollector) collect(ch chan<- prometheus.Metric, version *Version) error {
+	// An unresponsive pool mustn't wedge the scrape.
+	ctx := context.Background()
+	if p.scrapeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.scrapeTimeout)
+		defer cancel()
+	}
+
 	cmd := p.cephUsageCommand()
 	buf, _, err := p.conn.MonCommand(cmd)
 	if err != nil {
@@ -151,22 +455,132 @@ func (p *PoolUsageCollector) collect(ch chan<- prometheus.Metric) error {
 
 	stats := &cephPoolStats{}
 	if err := json.Unmarshal(buf, stats); err != nil {
+		p.parseErrors.observe("poolUsage")
 		return err
 	}
 
+	pgStats, err := p.getPGStats()
+	if err != nil {
+		p.logger.WithError(err).Error("error getting pg stats per pool")
+	}
+
+	pools, err := p.poolApplications()
+	if err != nil {
+		p.logger.WithError(err).Error("error getting pool applications, using the last known ones")
+	}
+
+	// The byte rates are measured between consecutive collections, the
+	// samples of the pools that went away are dropped along the way.
+	now := p.now()
+	ioSamples := make(map[int]poolIOSample, len(stats.Pools))
+	defer func() {
+		p.ioSamples = ioSamples
+	}()
+
+	// The listings of all the pools share a single deadline.
+	listCtx, cancel := context.WithTimeout(ctx, inconsistentObjectsTimeout)
+	defer cancel()
+
 	for _, pool := range stats.Pools {
-		ch <- prometheus.MustNewConstMetric(p.UsedBytes, prometheus.GaugeValue, pool.Stats.Stored, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.RawUsedBytes, prometheus.GaugeValue, math.Max(pool.Stats.StoredRaw, pool.Stats.BytesUsed), pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.MaxAvail, prometheus.GaugeValue, pool.Stats.MaxAvail, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.PercentUsed, prometheus.GaugeValue, pool.Stats.PercentUsed, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.Objects, prometheus.GaugeValue, pool.Stats.Objects, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.DirtyObjects, prometheus.GaugeValue, pool.Stats.DirtyObjects, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.ReadIO, prometheus.GaugeValue, pool.Stats.ReadIO, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.ReadBytes, prometheus.GaugeValue, pool.Stats.ReadBytes, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.WriteIO, prometheus.GaugeValue, pool.Stats.WriteIO, pool.Name)
-		ch <- prometheus.MustNewConstMetric(p.WriteBytes, prometheus.GaugeValue, pool.Stats.WriteBytes, pool.Name)
-
-		st, err := p.conn.GetPoolStats(pool.Name)
+		if p.poolFilter != nil && !p.poolFilter.MatchString(pool.Name) {
+			continue
+		}
+
+		app, ok := pools.application(pool.Name)
+		if !ok {
+			// Keep the rate samples of the pool for the next collection.
+			if prev, ok := p.ioSamples[pool.ID]; ok {
+				ioSamples[pool.ID] = prev
+			}
+			continue
+		}
+
+		if pgStats != nil {
+			ch <- prometheus.MustNewConstMetric(p.PGs, prometheus.GaugeValue, pgStats.total[pool.ID], pool.Name, app)
+			ch <- prometheus.MustNewConstMetric(p.ActiveCleanPGs, prometheus.GaugeValue, pgStats.activeClean[pool.ID], pool.Name, app)
+			for _, state := range poolPGStates {
+				ch <- prometheus.MustNewConstMetric(p.PGState, prometheus.GaugeValue, pgStats.states[pool.ID][state], pool.Name, app, state)
+			}
+			ch <- prometheus.MustNewConstMetric(p.RemappedPGs, prometheus.GaugeValue, pgStats.remapped[pool.ID], pool.Name, app)
+			if age, ok := pgStats.maxScrubAge[pool.ID]; ok {
+				ch <- prometheus.MustNewConstMetric(p.MaxScrubAge, prometheus.GaugeValue, age, pool.Name, app)
+			}
+			if keys := pgStats.omapKeys[pool.ID]; keys > 0 {
+				ch <- prometheus.MustNewConstMetric(p.OMapKeys, prometheus.GaugeValue, keys, pool.Name, app)
+			}
+			ch <- prometheus.MustNewConstMetric(p.ScrubErrors, prometheus.GaugeValue, pgStats.scrubErrors[pool.ID], pool.Name, app)
+			if p.inconsistentObjects {
+				if objects, err := p.countInconsistentObjects(listCtx, pgStats.inconsistentPGs[pool.ID]); err != nil {
+					p.logger.WithError(err).WithField("pool", pool.Name).Error("error listing inconsistent objects")
+				} else {
+					ch <- prometheus.MustNewConstMetric(p.InconsistentObjects, prometheus.GaugeValue, objects, pool.Name, app)
+				}
+			}
+		}
+
+		// Before Nautilus, bytes_used was the data stored by the clients
+		// and the raw usage was reported as raw_bytes_used. Nautilus
+		// reports the former as stored and turned bytes_used into the raw
+		// usage.
+		stored, rawUsed := pool.Stats.Stored, math.Max(pool.Stats.StoredRaw, pool.Stats.BytesUsed)
+		if !version.IsAtLeast(Nautilus) {
+			stored, rawUsed = pool.Stats.BytesUsed, pool.Stats.RawBytesUsed
+		}
+
+		ch <- prometheus.MustNewConstMetric(p.UsedBytes, prometheus.GaugeValue, stored, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.RawUsedBytes, prometheus.GaugeValue, rawUsed, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.MaxAvail, prometheus.GaugeValue, pool.Stats.MaxAvail, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.PercentUsed, prometheus.GaugeValue, pool.Stats.PercentUsed, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.Objects, prometheus.GaugeValue, pool.Stats.Objects, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.DirtyObjects, prometheus.GaugeValue, pool.Stats.DirtyObjects, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.ReadIO, prometheus.GaugeValue, pool.Stats.ReadIO, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.ReadBytes, prometheus.GaugeValue, pool.Stats.ReadBytes, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.WriteIO, prometheus.GaugeValue, pool.Stats.WriteIO, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.WriteBytes, prometheus.GaugeValue, pool.Stats.WriteBytes, pool.Name, app)
+		if prev, ok := p.ioSamples[pool.ID]; ok {
+			elapsed := now.Sub(prev.at)
+			if rate, ok := byteRate(prev.readBytes, pool.Stats.ReadBytes, elapsed); ok {
+				ch <- prometheus.MustNewConstMetric(p.ReadBytesRate, prometheus.GaugeValue, rate, pool.Name, app)
+			}
+			if rate, ok := byteRate(prev.writeBytes, pool.Stats.WriteBytes, elapsed); ok {
+				ch <- prometheus.MustNewConstMetric(p.WriteBytesRate, prometheus.GaugeValue, rate, pool.Name, app)
+			}
+			if p.opsRates {
+				if rate, ok := opsRate(prev.readOps, pool.Stats.ReadIO, elapsed); ok {
+					ch <- prometheus.MustNewConstMetric(p.ReadOpsRate, prometheus.GaugeValue, rate, pool.Name, app)
+				}
+				if rate, ok := opsRate(prev.writeOps, pool.Stats.WriteIO, elapsed); ok {
+					ch <- prometheus.MustNewConstMetric(p.WriteOpsRate, prometheus.GaugeValue, rate, pool.Name, app)
+				}
+			}
+		}
+		ioSamples[pool.ID] = poolIOSample{
+			at:         now,
+			readBytes:  pool.Stats.ReadBytes,
+			writeBytes: pool.Stats.WriteBytes,
+			readOps:    pool.Stats.ReadIO,
+			writeOps:   pool.Stats.WriteIO,
+		}
+		if pool.Stats.WriteIO > 0 {
+			ch <- prometheus.MustNewConstMetric(p.ReadWriteRatio, prometheus.GaugeValue, pool.Stats.ReadIO/pool.Stats.WriteIO, pool.Name, app)
+		}
+		ch <- prometheus.MustNewConstMetric(p.QuotaBytes, prometheus.GaugeValue, pool.Stats.QuotaBytes, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.QuotaObjects, prometheus.GaugeValue, pool.Stats.QuotaObjects, pool.Name, app)
+		// The quotas are enforced against the data stored by the clients,
+		// not the raw usage.
+		if pool.Stats.QuotaBytes > 0 {
+			ch <- prometheus.MustNewConstMetric(p.QuotaBytesUsedPercent, prometheus.GaugeValue, stored/pool.Stats.QuotaBytes*100, pool.Name, app)
+		}
+		if pool.Stats.QuotaObjects > 0 {
+			ch <- prometheus.MustNewConstMetric(p.QuotaObjectsUsedPercent, prometheus.GaugeValue, pool.Stats.Objects/pool.Stats.QuotaObjects*100, pool.Name, app)
+		}
+		ch <- prometheus.MustNewConstMetric(p.CompressBytesUsed, prometheus.GaugeValue, pool.Stats.CompressBytesUsed, pool.Name, app)
+		ch <- prometheus.MustNewConstMetric(p.CompressUnderBytes, prometheus.GaugeValue, pool.Stats.CompressUnderBytes, pool.Name, app)
+		if omap := pool.Stats.OMapBytesUsed; omap != nil && *omap > 0 {
+			ch <- prometheus.MustNewConstMetric(p.OMapBytesUsed, prometheus.GaugeValue, *omap, pool.Name, app)
+		}
+
+		st, err := p.conn.GetPoolStatsContext(ctx, pool.Name)
 		if err != nil {
 			p.logger.WithError(err).WithField(
 				"pool", pool.Name,
@@ -175,12 +589,317 @@ func (p *PoolUsageCollector) collect(ch chan<- prometheus.Metric) error {
 			continue
 		}
 
-		ch <- prometheus.MustNewConstMetric(p.UnfoundObjects, prometheus.GaugeValue, float64(st.ObjectsUnfound), pool.Name)
+		ch <- prometheus.MustNewConstMetric(p.UnfoundObjects, prometheus.GaugeValue, float64(st.ObjectsUnfound), pool.Name, app)
 	}
 
+	p.collectAutoscaleStatus(ch, pools)
+
 	return nil
 }
 
+// countInconsistentObjects counts the objects the last scrub of the given
+// PGs found inconsistent. The objects aren't part of the PG stats, they are
+// listed through the rados CLI, only ever run for the inconsistent PGs.
+func (p *PoolUsageCollector) countInconsistentObjects(ctx context.Context, pgids []string) (float64, error) {
+	var objects float64
+	for _, pgid := range pgids {
+		if err := ctx.Err(); err != nil {
+			return 0, fmt.Errorf("error listing inconsistent objects of pg %s: %w", pgid, err)
+		}
+
+		start := time.Now()
+		buf, err := p.runListInconsistentObjFn(ctx, p.config, p.user, p.keyring, pgid)
+		p.scrapeTime.observeCLI(start)
+		if err != nil {
+			return 0, fmt.Errorf("error listing inconsistent objects of pg %s: %w", pgid, err)
+		}
+
+		var ls struct {
+			Inconsistents []json.RawMessage `json:"inconsistents"`
+		}
+		if err := json.Unmarshal(buf, &ls); err != nil {
+			p.parseErrors.observe("poolUsage")
+			return 0, fmt.Errorf("error unmarshalling inconsistent objects of pg %s: %w", pgid, err)
+		}
+
+		objects += float64(len(ls.Inconsistents))
+	}
+
+	return objects, nil
+}
+
+// poolAutoscaleModes maps the PG autoscaler modes to the values of
+// ceph_pool_pg_autoscale_mode.
+var poolAutoscaleModes = map[string]float64{
+	"off":  0,
+	"warn": 1,
+	"on":   2,
+}
+
+// collectAutoscaleStatus reports the PG autoscaler mode and target ratio of
+// the pools. The autoscaler is a mgr module that may be disabled, in which
+// case the command fails and the metrics are left out.
+func (p *PoolUsageCollector) collectAutoscaleStatus(ch chan<- prometheus.Metric, apps poolApplications) {
+	args := p.cephAutoscaleStatusCommand()
+	buf, _, err := p.conn.MgrCommand(args)
+	if err != nil {
+		p.logger.WithError(err).WithField(
+			"args", string(bytes.Join(args, []byte(","))),
+		).Debug("error getting pool autoscale status, is the pg_autoscaler module enabled?")
+
+		return
+	}
+
+	var pools []struct {
+		Name        string  `json:"pool_name"`
+		Mode        string  `json:"pg_autoscale_mode"`
+		TargetRatio float64 `json:"target_ratio"`
+	}
+	if err := json.Unmarshal(buf, &pools); err != nil {
+		p.parseErrors.observe("poolUsage")
+		p.logger.WithError(err).Error("error unmarshalling pool autoscale status")
+		return
+	}
+
+	for _, pool := range pools {
+		if p.poolFilter != nil && !p.poolFilter.MatchString(pool.Name) {
+			continue
+		}
+
+		app, ok := apps.application(pool.Name)
+		if !ok {
+			continue
+		}
+
+		if mode, ok := poolAutoscaleModes[pool.Mode]; ok {
+			ch <- prometheus.MustNewConstMetric(p.PGAutoscaleMode, prometheus.GaugeValue, mode, pool.Name, app)
+		}
+		ch <- prometheus.MustNewConstMetric(p.TargetSizeRatio, prometheus.GaugeValue, pool.TargetRatio, pool.Name, app)
+	}
+}
+
+// poolApplicationNone is the application label value of the pools that are
+// not tagged with any application.
+const poolApplicationNone = "none"
+
+// poolApplications holds the applications of the pools for a collection.
+type poolApplications struct {
+	apps map[string]string
+	// stale is set when the applications couldn't be read and are the ones
+	// of a previous collection.
+	stale bool
+}
+
+// application returns the application label value of pool. It is false for
+// the pools the stale applications don't know about, which are left out of
+// the collection rather than labeled none until the next one, so that no
+// series gets relabeled.
+func (a poolApplications) application(pool string) (string, bool) {
+	if app, ok := a.apps[pool]; ok {
+		return app, true
+	}
+
+	return poolApplicationNone, !a.stale
+}
+
+// poolApplications returns the applications of the pools, the last known
+// ones along with the error when they can't be read.
+func (p *PoolUsageCollector) poolApplications() (poolApplications, error) {
+	apps, err := p.getPoolApplications()
+	if err != nil {
+		return poolApplications{apps: p.apps, stale: true}, err
+	}
+
+	p.apps = apps
+	return poolApplications{apps: apps}, nil
+}
+
+// getPoolApplications returns the applications (rbd, cephfs, rgw...) each
+// pool is tagged with, keyed by pool name. Pools tagged with several
+// applications get them comma separated in alphabetical order.
+func (p *PoolUsageCollector) getPoolApplications() (map[string]string, error) {
+	cmd := p.cephPoolDetailCommand()
+	buf, _, err := p.conn.MonCommand(cmd)
+	if err != nil {
+		p.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return nil, err
+	}
+
+	var pools []struct {
+		Name                string                     `json:"pool_name"`
+		ApplicationMetadata map[string]json.RawMessage `json:"application_metadata"`
+	}
+	if err := json.Unmarshal(buf, &pools); err != nil {
+		p.parseErrors.observe("poolUsage")
+		return nil, err
+	}
+
+	apps := make(map[string]string)
+	for _, pool := range pools {
+		if len(pool.ApplicationMetadata) == 0 {
+			apps[pool.Name] = poolApplicationNone
+			continue
+		}
+
+		names := make([]string, 0, len(pool.ApplicationMetadata))
+		for name := range pool.ApplicationMetadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		apps[pool.Name] = strings.Join(names, ",")
+	}
+
+	return apps, nil
+}
+
+// poolPGStats holds the per pool aggregates computed from the PG dump, keyed
+// by pool ID.
+type poolPGStats struct {
+	total       map[int]float64
+	activeClean map[int]float64
+	states      map[int]map[string]float64
+	remapped    map[int]float64
+	maxScrubAge map[int]float64
+	omapKeys    map[int]float64
+	scrubErrors map[int]float64
+
+	// inconsistentPGs holds the IDs of the inconsistent PGs.
+	inconsistentPGs map[int][]string
+}
+
+// poolPGStates are the PG states broken down per pool, they match the ones
+// reported cluster-wide by ceph_pg_state but unclean, which isn't a flag of
+// the PG states and would always be 0. The PGs that aren't active+clean are
+// the difference between ceph_pool_pgs and ceph_pool_active_clean_pgs.
+var poolPGStates = []string{
+	"degraded",
+	"active",
+	"undersized",
+	"peering",
+	"activating",
+	"stale",
+	"scrubbing",
+	"deep_scrubbing",
+	"recovering",
+	"recovery_wait",
+	"backfilling",
+	"backfill_wait",
+	"forced_recovery",
+	"forced_backfill",
+	"down",
+	"incomplete",
+	"inconsistent",
+	"snaptrim",
+	"snaptrim_wait",
+	"repair",
+}
+
+// getPGStats aggregates the PG dump per pool.
+func (p *PoolUsageCollector) getPGStats() (*poolPGStats, error) {
+	pgDump, err := p.pgDumps.get("poolUsage")
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.now()
+	stats := &poolPGStats{
+		total:       make(map[int]float64),
+		activeClean: make(map[int]float64),
+		states:      make(map[int]map[string]float64),
+		remapped:    make(map[int]float64),
+		maxScrubAge: make(map[int]float64),
+		omapKeys:    make(map[int]float64),
+		scrubErrors: make(map[int]float64),
+
+		inconsistentPGs: make(map[int][]string),
+	}
+	for _, pg := range pgDump.PGStats {
+		poolID, err := pgPoolID(pg.PGID)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.total[poolID]++
+
+		if _, ok := stats.states[poolID]; !ok {
+			stats.states[poolID] = make(map[string]float64)
+		}
+
+		var active, clean bool
+		state := strings.ReplaceAll(pg.State, "scrubbing+deep", "deep_scrubbing")
+		for _, s := range strings.Split(state, "+") {
+			switch s {
+			case "active":
+				active = true
+			case "clean":
+				clean = true
+			}
+			stats.states[poolID][s]++
+		}
+
+		if active && clean {
+			stats.activeClean[poolID]++
+		}
+
+		if strings.Contains(pg.State, "remapped") {
+			stats.remapped[poolID]++
+		}
+
+		if strings.Contains(pg.State, "inconsistent") {
+			stats.inconsistentPGs[poolID] = append(stats.inconsistentPGs[poolID], pg.PGID)
+		}
+
+		stats.omapKeys[poolID] += pg.StatSum.NumOMapKeys
+		stats.scrubErrors[poolID] += pg.StatSum.NumScrubErrors
+
+		if age, ok := scrubAge(pg.LastScrubStamp, now); ok {
+			if maxAge, found := stats.maxScrubAge[poolID]; !found || age > maxAge {
+				stats.maxScrubAge[poolID] = age
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// pgPoolID returns the ID of the pool the given PG belongs to. PG IDs are
+// of the form "<pool id>.<pg seed in hex>", e.g. "11.1f".
+func pgPoolID(pgid string) (int, error) {
+	parts := strings.SplitN(pgid, ".", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid pgid %q", pgid)
+	}
+
+	return strconv.Atoi(parts[0])
+}
+
+func (p *PoolUsageCollector) cephAutoscaleStatusCommand() [][]byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "osd pool autoscale-status",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		p.logger.WithError(err).Panic("error marshalling ceph osd pool autoscale-status")
+	}
+	return [][]byte{cmd}
+}
+
+func (p *PoolUsageCollector) cephPoolDetailCommand() []byte {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "osd pool ls",
+		"detail": "detail",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		p.logger.WithError(err).Panic("error marshalling ceph osd pool ls")
+	}
+	return cmd
+}
+
 func (p *PoolUsageCollector) cephUsageCommand() []byte {
 	cmd, err := json.Marshal(map[string]interface{}{
 		"prefix": "df",
@@ -207,14 +926,38 @@ func (p *PoolUsageCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- p.ReadBytes
 	ch <- p.WriteIO
 	ch <- p.WriteBytes
+	ch <- p.ReadBytesRate
+	ch <- p.WriteBytesRate
+	ch <- p.ReadOpsRate
+	ch <- p.WriteOpsRate
+	ch <- p.ReadWriteRatio
+	ch <- p.QuotaBytes
+	ch <- p.QuotaObjects
+	ch <- p.QuotaBytesUsedPercent
+	ch <- p.QuotaObjectsUsedPercent
+	ch <- p.CompressBytesUsed
+	ch <- p.CompressUnderBytes
+	ch <- p.PGs
+	ch <- p.ActiveCleanPGs
+	ch <- p.PGState
+	ch <- p.RemappedPGs
+	ch <- p.MaxScrubAge
+	ch <- p.ScrubErrors
+	ch <- p.InconsistentObjects
+	ch <- p.OMapBytesUsed
+	ch <- p.OMapKeys
+	ch <- p.PGAutoscaleMode
+	ch <- p.TargetSizeRatio
 }
 
 // Collect extracts the current values of all the metrics and sends them to the
 // prometheus channel.
-func (p *PoolUsageCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+func (p *PoolUsageCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
 	p.logger.Debug("collecting pool usage metrics")
-	if err := p.collect(ch); err != nil {
+	if err := p.collect(ch, version); err != nil {
 		p.logger.WithError(err).Error("error collecting pool usage metrics")
-		return
+		return err
 	}
+
+	return nil
 }
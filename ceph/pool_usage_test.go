@@ -15,13 +15,19 @@
 package ceph
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
@@ -32,6 +38,12 @@ import (
 func TestPoolUsageCollector(t *testing.T) {
 	for _, tt := range []struct {
 		input              string
+		pgDump             string
+		poolStats          map[string]*PoolStat
+		poolDetail         string
+		autoscaleStatus    string
+		inconsistentObjs   map[string]string
+		poolFilter         *regexp.Regexp
 		version            string
 		reMatch, reUnmatch []*regexp.Regexp
 	}{
@@ -42,24 +54,47 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 20`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 5`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 4`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 6`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 5`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 4`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 6`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
 		{
 			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "stored_raw": 60, "bytes_used": 72, "objects": 5}}
+]}`,
+			version: `{"version":"ceph version 14.2.18-97-gcc1e126 (cc1e1267bc7afc8288c718fc3e59c5a6735f6f4a) nautilus (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20\n`),
+				regexp.MustCompile(`pool_raw_used_bytes{application="none",cluster="ceph",pool="rbd"} 72\n`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"bytes_used": 20, "raw_bytes_used": 60, "objects": 5, "quota_bytes": 40}}
+]}`,
+			version: `{"version":"ceph version 13.2.10 (564bdc4ae87418a232fc901524470e1a0f76d641) mimic (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20\n`),
+				regexp.MustCompile(`pool_raw_used_bytes{application="none",cluster="ceph",pool="rbd"} 60\n`),
+				regexp.MustCompile(`pool_quota_bytes_used_percent{application="none",cluster="ceph",pool="rbd"} 50\n`),
+			},
+		},
+		{
+			input: `
 {"pools": [
 	{"name": "rbd", "id": 11, "stats": {"objects": 5, "rd": 4, "wr": 6}}
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 0`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 5`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 4`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 6`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 5`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 4`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 6`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
@@ -70,10 +105,10 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 20`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 0`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 4`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 6`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 4`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 6`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
@@ -84,10 +119,10 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 20`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 5`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 0`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 6`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 5`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 6`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
@@ -98,12 +133,15 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 20`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 5`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 4`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 5`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 4`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// No writes, the ratio is undefined.
+				regexp.MustCompile(`pool_read_write_ratio{`),
 			},
-			reUnmatch: []*regexp.Regexp{},
 		},
 		{
 			input: `
@@ -127,14 +165,14 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd"} 20`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd"} 5`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd"} 4`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd"} 6`),
-				regexp.MustCompile(`pool_used_bytes{cluster="ceph",pool="rbd-new"} 50`),
-				regexp.MustCompile(`pool_objects_total{cluster="ceph",pool="rbd-new"} 20`),
-				regexp.MustCompile(`pool_read_total{cluster="ceph",pool="rbd-new"} 10`),
-				regexp.MustCompile(`pool_write_total{cluster="ceph",pool="rbd-new"} 30`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd"} 5`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd"} 4`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd"} 6`),
+				regexp.MustCompile(`pool_used_bytes{application="none",cluster="ceph",pool="rbd-new"} 50`),
+				regexp.MustCompile(`pool_objects_total{application="none",cluster="ceph",pool="rbd-new"} 20`),
+				regexp.MustCompile(`pool_read_total{application="none",cluster="ceph",pool="rbd-new"} 10`),
+				regexp.MustCompile(`pool_write_total{application="none",cluster="ceph",pool="rbd-new"} 30`),
 			},
 			reUnmatch: []*regexp.Regexp{},
 		},
@@ -145,7 +183,7 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_available_bytes{cluster="ceph",pool="ssd"} 4.618201748262e\+12`),
+				regexp.MustCompile(`pool_available_bytes{application="none",cluster="ceph",pool="ssd"} 4.618201748262e\+12`),
 			},
 		},
 		{
@@ -155,7 +193,7 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`pool_percent_used{cluster="ceph",pool="ssd"} 1.3390908861765638e\-06`),
+				regexp.MustCompile(`pool_percent_used{application="none",cluster="ceph",pool="ssd"} 1.3390908861765638e\-06`),
 			},
 		},
 		{
@@ -166,41 +204,388 @@ func TestPoolUsageCollector(t *testing.T) {
 ]}`,
 			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
 			reMatch: []*regexp.Regexp{
-				regexp.MustCompile(`ceph_pool_available_bytes{cluster="ceph",pool="cinder_sas"} 6.038098673664e\+12`),
-				regexp.MustCompile(`ceph_pool_dirty_objects_total{cluster="ceph",pool="cinder_sas"} 17124`),
-				regexp.MustCompile(`ceph_pool_objects_total{cluster="ceph",pool="cinder_sas"} 17124`),
-				regexp.MustCompile(`ceph_pool_raw_used_bytes{cluster="ceph",pool="cinder_sas"} 2.14576054272e\+11`),
-				regexp.MustCompile(`ceph_pool_read_bytes_total{cluster="ceph",pool="cinder_sas"} 3.288983853056e\+12`),
-				regexp.MustCompile(`ceph_pool_read_total{cluster="ceph",pool="cinder_sas"} 3.48986643e\+08`),
-				regexp.MustCompile(`ceph_pool_used_bytes{cluster="ceph",pool="cinder_sas"} 7.1525351713e\+10`),
-				regexp.MustCompile(`ceph_pool_write_bytes_total{cluster="ceph",pool="cinder_sas"} 2.72268791808e\+11`),
-				regexp.MustCompile(`ceph_pool_write_total{cluster="ceph",pool="cinder_sas"} 4.5792703e\+07`),
-				regexp.MustCompile(`ceph_pool_available_bytes{cluster="ceph",pool="cinder_ssd"} 1.86205372416e\+11`),
-				regexp.MustCompile(`ceph_pool_dirty_objects_total{cluster="ceph",pool="cinder_ssd"} 16461`),
-				regexp.MustCompile(`ceph_pool_objects_total{cluster="ceph",pool="cinder_ssd"} 16461`),
-				regexp.MustCompile(`ceph_pool_raw_used_bytes{cluster="ceph",pool="cinder_ssd"} 2.06596702208e\+11`),
-				regexp.MustCompile(`ceph_pool_read_bytes_total{cluster="ceph",pool="cinder_ssd"} 1.2899328e\+07`),
-				regexp.MustCompile(`ceph_pool_read_total{cluster="ceph",pool="cinder_ssd"} 347`),
-				regexp.MustCompile(`ceph_pool_used_bytes{cluster="ceph",pool="cinder_ssd"} 6.8865564849e\+10`),
-				regexp.MustCompile(`ceph_pool_write_bytes_total{cluster="ceph",pool="cinder_ssd"} 6.8882356224e\+10`),
-				regexp.MustCompile(`ceph_pool_write_total{cluster="ceph",pool="cinder_ssd"} 26721`),
+				regexp.MustCompile(`ceph_pool_available_bytes{application="none",cluster="ceph",pool="cinder_sas"} 6.038098673664e\+12`),
+				regexp.MustCompile(`ceph_pool_dirty_objects_total{application="none",cluster="ceph",pool="cinder_sas"} 17124`),
+				regexp.MustCompile(`ceph_pool_objects_total{application="none",cluster="ceph",pool="cinder_sas"} 17124`),
+				regexp.MustCompile(`ceph_pool_raw_used_bytes{application="none",cluster="ceph",pool="cinder_sas"} 2.14576054272e\+11`),
+				regexp.MustCompile(`ceph_pool_read_bytes_total{application="none",cluster="ceph",pool="cinder_sas"} 3.288983853056e\+12`),
+				regexp.MustCompile(`ceph_pool_read_total{application="none",cluster="ceph",pool="cinder_sas"} 3.48986643e\+08`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="cinder_sas"} 7.1525351713e\+10`),
+				regexp.MustCompile(`ceph_pool_write_bytes_total{application="none",cluster="ceph",pool="cinder_sas"} 2.72268791808e\+11`),
+				regexp.MustCompile(`ceph_pool_write_total{application="none",cluster="ceph",pool="cinder_sas"} 4.5792703e\+07`),
+				regexp.MustCompile(`ceph_pool_available_bytes{application="none",cluster="ceph",pool="cinder_ssd"} 1.86205372416e\+11`),
+				regexp.MustCompile(`ceph_pool_dirty_objects_total{application="none",cluster="ceph",pool="cinder_ssd"} 16461`),
+				regexp.MustCompile(`ceph_pool_objects_total{application="none",cluster="ceph",pool="cinder_ssd"} 16461`),
+				regexp.MustCompile(`ceph_pool_raw_used_bytes{application="none",cluster="ceph",pool="cinder_ssd"} 2.06596702208e\+11`),
+				regexp.MustCompile(`ceph_pool_read_bytes_total{application="none",cluster="ceph",pool="cinder_ssd"} 1.2899328e\+07`),
+				regexp.MustCompile(`ceph_pool_read_total{application="none",cluster="ceph",pool="cinder_ssd"} 347`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="cinder_ssd"} 6.8865564849e\+10`),
+				regexp.MustCompile(`ceph_pool_write_bytes_total{application="none",cluster="ceph",pool="cinder_ssd"} 6.8882356224e\+10`),
+				regexp.MustCompile(`ceph_pool_write_total{application="none",cluster="ceph",pool="cinder_ssd"} 26721`),
+				regexp.MustCompile(`ceph_pool_read_write_ratio{application="none",cluster="ceph",pool="cinder_sas"} 7.62100990`),
+				regexp.MustCompile(`ceph_pool_read_write_ratio{application="none",cluster="ceph",pool="cinder_ssd"} 0.01298604`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "quota_bytes": 81032609792, "quota_objects": 20000}},
+	{"name": "rgw", "id": 12, "stats": {"stored": 20, "objects": 5}}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_quota_bytes{application="none",cluster="ceph",pool="rbd"} 8.1032609792e\+10\n`),
+				regexp.MustCompile(`ceph_pool_quota_objects{application="none",cluster="ceph",pool="rbd"} 20000\n`),
+				// Unset quotas are reported as 0, unlimited.
+				regexp.MustCompile(`ceph_pool_quota_bytes{application="none",cluster="ceph",pool="rgw"} 0\n`),
+				regexp.MustCompile(`ceph_pool_quota_objects{application="none",cluster="ceph",pool="rgw"} 0\n`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 750, "objects": 50, "quota_bytes": 1000, "quota_objects": 400}},
+	{"name": "rgw", "id": 12, "stats": {"stored": 750, "objects": 50, "quota_bytes": 0, "quota_objects": 0}}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_quota_bytes_used_percent{application="none",cluster="ceph",pool="rbd"} 75\n`),
+				regexp.MustCompile(`ceph_pool_quota_objects_used_percent{application="none",cluster="ceph",pool="rbd"} 12.5\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_quota_[a-z]+_used_percent{application="none",cluster="ceph",pool="rgw"}`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "rgw.buckets.data", "id": 12, "stats": {"stored": 3298534883328, "objects": 51200, "stored_raw": 9895604649984, "compress_bytes_used": 1099511627776, "compress_under_bytes": 2748779069440}}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_compress_bytes_used{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`ceph_pool_compress_under_bytes{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`ceph_pool_compress_bytes_used{application="none",cluster="ceph",pool="rgw.buckets.data"} 1.099511627776e\+12`),
+				regexp.MustCompile(`ceph_pool_compress_under_bytes{application="none",cluster="ceph",pool="rgw.buckets.data"} 2.74877906944e\+12`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "up_primary": 1, "acting": [1, 2, 3], "acting_primary": 1},
+	{"pgid": "11.1", "state": "active+undersized+degraded", "up_primary": 2, "acting": [2, 3], "acting_primary": 2}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_pgs{application="none",cluster="ceph",pool="rbd"} 2\n`),
+				regexp.MustCompile(`ceph_pool_active_clean_pgs{application="none",cluster="ceph",pool="rbd"} 1\n`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rbd",state="undersized"} 1\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// No PG state carries an unclean flag.
+				regexp.MustCompile(`ceph_pool_pg_state{[^}]*state="unclean"}`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "rgw", "id": 12, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "up_primary": 1, "acting": [1, 2, 3], "acting_primary": 1, "last_scrub_stamp": "2024-01-10T12:00:00.000000+0000"},
+	{"pgid": "11.1", "state": "active+clean", "up_primary": 2, "acting": [2, 3, 4], "acting_primary": 2, "last_scrub_stamp": "2024-01-09T13:00:00.000000+0000"},
+	{"pgid": "12.0", "state": "active+remapped+backfill_wait", "up_primary": 1, "acting": [1, 4, 5], "acting_primary": 1, "last_scrub_stamp": "2024-01-10 12:30:00.000000"},
+	{"pgid": "12.1a", "state": "active+undersized+degraded+remapped+backfilling", "up_primary": 3, "acting": [3, 4, 5], "acting_primary": 3, "last_scrub_stamp": "2024-01-10T12:59:00.000000+0000"},
+	{"pgid": "12.1b", "state": "active+clean+remapped", "up_primary": 5, "acting": [5, 4, 1], "acting_primary": 5, "last_scrub_stamp": "0.000000"}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_remapped_pgs{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`ceph_pool_remapped_pgs{application="none",cluster="ceph",pool="rgw"} 3`),
+				regexp.MustCompile(`ceph_pool_pgs{application="none",cluster="ceph",pool="rbd"} 2`),
+				regexp.MustCompile(`ceph_pool_pgs{application="none",cluster="ceph",pool="rgw"} 3`),
+				regexp.MustCompile(`ceph_pool_active_clean_pgs{application="none",cluster="ceph",pool="rbd"} 2`),
+				regexp.MustCompile(`ceph_pool_active_clean_pgs{application="none",cluster="ceph",pool="rgw"} 1`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rbd",state="active"} 2`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rbd",state="backfill_wait"} 0`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rgw",state="active"} 3`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rgw",state="backfill_wait"} 1`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rgw",state="backfilling"} 1`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="none",cluster="ceph",pool="rgw",state="degraded"} 1`),
+				regexp.MustCompile(`ceph_pool_max_scrub_age_seconds{application="none",cluster="ceph",pool="rbd"} 86400`),
+				regexp.MustCompile(`ceph_pool_max_scrub_age_seconds{application="none",cluster="ceph",pool="rgw"} 1800`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "rgw", "id": 12, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "acting": [1, 2, 3], "acting_primary": 1, "stat_sum": {"num_objects": 10, "num_objects_unfound": 0}},
+	{"pgid": "12.0", "state": "active+recovery_unfound+degraded", "acting": [1, 4, 5], "acting_primary": 1, "stat_sum": {"num_objects": 10, "num_objects_unfound": 3}},
+	{"pgid": "12.1", "state": "active+recovery_unfound+degraded", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 10, "num_objects_unfound": 2}}
+]}`,
+			poolStats: map[string]*PoolStat{
+				"rbd": {ObjectsUnfound: 0},
+				"rgw": {ObjectsUnfound: 5},
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_unfound_objects_total{application="none",cluster="ceph",pool="rbd"} 0`),
+				regexp.MustCompile(`ceph_pool_unfound_objects_total{application="none",cluster="ceph",pool="rgw"} 5`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_unfound_objects{`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "rgw", "id": 12, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "legacy", "id": 13, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "acting": [1, 2, 3], "acting_primary": 1, "stat_sum": {"num_objects": 10, "num_scrub_errors": 0}},
+	{"pgid": "12.0", "state": "active+clean+inconsistent", "acting": [1, 4, 5], "acting_primary": 1, "stat_sum": {"num_objects": 10, "num_scrub_errors": 3}},
+	{"pgid": "12.1", "state": "active+clean+scrubbing+deep+inconsistent+repair", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 10, "num_scrub_errors": 1}},
+	{"pgid": "12.2", "state": "active+clean", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 10, "num_scrub_errors": 0}},
+	{"pgid": "13.0", "state": "active+clean+inconsistent", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 10, "num_scrub_errors": 2}}
+]}`,
+			inconsistentObjs: map[string]string{
+				"12.0": `{"epoch": 1021, "inconsistents": [{"object": {"name": "rbd_data.1"}, "errors": ["data_digest_mismatch"]}, {"object": {"name": "rbd_data.2"}, "errors": ["size_mismatch"]}]}`,
+				"12.1": `{"epoch": 1021, "inconsistents": [{"object": {"name": "rbd_data.3"}, "errors": ["read_error"]}]}`,
+			},
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_scrub_errors{application="none",cluster="ceph",pool="rbd"} 0\n`),
+				regexp.MustCompile(`ceph_pool_scrub_errors{application="none",cluster="ceph",pool="rgw"} 4\n`),
+				regexp.MustCompile(`ceph_pool_scrub_errors{application="none",cluster="ceph",pool="legacy"} 2\n`),
+				regexp.MustCompile(`ceph_pool_inconsistent_objects{application="none",cluster="ceph",pool="rbd"} 0\n`),
+				regexp.MustCompile(`ceph_pool_inconsistent_objects{application="none",cluster="ceph",pool="rgw"} 3\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// The scrub information of 13.0 expired, its objects are unknown.
+				regexp.MustCompile(`ceph_pool_inconsistent_objects{application="none",cluster="ceph",pool="legacy"}`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "omap_bytes_used": 0}},
+	{"name": "rgw.buckets.index", "id": 12, "stats": {"stored": 0, "objects": 2, "omap_bytes_used": 3145728}},
+	{"name": "legacy", "id": 13, "stats": {"bytes_used": 20, "objects": 5}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+clean", "acting": [1, 2, 3], "acting_primary": 1, "stat_sum": {"num_objects": 5, "num_omap_bytes": 0, "num_omap_keys": 0}},
+	{"pgid": "12.0", "state": "active+clean", "acting": [1, 4, 5], "acting_primary": 1, "stat_sum": {"num_objects": 1, "num_omap_bytes": 524288, "num_omap_keys": 1200}},
+	{"pgid": "12.1", "state": "active+clean", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 1, "num_omap_bytes": 524288, "num_omap_keys": 800}},
+	{"pgid": "13.0", "state": "active+clean", "acting": [3, 4, 5], "acting_primary": 3, "stat_sum": {"num_objects": 5}}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_omap_bytes_used{application="none",cluster="ceph",pool="rgw.buckets.index"} 3.145728e\+06`),
+				regexp.MustCompile(`ceph_pool_omap_keys{application="none",cluster="ceph",pool="rgw.buckets.index"} 2000`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// No omap data, or not reported at all.
+				regexp.MustCompile(`ceph_pool_omap_[a-z_]+{application="none",cluster="ceph",pool="rbd"}`),
+				regexp.MustCompile(`ceph_pool_omap_[a-z_]+{application="none",cluster="ceph",pool="legacy"}`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "cephfs.data", "id": 12, "stats": {"stored": 30, "objects": 7, "rd": 4, "wr": 6}},
+	{"name": "scratch", "id": 13, "stats": {"stored": 40, "objects": 9, "rd": 4, "wr": 6}},
+	{"name": "shared", "id": 14, "stats": {"stored": 50, "objects": 11, "rd": 4, "wr": 6}}
+]}`,
+			poolDetail: `
+[
+	{"pool_name": "rbd", "pool_id": 11, "application_metadata": {"rbd": {}}},
+	{"pool_name": "cephfs.data", "pool_id": 12, "application_metadata": {"cephfs": {"data": "cephfs"}}},
+	{"pool_name": "scratch", "pool_id": 13, "application_metadata": {}},
+	{"pool_name": "shared", "pool_id": 14, "application_metadata": {"rgw": {}, "rbd": {}}}
+]`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_used_bytes{application="rbd",cluster="ceph",pool="rbd"} 20`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="cephfs",cluster="ceph",pool="cephfs.data"} 30`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="scratch"} 40`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="rbd,rgw",cluster="ceph",pool="shared"} 50`),
+				regexp.MustCompile(`ceph_pool_pg_state{application="rbd",cluster="ceph",pool="rbd",state="active"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "cinder_sas", "id": 32, "stats": {"stored": 30, "objects": 7, "rd": 4, "wr": 6}},
+	{"name": "cinder_ssd", "id": 33, "stats": {"stored": 40, "objects": 9, "rd": 4, "wr": 6}}
+]}`,
+			pgDump: `
+{"pg_stats": [
+	{"pgid": "11.0", "state": "active+remapped", "acting": [1, 2, 3], "acting_primary": 1},
+	{"pgid": "32.0", "state": "active+remapped", "acting": [1, 2, 3], "acting_primary": 1}
+]}`,
+			poolFilter: regexp.MustCompile(`^cinder_`),
+			version:    `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="cinder_sas"} 30`),
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="cinder_ssd"} 40`),
+				regexp.MustCompile(`ceph_pool_remapped_pgs{application="none",cluster="ceph",pool="cinder_sas"} 1`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`pool="rbd"`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "cephfs_data", "id": 12, "stats": {"stored": 30, "objects": 7, "rd": 4, "wr": 6}},
+	{"name": "scratch", "id": 13, "stats": {"stored": 40, "objects": 9, "rd": 4, "wr": 6}}
+]}`,
+			autoscaleStatus: `
+[
+	{"pool_name": "rbd", "pool_id": 11, "target_ratio": 0.2, "pg_autoscale_mode": "on"},
+	{"pool_name": "cephfs_data", "pool_id": 12, "target_ratio": 0.0, "pg_autoscale_mode": "warn"},
+	{"pool_name": "scratch", "pool_id": 13, "target_ratio": 0.0, "pg_autoscale_mode": "off"}
+]`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode{application="none",cluster="ceph",pool="rbd"} 2`),
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode{application="none",cluster="ceph",pool="cephfs_data"} 1`),
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode{application="none",cluster="ceph",pool="scratch"} 0`),
+				regexp.MustCompile(`ceph_pool_target_size_ratio{application="none",cluster="ceph",pool="rbd"} 0.2`),
+				regexp.MustCompile(`ceph_pool_target_size_ratio{application="none",cluster="ceph",pool="scratch"} 0`),
+			},
+		},
+		{
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}},
+	{"name": "scratch", "id": 13, "stats": {"stored": 40, "objects": 9, "rd": 4, "wr": 6}}
+]}`,
+			poolDetail: `
+[
+	{"pool_name": "rbd", "pool_id": 11, "application_metadata": {"rbd": {}}},
+	{"pool_name": "scratch", "pool_id": 13, "application_metadata": {}}
+]`,
+			autoscaleStatus: `
+[
+	{"pool_name": "rbd", "pool_id": 11, "target_ratio": 0.2, "pg_autoscale_mode": "on"},
+	{"pool_name": "scratch", "pool_id": 13, "target_ratio": 0.0, "pg_autoscale_mode": "off"}
+]`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode{application="rbd",cluster="ceph",pool="rbd"} 2\n`),
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode{application="none",cluster="ceph",pool="scratch"} 0\n`),
+				regexp.MustCompile(`ceph_pool_target_size_ratio{application="rbd",cluster="ceph",pool="rbd"} 0.2\n`),
+				regexp.MustCompile(`ceph_pool_target_size_ratio{application="none",cluster="ceph",pool="scratch"} 0\n`),
+			},
+		},
+		{
+			// The pg_autoscaler module is disabled.
+			input: `
+{"pools": [
+	{"name": "rbd", "id": 11, "stats": {"stored": 20, "objects": 5, "rd": 4, "wr": 6}}
+]}`,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="rbd"} 20`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_pool_pg_autoscale_mode`),
+				regexp.MustCompile(`ceph_pool_target_size_ratio`),
 			},
 		},
 	} {
 		func() {
 			conn := setupVersionMocks(tt.version, "{}")
 
+			poolDetail := tt.poolDetail
+			if poolDetail == "" {
+				poolDetail = `[]`
+			}
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([]byte), &v)
+				require.NoError(t, err)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "osd pool ls",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return(
+				[]byte(poolDetail), "", nil,
+			)
+
 			conn.On("MonCommand", mock.Anything).Return(
 				[]byte(tt.input), "", nil,
 			)
 
-			conn.On("GetPoolStats", mock.Anything).Return(
+			autoscaleStatus := mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				err := json.Unmarshal(in.([][]byte)[0], &v)
+				require.NoError(t, err)
+
+				return v["prefix"] == "osd pool autoscale-status"
+			})
+			if tt.autoscaleStatus != "" {
+				conn.On("MgrCommand", autoscaleStatus).Return([]byte(tt.autoscaleStatus), "", nil)
+			} else {
+				conn.On("MgrCommand", autoscaleStatus).Return(nil, "", fmt.Errorf("module 'pg_autoscaler' is not enabled"))
+			}
+
+			pgDump := tt.pgDump
+			if pgDump == "" {
+				pgDump = `{"pg_stats": []}`
+			}
+			conn.On("MgrCommand", mock.Anything).Return(
+				[]byte(pgDump), "", nil,
+			)
+
+			for pool, st := range tt.poolStats {
+				conn.On("GetPoolStatsContext", mock.Anything, pool).Return(st, nil)
+			}
+			conn.On("GetPoolStatsContext", mock.Anything, mock.Anything).Return(
 				nil, fmt.Errorf("not implemented"),
 			)
 
-			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), PoolFilter: tt.poolFilter}
+			poolUsage := NewPoolUsageCollector(e)
+			poolUsage.now = func() time.Time {
+				return time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+			}
+			poolUsage.inconsistentObjects = tt.inconsistentObjs != nil
+			poolUsage.runListInconsistentObjFn = func(_ context.Context, config, user, keyring, pgid string) ([]byte, error) {
+				if objs, ok := tt.inconsistentObjs[pgid]; ok {
+					return []byte(objs), nil
+				}
+				return nil, fmt.Errorf("No scrub information available for pg %s", pgid)
+			}
 			e.cc = map[string]versionedCollector{
-				"poolUsage": NewPoolUsageCollector(e),
+				"poolUsage": poolUsage,
 			}
 			err := prometheus.Register(e)
 			require.NoError(t, err)
@@ -225,3 +610,332 @@ func TestPoolUsageCollector(t *testing.T) {
 		}()
 	}
 }
+
+func TestPoolUsageByteRates(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	df := func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	}
+	conn.On("MonCommand", mock.MatchedBy(df)).Return([]byte(`
+{"pools": [
+	{"id": 32, "name": "cinder_sas", "stats": {"rd_bytes": 3288983853056, "wr_bytes": 272268791808}},
+	{"id": 33, "name": "cinder_ssd", "stats": {"rd_bytes": 12899328, "wr_bytes": 68882356224}}
+]}`), "", nil).Once()
+	conn.On("MonCommand", mock.MatchedBy(df)).Return([]byte(`
+{"pools": [
+	{"id": 32, "name": "cinder_sas", "stats": {"rd_bytes": 3288986853056, "wr_bytes": 272269391808}},
+	{"id": 33, "name": "cinder_ssd", "stats": {"rd_bytes": 1024, "wr_bytes": 68882359224}}
+]}`), "", nil).Once()
+	conn.On("MonCommand", mock.Anything).Return([]byte(`[]`), "", nil)
+	conn.On("MgrCommand", mock.Anything).Return([]byte(`{"pg_stats": []}`), "", nil)
+	conn.On("GetPoolStatsContext", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("not implemented"))
+
+	now := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	poolUsage := NewPoolUsageCollector(e)
+	poolUsage.now = func() time.Time {
+		return now
+	}
+	e.cc = map[string]versionedCollector{
+		"poolUsage": poolUsage,
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return buf
+	}
+
+	// There's nothing to derive a rate from on the first collection.
+	buf := scrape()
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_(read|write)_bytes_per_sec`), string(buf))
+
+	now = now.Add(30 * time.Second)
+	buf = scrape()
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_pool_read_bytes_per_sec{application="none",cluster="ceph",pool="cinder_sas"} 100000\n`),
+		regexp.MustCompile(`ceph_pool_write_bytes_per_sec{application="none",cluster="ceph",pool="cinder_sas"} 20000\n`),
+		regexp.MustCompile(`ceph_pool_write_bytes_per_sec{application="none",cluster="ceph",pool="cinder_ssd"} 100\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+
+	// The read counter of cinder_ssd went backwards.
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_read_bytes_per_sec{application="none",cluster="ceph",pool="cinder_ssd"}`), string(buf))
+	// The op rates are opt-in.
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_(read|write)_ops_per_sec`), string(buf))
+}
+
+func TestPoolUsageOpsRates(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	df := func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	}
+	conn.On("MonCommand", mock.MatchedBy(df)).Return([]byte(`
+{"pools": [
+	{"id": 32, "name": "cinder_sas", "stats": {"rd": 6195002, "wr": 34271049}},
+	{"id": 33, "name": "cinder_ssd", "stats": {"rd": 2001, "wr": 5002}}
+]}`), "", nil).Once()
+	conn.On("MonCommand", mock.MatchedBy(df)).Return([]byte(`
+{"pools": [
+	{"id": 32, "name": "cinder_sas", "stats": {"rd": 6198002, "wr": 34277049}},
+	{"id": 33, "name": "cinder_ssd", "stats": {"rd": 12, "wr": 5002}}
+]}`), "", nil).Once()
+	conn.On("MonCommand", mock.Anything).Return([]byte(`[]`), "", nil)
+	conn.On("MgrCommand", mock.Anything).Return([]byte(`{"pg_stats": []}`), "", nil)
+	conn.On("GetPoolStatsContext", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("not implemented"))
+
+	now := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", PoolOpsRates: true, Logger: logrus.New()}
+	poolUsage := NewPoolUsageCollector(e)
+	poolUsage.now = func() time.Time {
+		return now
+	}
+	e.cc = map[string]versionedCollector{
+		"poolUsage": poolUsage,
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return buf
+	}
+
+	// There's nothing to derive a rate from on the first collection.
+	buf := scrape()
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_(read|write)_ops_per_sec`), string(buf))
+
+	now = now.Add(30 * time.Second)
+	buf = scrape()
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_pool_read_ops_per_sec{application="none",cluster="ceph",pool="cinder_sas"} 100\n`),
+		regexp.MustCompile(`ceph_pool_write_ops_per_sec{application="none",cluster="ceph",pool="cinder_sas"} 200\n`),
+		regexp.MustCompile(`ceph_pool_write_ops_per_sec{application="none",cluster="ceph",pool="cinder_ssd"} 0\n`),
+		// The read counter of cinder_ssd was reset.
+		regexp.MustCompile(`ceph_pool_read_ops_per_sec{application="none",cluster="ceph",pool="cinder_ssd"} 0\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+}
+
+func TestPoolUsageApplicationsUnavailable(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	poolDetail := func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "osd pool ls",
+			"detail": "detail",
+			"format": "json",
+		})
+	}
+	conn.On("MonCommand", mock.MatchedBy(poolDetail)).Return(nil, "", errors.New("timed out")).Once()
+	conn.On("MonCommand", mock.MatchedBy(poolDetail)).Return([]byte(`
+[
+	{"pool_name": "rbd", "application_metadata": {"rbd": {}}},
+	{"pool_name": "scratch", "application_metadata": {}}
+]`), "", nil).Once()
+	conn.On("MonCommand", mock.MatchedBy(poolDetail)).Return(nil, "", errors.New("timed out")).Once()
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).Return([]byte(`
+{"pools": [
+	{"id": 1, "name": "rbd", "stats": {"stored": 20}},
+	{"id": 2, "name": "scratch", "stats": {"stored": 30}}
+]}`), "", nil)
+	conn.On("MgrCommand", mock.Anything).Return([]byte(`{"pg_stats": []}`), "", nil)
+	conn.On("GetPoolStatsContext", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("not implemented"))
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.cc = map[string]versionedCollector{
+		"poolUsage": NewPoolUsageCollector(e),
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return buf
+	}
+
+	// Without any known applications the pools are left out.
+	buf := scrape()
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_used_bytes{`), string(buf))
+
+	buf = scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="rbd",cluster="ceph",pool="rbd"} 20\n`), string(buf))
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="scratch"} 30\n`), string(buf))
+
+	// The pools keep the applications they were last seen with.
+	buf = scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="rbd",cluster="ceph",pool="rbd"} 20\n`), string(buf))
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="scratch"} 30\n`), string(buf))
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="rbd"}`), string(buf))
+}
+
+func TestPoolUsageStatsTimeout(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).Return([]byte(`
+{"pools": [
+	{"id": 11, "name": "rbd", "stats": {"stored": 20}},
+	{"id": 12, "name": "wedged", "stats": {"stored": 30}}
+]}`), "", nil)
+	conn.On("MonCommand", mock.Anything).Return([]byte(`[]`), "", nil)
+	conn.On("MgrCommand", mock.Anything).Return([]byte(`{"pg_stats": []}`), "", nil)
+	conn.On("GetPoolStatsContext", mock.Anything, "rbd").Return(&PoolStat{ObjectsUnfound: 2}, nil)
+	// The wedged pool only answers once it's given up on.
+	conn.On("GetPoolStatsContext", mock.Anything, "wedged").Return(
+		func(ctx context.Context, _ string) *PoolStat {
+			<-ctx.Done()
+			return nil
+		},
+		func(ctx context.Context, _ string) error {
+			return ctx.Err()
+		},
+	)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), ScrapeTimeout: 100 * time.Millisecond}
+	e.cc = map[string]versionedCollector{
+		"poolUsage": NewPoolUsageCollector(e),
+	}
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_unfound_objects_total{application="none",cluster="ceph",pool="rbd"} 2\n`), string(buf))
+	require.Regexp(t, regexp.MustCompile(`ceph_pool_used_bytes{application="none",cluster="ceph",pool="wedged"} 30\n`), string(buf))
+	require.NotRegexp(t, regexp.MustCompile(`ceph_pool_unfound_objects_total{application="none",cluster="ceph",pool="wedged"}`), string(buf))
+}
+
+func TestPoolInconsistentObjectsRadosCLIMissing(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		cliErr  error
+		enabled bool
+	}{
+		{
+			name:    "rados cli",
+			enabled: true,
+		},
+		{
+			name:    "no rados cli",
+			cliErr:  os.ErrNotExist,
+			enabled: false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(f func() error) { radosCLIAvailable = f }(radosCLIAvailable)
+			radosCLIAvailable = func() error { return tt.cliErr }
+
+			e := &Exporter{Cluster: "ceph", PoolInconsistentObjects: true, Logger: logrus.New()}
+			require.Equal(t, tt.enabled, NewPoolUsageCollector(e).inconsistentObjects)
+		})
+	}
+}
+
+func TestCountInconsistentObjectsDeadline(t *testing.T) {
+	e := &Exporter{Cluster: "ceph", Logger: logrus.New()}
+	poolUsage := NewPoolUsageCollector(e)
+
+	var calls int
+	poolUsage.runListInconsistentObjFn = func(ctx context.Context, config, user, keyring, pgid string) ([]byte, error) {
+		calls++
+		return []byte(`{"epoch": 1021, "inconsistents": []}`), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The PGs left once the listings ran out of time aren't listed at all.
+	_, err := poolUsage.countInconsistentObjects(ctx, []string{"12.0", "12.1"})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, calls)
+}
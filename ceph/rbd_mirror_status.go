@@ -16,7 +16,11 @@ package ceph
 
 import (
 	"encoding/json"
+	"fmt"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -46,14 +50,62 @@ type rbdMirrorPoolStatus struct {
 	} `json:"summary"`
 }
 
+type rbdMirrorPoolImages struct {
+	Images []struct {
+		Name        string `json:"name"`
+		State       string `json:"state"`
+		Description string `json:"description"`
+	} `json:"images"`
+}
+
+// rbdMirrorEntriesBehindRegex matches the replay lag in the plain text
+// descriptions journal based images reported before they moved to JSON.
+var rbdMirrorEntriesBehindRegex = regexp.MustCompile(`entries_behind_(?:primary|master)=(\d+)`)
+
+// rbdMirrorEntriesBehind returns how many journal entries a replaying image is behind
+// its primary. The replay status is embedded in the image description,
+// e.g. `replaying, {"entries_behind_primary":3,...}`. The second return
+// value is false for images that don't report it, like snapshot based ones.
+func rbdMirrorEntriesBehind(description string) (float64, bool) {
+	if i := strings.Index(description, "{"); i >= 0 {
+		var replay struct {
+			EntriesBehindPrimary *float64 `json:"entries_behind_primary"`
+			EntriesBehindMaster  *float64 `json:"entries_behind_master"`
+		}
+		if err := json.Unmarshal([]byte(description[i:]), &replay); err == nil {
+			if replay.EntriesBehindPrimary != nil {
+				return *replay.EntriesBehindPrimary, true
+			}
+			if replay.EntriesBehindMaster != nil {
+				return *replay.EntriesBehindMaster, true
+			}
+		}
+	}
+
+	if m := rbdMirrorEntriesBehindRegex.FindStringSubmatch(description); m != nil {
+		v, err := strconv.ParseFloat(m[1], 64)
+		if err == nil {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
 // RbdMirrorStatusCollector displays statistics about each pool in the Ceph cluster.
 type RbdMirrorStatusCollector struct {
+	conn    Conn
 	config  string
 	user    string
+	keyring string
 	logger  *logrus.Logger
 	version *Version
 
-	getRbdMirrorStatus func(config string, user string) ([]byte, error)
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
+	getRbdMirrorStatus            func(config, user, keyring string) ([]byte, error)
+	getRbdMirrorPoolStatusVerbose func(config, user, keyring, pool string) ([]byte, error)
 
 	// RbdMirrorStatus shows the overall health status of a rbd-mirror.
 	RbdMirrorStatus prometheus.Gauge
@@ -63,11 +115,33 @@ type RbdMirrorStatusCollector struct {
 
 	// RbdMirrorImageStatus shows the health status of rbd-mirror images.
 	RbdMirrorImageStatus prometheus.Gauge
+
+	// RbdMirrorImageState shows the mirroring state of each image, e.g.
+	// up+replaying, up+error or up+stopped.
+	RbdMirrorImageState *prometheus.Desc
+
+	// RbdMirrorImageEntriesBehind shows how many journal entries each
+	// image is behind its primary. The replay status of journal based images
+	// only has the entries behind, the journal positions it also reports
+	// don't map to a number of bytes, and snapshot based images report
+	// neither.
+	RbdMirrorImageEntriesBehind *prometheus.Desc
 }
 
 // rbdMirrorStatus get the RBD Mirror Pool Status
-var rbdMirrorStatus = func(config string, user string) ([]byte, error) {
-	out, err := exec.Command(rbdPath, "-c", config, "--user", user, "mirror", "pool", "status", "--format", "json").Output()
+var rbdMirrorStatus = func(config, user, keyring string) ([]byte, error) {
+	args := cephCLIArgs(config, user, keyring, "mirror", "pool", "status", "--format", "json")
+	out, err := exec.Command(rbdPath, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// rbdMirrorPoolStatusVerbose gets the per image RBD Mirror Pool Status of pool
+var rbdMirrorPoolStatusVerbose = func(config, user, keyring, pool string) ([]byte, error) {
+	args := cephCLIArgs(config, user, keyring, "mirror", "pool", "status", pool, "--verbose", "--format", "json")
+	out, err := exec.Command(rbdPath, args...).Output()
 	if err != nil {
 		return nil, err
 	}
@@ -76,20 +150,24 @@ var rbdMirrorStatus = func(config string, user string) ([]byte, error) {
 
 // NewRbdMirrorStatusCollector creates a new RbdMirrorStatusCollector instance
 func NewRbdMirrorStatusCollector(exporter *Exporter) *RbdMirrorStatusCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
 
 	collector := &RbdMirrorStatusCollector{
-		config:  exporter.Config,
-		user:    exporter.User,
-		logger:  exporter.Logger,
-		version: exporter.Version,
+		conn:        exporter.Conn,
+		config:      exporter.Config,
+		user:        exporter.User,
+		keyring:     exporter.Keyring,
+		logger:      exporter.Logger,
+		parseErrors: exporter.parseErrors,
+		version:     exporter.Version,
 
-		getRbdMirrorStatus: rbdMirrorStatus,
+		getRbdMirrorStatus:            rbdMirrorStatus,
+		getRbdMirrorPoolStatusVerbose: rbdMirrorPoolStatusVerbose,
 
 		RbdMirrorStatus: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rbd_mirror_pool_status",
 				Help:        "Health status of rbd-mirror, can vary only between 3 states (err:2, warn:1, ok:0)",
 				ConstLabels: labels,
@@ -98,7 +176,7 @@ func NewRbdMirrorStatusCollector(exporter *Exporter) *RbdMirrorStatusCollector {
 
 		RbdMirrorDaemonStatus: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rbd_mirror_pool_daemon_status",
 				Help:        "Health status of rbd-mirror daemons, can vary only between 3 states (err:2, warn:1, ok:0)",
 				ConstLabels: labels,
@@ -107,12 +185,26 @@ func NewRbdMirrorStatusCollector(exporter *Exporter) *RbdMirrorStatusCollector {
 
 		RbdMirrorImageStatus: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rbd_mirror_pool_image_status",
 				Help:        "Health status of rbd-mirror images, can vary only between 3 states (err:2, warn:1, ok:0)",
 				ConstLabels: labels,
 			},
 		),
+
+		RbdMirrorImageState: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rbd_mirror_image_state"),
+			"Mirroring state of an image, always 1 for the state currently reported by rbd-mirror",
+			[]string{"pool", "image", "state"},
+			labels,
+		),
+
+		RbdMirrorImageEntriesBehind: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rbd_mirror_image_entries_behind"),
+			"Number of journal entries a journal based image is behind its primary, rbd mirror pool status reports no lag in bytes",
+			[]string{"pool", "image"},
+			labels,
+		),
 	}
 
 	return collector
@@ -152,17 +244,23 @@ func (c *RbdMirrorStatusCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range c.metricsList() {
 		ch <- metric.Desc()
 	}
+	ch <- c.RbdMirrorImageState
+	ch <- c.RbdMirrorImageEntriesBehind
 }
 
 // Collect sends all the collected metrics Prometheus.
-func (c *RbdMirrorStatusCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
-	status, err := rbdMirrorStatus(c.config, c.user)
+func (c *RbdMirrorStatusCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	status, err := c.getRbdMirrorStatus(c.config, c.user, c.keyring)
 	if err != nil {
 		c.logger.WithError(err).Error("failed to run 'rbd mirror pool status'")
 	}
 	var rbdStatus rbdMirrorPoolStatus
-	if err = json.Unmarshal(status, &rbdStatus); err != nil {
-		c.logger.WithError(err).Error("failed to Unmarshal rbd mirror pool status output")
+	if uerr := json.Unmarshal(status, &rbdStatus); uerr != nil {
+		c.parseErrors.observe("rbdMirror")
+		c.logger.WithError(uerr).Error("failed to Unmarshal rbd mirror pool status output")
+		if err == nil {
+			err = uerr
+		}
 	}
 
 	c.RbdMirrorStatus.Set(c.mirrorStatusStringToInt(rbdStatus.Summary.Health))
@@ -176,4 +274,81 @@ func (c *RbdMirrorStatusCollector) Collect(ch chan<- prometheus.Metric, version
 		ch <- metric
 	}
 
+	c.collectImages(ch)
+
+	return err
+}
+
+// getRbdPools returns the names of the pools tagged with the rbd application.
+func (c *RbdMirrorStatusCollector) getRbdPools() ([]string, error) {
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix": "osd pool ls",
+		"detail": "detail",
+		"format": jsonFormat,
+	})
+	if err != nil {
+		c.logger.WithError(err).Panic("error marshalling ceph osd pool ls")
+	}
+
+	buf, _, err := c.conn.MonCommand(cmd)
+	if err != nil {
+		c.logger.WithError(err).WithField(
+			"args", string(cmd),
+		).Error("error executing mon command")
+
+		return nil, err
+	}
+
+	var pools []struct {
+		Name                string                     `json:"pool_name"`
+		ApplicationMetadata map[string]json.RawMessage `json:"application_metadata"`
+	}
+	if err := json.Unmarshal(buf, &pools); err != nil {
+		c.parseErrors.observe("rbdMirror")
+		return nil, err
+	}
+
+	var names []string
+	for _, pool := range pools {
+		if _, ok := pool.ApplicationMetadata["rbd"]; ok {
+			names = append(names, pool.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// collectImages reports the state and replay lag of every mirrored image in
+// the rbd pools.
+func (c *RbdMirrorStatusCollector) collectImages(ch chan<- prometheus.Metric) {
+	pools, err := c.getRbdPools()
+	if err != nil {
+		c.logger.WithError(err).Error("failed to list rbd pools")
+		return
+	}
+
+	for _, pool := range pools {
+		out, err := c.getRbdMirrorPoolStatusVerbose(c.config, c.user, c.keyring, pool)
+		if err != nil {
+			// rbd fails for pools that don't have mirroring enabled,
+			// which is expected for most of them.
+			c.logger.WithError(err).WithField("pool", pool).Debug("failed to run 'rbd mirror pool status --verbose'")
+			continue
+		}
+
+		var status rbdMirrorPoolImages
+		if err := json.Unmarshal(out, &status); err != nil {
+			c.parseErrors.observe("rbdMirror")
+			c.logger.WithError(err).WithField("pool", pool).Error("failed to Unmarshal rbd mirror pool status output")
+			continue
+		}
+
+		for _, image := range status.Images {
+			ch <- prometheus.MustNewConstMetric(c.RbdMirrorImageState, prometheus.GaugeValue, 1, pool, image.Name, image.State)
+
+			if behind, ok := rbdMirrorEntriesBehind(image.Description); ok {
+				ch <- prometheus.MustNewConstMetric(c.RbdMirrorImageEntriesBehind, prometheus.GaugeValue, behind, pool, image.Name)
+			}
+		}
+	}
 }
@@ -15,20 +15,34 @@
 package ceph
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 func setStatus(b []byte) {
-	rbdMirrorStatus = func(string, string) ([]byte, error) {
+	rbdMirrorStatus = func(string, string, string) ([]byte, error) {
+		return b, nil
+	}
+}
+
+func setVerboseStatus(statuses map[string][]byte) {
+	rbdMirrorPoolStatusVerbose = func(_, _, _, pool string) ([]byte, error) {
+		b, ok := statuses[pool]
+		if !ok {
+			return nil, errors.New("mirroring not enabled on the pool")
+		}
 		return b, nil
 	}
 }
@@ -36,10 +50,13 @@ func setStatus(b []byte) {
 func TestRbdMirrorStatusCollector(t *testing.T) {
 
 	for _, tt := range []struct {
-		input    []byte
-		version  string
-		versions string
-		reMatch  []*regexp.Regexp
+		input     []byte
+		version   string
+		versions  string
+		pools     string
+		verbose   map[string][]byte
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
 	}{
 		{
 			input: []byte(`
@@ -130,22 +147,102 @@ func TestRbdMirrorStatusCollector(t *testing.T) {
 				regexp.MustCompile(`ceph_rbd_mirror_pool_status{cluster="ceph"} 0`),
 			},
 		},
+		{
+			input: []byte(`
+			{
+				"summary": {
+				  "health": "WARNING",
+				  "daemon_health": "OK",
+				  "image_health": "WARNING",
+				  "states": {
+					"replaying": 2,
+					"error": 1
+				  }
+				}
+			  }`),
+			version:  `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			versions: `{"rbd-mirror":{"ceph version 16.2.11-98-g1984a8c (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)":3}}`,
+			pools: `
+[
+	{"pool_name": "volumes", "application_metadata": {"rbd": {}}},
+	{"pool_name": "images", "application_metadata": {"rbd": {}}},
+	{"pool_name": ".rgw.root", "application_metadata": {"rgw": {}}}
+]`,
+			verbose: map[string][]byte{
+				"volumes": []byte(`
+{
+	"summary": {"health": "WARNING", "daemon_health": "OK", "image_health": "WARNING", "states": {"replaying": 2, "error": 1}},
+	"daemons": [],
+	"images": [
+		{
+			"name": "vol-1",
+			"global_id": "4b4a1a0e-1b5c-4c2c-9a0e-4f0b8d4d6a8e",
+			"state": "up+replaying",
+			"description": "replaying, {\"bytes_per_second\":1024.0,\"entries_behind_primary\":17,\"entries_per_second\":2.0,\"replay_state\":\"replaying\"}",
+			"last_update": "2024-05-02 10:11:12"
+		},
+		{
+			"name": "vol-2",
+			"global_id": "8e1a6f02-5e0b-4f0a-b1f3-1d2a3b4c5d6e",
+			"state": "up+replaying",
+			"description": "replaying, {\"bytes_per_second\":0.0,\"bytes_per_snapshot\":0.0,\"replay_state\":\"idle\"}",
+			"last_update": "2024-05-02 10:11:12"
+		},
+		{
+			"name": "vol-3",
+			"global_id": "0c9d8e7f-6a5b-4c3d-2e1f-0a9b8c7d6e5f",
+			"state": "up+error",
+			"description": "split-brain",
+			"last_update": "2024-05-02 10:11:12"
+		}
+	]
+}`),
+			},
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rbd_mirror_image_state{cluster="ceph",image="vol-1",pool="volumes",state="up\+replaying"} 1`),
+				regexp.MustCompile(`ceph_rbd_mirror_image_state{cluster="ceph",image="vol-2",pool="volumes",state="up\+replaying"} 1`),
+				regexp.MustCompile(`ceph_rbd_mirror_image_state{cluster="ceph",image="vol-3",pool="volumes",state="up\+error"} 1`),
+				regexp.MustCompile(`ceph_rbd_mirror_image_entries_behind{cluster="ceph",image="vol-1",pool="volumes"} 17`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rbd_mirror_image_entries_behind{cluster="ceph",image="vol-2"`),
+				regexp.MustCompile(`ceph_rbd_mirror_image_entries_behind{cluster="ceph",image="vol-3"`),
+				regexp.MustCompile(`pool="images"`),
+			},
+		},
 	} {
 		func() {
 			conn := setupVersionMocks(tt.version, tt.versions)
 
+			pools := tt.pools
+			if pools == "" {
+				pools = "[]"
+			}
+			conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+				v := map[string]interface{}{}
+
+				_ = json.Unmarshal(in.([]byte), &v)
+
+				return cmp.Equal(v, map[string]interface{}{
+					"prefix": "osd pool ls",
+					"detail": "detail",
+					"format": "json",
+				})
+			})).Return([]byte(pools), "", nil)
+
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
 			// We do not create the rbdCollector since it will
 			// be automatically initiated from the output of `ceph versions`
 			// if the rbd-mirror key is present
 			e.cc = map[string]versionedCollector{}
 
+			setStatus(tt.input)
+			setVerboseStatus(tt.verbose)
+
 			err := prometheus.Register(e)
 			require.NoError(t, err)
 			defer prometheus.Unregister(e)
 
-			setStatus(tt.input)
-
 			server := httptest.NewServer(promhttp.Handler())
 			defer server.Close()
 
@@ -159,6 +256,10 @@ func TestRbdMirrorStatusCollector(t *testing.T) {
 			for _, re := range tt.reMatch {
 				require.True(t, re.Match(buf))
 			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
 		}()
 	}
 }
@@ -15,12 +15,22 @@
 package ceph
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
@@ -28,6 +38,40 @@ import (
 const rgwGCTimeFormat = "2006-01-02 15:04:05"
 const radosgwAdminPath = "/usr/bin/radosgw-admin"
 const backgroundCollectInterval = time.Duration(5 * time.Minute)
+const defaultRGWTimeout = 60 * time.Second
+
+const (
+	// rgwDefaultGCMaxObjs is the default number of RGW GC shards
+	// (rgw_gc_max_objs), assumed when it can't be read.
+	rgwDefaultGCMaxObjs = 32
+	// rgwGCTagSeed is the seed RGW hashes the GC tags with.
+	rgwGCTagSeed = 8675309
+
+	rgwShardsPrime0 = 7877
+	rgwShardsPrime1 = 65521
+)
+
+const (
+	// rgwAdminSocketGlob matches the admin sockets of the radosgw instances
+	// running on the host of the exporter.
+	rgwAdminSocketGlob = "/var/run/ceph/*client.rgw.*.asok"
+)
+
+var (
+	// rgwAdminSocketRegex extracts the daemon name out of an admin socket
+	// path, which cephadm suffixes with the pid and an instance id.
+	rgwAdminSocketRegex = regexp.MustCompile(`^[^-]+-(?P<name>client\.rgw\..+?)(?:\.[0-9]+\.[0-9]+)?\.asok$`)
+
+	// rgwPerfOpCounters are the perf counters of the requests of each op,
+	// by name across releases. Reef renamed the counters and added the
+	// deletes and listings.
+	rgwPerfOpCounters = map[string][]string{
+		"get":    {"get_obj_ops", "get"},
+		"put":    {"put_obj_ops", "put"},
+		"delete": {"del_obj_ops"},
+		"list":   {"list_obj_ops"},
+	}
+)
 
 const (
 	RGWModeDisabled   = 0
@@ -56,25 +100,84 @@ type rgwReshardOp struct {
 	NewNumShards  int    `json:"new_num_shards"`
 }
 
-// Expires returns the timestamp that this task will expire and become active
-func (gc rgwTaskGC) ExpiresAt() time.Time {
-	tmp := strings.SplitN(gc.Time, ".", 2)
+// rgwGCTimeRegex matches the expiration time of the GC tasks, e.g.
+// "1975-01-01 16:31:09.0.564455s": the date and time in UTC, an always zero
+// field and the microseconds suffixed with an "s".
+var rgwGCTimeRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?:\.\d+)?(?:\.(\d{1,9})s)?$`)
 
-	last, err := time.Parse(rgwGCTimeFormat, tmp[0])
+// parseRGWGCTime parses the expiration time of a GC task.
+func parseRGWGCTime(s string) (time.Time, error) {
+	m := rgwGCTimeRegex.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid gc task time %q", s)
+	}
+
+	t, err := time.Parse(rgwGCTimeFormat, m[1])
 	if err != nil {
-		return time.Now()
+		return time.Time{}, err
 	}
-	return last
+
+	if m[2] != "" {
+		// The fraction of second is right-padded to nanoseconds.
+		nsec, err := strconv.Atoi(m[2] + strings.Repeat("0", 9-len(m[2])))
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = t.Add(time.Duration(nsec))
+	}
+
+	return t, nil
+}
+
+// Shard returns the GC shard the task is queued on out of maxObjs, computed
+// the same way RGW does from its tag.
+func (gc rgwTaskGC) Shard(maxObjs int) int {
+	d := xxhash.NewWithSeed(rgwGCTagSeed)
+	_, _ = d.WriteString(gc.Tag)
+
+	// RGW truncates the hash to an unsigned int.
+	hval := uint32(d.Sum64())
+	if maxObjs <= rgwShardsPrime0 {
+		return int(hval % rgwShardsPrime0 % uint32(maxObjs))
+	}
+	return int(hval % rgwShardsPrime1 % uint32(maxObjs))
+}
+
+// radosgwAdminArgs returns the arguments running the given radosgw-admin
+// command as the given user, authenticating with the given keyring unless
+// it's empty.
+func radosgwAdminArgs(config, user, keyring string, args ...string) []string {
+	adminArgs := []string{"-c", config, "--user", user}
+	if keyring != "" {
+		adminArgs = append(adminArgs, "--keyring", keyring)
+	}
+
+	return append(adminArgs, args...)
 }
 
 // rgwGetGCTaskList get the RGW Garbage Collection task list
-func rgwGetGCTaskList(config string, user string) ([]byte, error) {
+func rgwGetGCTaskList(ctx context.Context, config, user, keyring string) ([]byte, error) {
 	var (
 		out []byte
 		err error
 	)
 
-	if out, err = exec.Command(radosgwAdminPath, "-c", config, "--user", user, "gc", "list", "--include-all").Output(); err != nil {
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "gc", "list", "--include-all")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwGetGCMaxObjs retrieves the number of GC shards, as seen by the
+// radosgw-admin listing them.
+func rgwGetGCMaxObjs(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "--show-config-value", "rgw_gc_max_objs")...).Output(); err != nil {
 		return nil, err
 	}
 
@@ -82,19 +185,252 @@ func rgwGetGCTaskList(config string, user string) ([]byte, error) {
 }
 
 // rgwGetReshardList retrieves the list of buckets that are currently being sharded.
-func rgwGetReshardList(config string, user string) ([]byte, error) {
+func rgwGetReshardList(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "reshard", "list")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwBucketStats is the subset of the per-bucket stats we care about.
+type rgwBucketStats struct {
+	Bucket            string `json:"bucket"`
+	Owner             string `json:"owner"`
+	NumShards         int    `json:"num_shards"`
+	VersioningEnabled bool   `json:"versioning_enabled"`
+	Usage             struct {
+		// Main accounts for the objects stored in the bucket.
+		Main struct {
+			Size       float64 `json:"size"`
+			NumObjects int     `json:"num_objects"`
+		} `json:"rgw.main"`
+		// MultiMeta accounts for the meta objects of the multipart uploads
+		// that were neither completed nor aborted, there is one per upload.
+		MultiMeta struct {
+			NumObjects int `json:"num_objects"`
+		} `json:"rgw.multimeta"`
+	} `json:"usage"`
+}
+
+// rgwGetBucketStats retrieves the stats of every bucket.
+func rgwGetBucketStats(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "bucket", "stats")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwBucketLimitCheck is the subset of the bucket limit check we care about,
+// the index shard fill of the buckets of each user.
+type rgwBucketLimitCheck struct {
+	UserID  string `json:"user_id"`
+	Buckets []struct {
+		Bucket          string  `json:"bucket"`
+		NumShards       int     `json:"num_shards"`
+		ObjectsPerShard float64 `json:"objects_per_shard"`
+		// FillStatus is OK below rgw_shard_warning_threshold percent of
+		// rgw_max_objs_per_shard, e.g. "WARN 92.00%" up to it and e.g.
+		// "OVER 110.00%" past it.
+		FillStatus string `json:"fill_status"`
+	} `json:"buckets"`
+}
+
+// rgwGetBucketLimitCheck retrieves the index shard fill of every bucket.
+func rgwGetBucketLimitCheck(ctx context.Context, config, user, keyring string) ([]byte, error) {
 	var (
 		out []byte
 		err error
 	)
 
-	if out, err = exec.Command(radosgwAdminPath, "-c", config, "--user", user, "reshard", "list").Output(); err != nil {
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "bucket", "limit", "check")...).Output(); err != nil {
 		return nil, err
 	}
 
 	return out, nil
 }
 
+// rgwZone is the subset of the local zone configuration we care about.
+type rgwZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// rgwGetZone retrieves the configuration of the local zone.
+func rgwGetZone(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "zone", "get")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwPeriod is the subset of the current period of the realm we care about.
+type rgwPeriod struct {
+	MasterZone string `json:"master_zone"`
+	RealmName  string `json:"realm_name"`
+	PeriodMap  struct {
+		Zonegroups []struct {
+			Name  string    `json:"name"`
+			Zones []rgwZone `json:"zones"`
+		} `json:"zonegroups"`
+	} `json:"period_map"`
+}
+
+// rgwGetPeriod retrieves the current period of the realm of the local zone.
+// It fails when the zone isn't part of a realm.
+func rgwGetPeriod(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "period", "get")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwUserInfo is the subset of the user info we care about.
+type rgwUserInfo struct {
+	UserID    string `json:"user_id"`
+	UserQuota struct {
+		Enabled    bool    `json:"enabled"`
+		MaxSize    float64 `json:"max_size"`
+		MaxObjects float64 `json:"max_objects"`
+	} `json:"user_quota"`
+}
+
+// rgwGetUserList retrieves the IDs of all the users.
+func rgwGetUserList(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "user", "list")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwGetUserInfo retrieves the info of the given user, including its quota.
+func rgwGetUserInfo(ctx context.Context, config, user, keyring, uid string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "user", "info", "--uid", uid)...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// rgwGetPerfDump retrieves the rgw perf counters of the radosgw instance
+// listening on the given admin socket.
+func rgwGetPerfDump(ctx context.Context, asok string) ([]byte, error) {
+	return exec.CommandContext(ctx, cephCmd, "--admin-daemon", asok, "perf", "dump", "rgw").Output()
+}
+
+// rgwDaemonName returns the name of the radosgw instance listening on the
+// given admin socket, or the socket file name if it doesn't look like one
+// of ours.
+func rgwDaemonName(asok string) string {
+	base := filepath.Base(asok)
+	if groups := getGroups(*rgwAdminSocketRegex, base); groups["name"] != "" {
+		return groups["name"]
+	}
+
+	return base
+}
+
+// rgwGetSyncStatus retrieves the multisite sync status of the local zone.
+func rgwGetSyncStatus(ctx context.Context, config, user, keyring string) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if out, err = exec.CommandContext(ctx, radosgwAdminPath, radosgwAdminArgs(config, user, keyring, "sync", "status")...).Output(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+var (
+	rgwSyncSourceRegex     = regexp.MustCompile(`^(?:data sync )?source: \S+ \((?P<zone>[^)]*)\)`)
+	rgwSyncBehindRegex     = regexp.MustCompile(`data is behind on (?P<shards>[0-9]+) shards`)
+	rgwSyncRecoveringRegex = regexp.MustCompile(`(?P<shards>[0-9]+) shards are recovering`)
+)
+
+// rgwSyncSource is the data sync state of the local zone against one of
+// its source zones.
+type rgwSyncSource struct {
+	Zone             string
+	BehindShards     int
+	RecoveringShards int
+}
+
+// parseRGWSyncStatus extracts the data sync state of every source zone out
+// of the output of `radosgw-admin sync status`, which is only available as
+// text. Zones that are not part of a multisite setup have no source zone,
+// and so do sources whose sync info couldn't be retrieved.
+func parseRGWSyncStatus(data []byte) []rgwSyncSource {
+	var (
+		sources []rgwSyncSource
+		current *rgwSyncSource
+	)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := rgwSyncSourceRegex.FindStringSubmatch(line); m != nil {
+			sources = append(sources, rgwSyncSource{Zone: m[1]})
+			current = &sources[len(sources)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if strings.HasPrefix(line, "failed to retrieve sync info") {
+			sources = sources[:len(sources)-1]
+			current = nil
+			continue
+		}
+
+		if m := rgwSyncBehindRegex.FindStringSubmatch(line); m != nil {
+			current.BehindShards, _ = strconv.Atoi(m[1])
+		} else if m := rgwSyncRecoveringRegex.FindStringSubmatch(line); m != nil {
+			current.RecoveringShards, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	return sources
+}
+
 // RGWCollector collects metrics from the RGW service
 type RGWCollector struct {
 	config     string
@@ -102,6 +438,50 @@ type RGWCollector struct {
 	background bool
 	logger     *logrus.Logger
 
+	// keyring is the keyring radosgw-admin authenticates with, empty means
+	// the one found through the config.
+	keyring string
+
+	// bucketStats enables the collection of per-bucket metrics, which
+	// can be expensive on clusters with many buckets.
+	bucketStats bool
+
+	// timeout bounds the duration of every radosgw-admin command.
+	timeout time.Duration
+
+	// now returns the current time, reshard ages are computed relative to it.
+	now func() time.Time
+
+	// metricsMu protects metrics.
+	metricsMu sync.Mutex
+	// metrics holds the metrics of the last complete collection.
+	metrics []prometheus.Metric
+
+	// backgroundOnce starts the background collection on the first scrape.
+	backgroundOnce sync.Once
+
+	// reshardsMu protects reshards.
+	reshardsMu sync.Mutex
+	// reshards holds the last observed reshard of each bucket.
+	reshards map[string]*rgwBucketReshard
+
+	// gcShards is the number of GC shards reported by the last collection.
+	gcShards int
+
+	// userStats enables the collection of per-user metrics, which requires
+	// one radosgw-admin call per user.
+	userStats bool
+
+	// orphansFile is the output of the last rgw-orphan-list run, empty
+	// means the orphans aren't reported.
+	orphansFile string
+
+	// scrapeTime accounts for the time spent running radosgw-admin.
+	scrapeTime *ScrapeTimeCollector
+
+	// parseErrors counts the replies this collector failed to parse.
+	parseErrors *ParseErrorsCollector
+
 	// GCActiveTasks reports the number of (expired) RGW GC tasks.
 	GCActiveTasks *prometheus.GaugeVec
 	// GCActiveObjects reports the total number of RGW GC objects contained in active tasks.
@@ -112,32 +492,132 @@ type RGWCollector struct {
 	// GCPendingObjects reports the total number of RGW GC objects contained in pending tasks.
 	GCPendingObjects *prometheus.GaugeVec
 
+	// GCQueueLength reports the number of RGW GC tasks queued on a particular shard.
+	GCQueueLength *prometheus.GaugeVec
+
+	// GCOldestActiveTaskAge reports how long ago the oldest active RGW GC task expired.
+	GCOldestActiveTaskAge *prometheus.GaugeVec
+
 	// ActiveReshards reports the number of active RGW bucket reshard operations.
 	ActiveReshards *prometheus.GaugeVec
 	// ActiveBucketReshard reports the state of reshard operation for a particular bucket.
 	ActiveBucketReshard *prometheus.Desc
 
-	getRGWGCTaskList  func(string, string) ([]byte, error)
-	getRGWReshardList func(string, string) ([]byte, error)
+	// BucketSecondsSinceReshard reports how long ago a particular bucket was last resharded.
+	BucketSecondsSinceReshard *prometheus.Desc
+
+	// BucketVersioningEnabled reports whether versioning is enabled on a particular bucket.
+	BucketVersioningEnabled *prometheus.Desc
+	// BucketIncompleteMultipartUploads reports the number of incomplete multipart uploads in a particular bucket.
+	BucketIncompleteMultipartUploads *prometheus.Desc
+	// BucketUsedBytes reports the size of the objects stored in a particular bucket.
+	BucketUsedBytes *prometheus.Desc
+	// BucketObjects reports the number of objects stored in a particular bucket.
+	BucketObjects *prometheus.Desc
+	// BucketNumShards reports the number of index shards of a particular bucket.
+	BucketNumShards *prometheus.Desc
+	// BucketObjectsPerShard reports the average number of objects per index shard of a particular bucket.
+	BucketObjectsPerShard *prometheus.Desc
+	// BucketShardFillStatus reports how full the index shards of a particular bucket are.
+	BucketShardFillStatus *prometheus.Desc
+	// ZoneBuckets reports the number of buckets in the local zone.
+	ZoneBuckets *prometheus.Desc
+	// ZoneObjects reports the number of objects stored in the buckets of the local zone.
+	ZoneObjects *prometheus.Desc
+	// ZoneInfo reports the zone, zonegroup and realm of the local zone and whether it is the master zone.
+	ZoneInfo *prometheus.Desc
+
+	// UserQuotaMaxBytes reports the maximum number of bytes a particular user may store.
+	UserQuotaMaxBytes *prometheus.Desc
+	// UserQuotaMaxObjects reports the maximum number of objects a particular user may store.
+	UserQuotaMaxObjects *prometheus.Desc
+	// UserUsedBytes reports the size of the objects stored in the buckets of a particular user.
+	UserUsedBytes *prometheus.Desc
+
+	// SyncBehindShards reports the number of data log shards the local zone is behind on, per source zone.
+	SyncBehindShards *prometheus.Desc
+	// SyncRecoveringShards reports the number of data log shards being recovered, per source zone.
+	SyncRecoveringShards *prometheus.Desc
+
+	// OrphanObjects reports the number of RADOS objects rgw-orphan-list found orphaned.
+	OrphanObjects *prometheus.Desc
+	// OrphanListTimestamp reports when the rgw-orphan-list output was last written.
+	OrphanListTimestamp *prometheus.Desc
+
+	// OpTotal reports the requests served by a radosgw instance, per op.
+	OpTotal *prometheus.Desc
+	// FailedOpTotal reports the requests a radosgw instance failed to serve.
+	FailedOpTotal *prometheus.Desc
+
+	getRGWGCTaskList  func(context.Context, string, string, string) ([]byte, error)
+	getRGWGCMaxObjs   func(context.Context, string, string, string) ([]byte, error)
+	getRGWReshardList func(context.Context, string, string, string) ([]byte, error)
+	getRGWBucketStats func(context.Context, string, string, string) ([]byte, error)
+	getRGWLimitCheck  func(context.Context, string, string, string) ([]byte, error)
+	getRGWZone        func(context.Context, string, string, string) ([]byte, error)
+	getRGWPeriod      func(context.Context, string, string, string) ([]byte, error)
+	getRGWSyncStatus  func(context.Context, string, string, string) ([]byte, error)
+	getRGWUserList    func(context.Context, string, string, string) ([]byte, error)
+	getRGWUserInfo    func(context.Context, string, string, string, string) ([]byte, error)
+
+	// perfCounters enables the request counters read from the admin
+	// sockets of the radosgw instances on the local host.
+	perfCounters        bool
+	listRGWAdminSockets func() ([]string, error)
+	getRGWPerfDump      func(context.Context, string) ([]byte, error)
 }
 
 // NewRGWCollector creates an instance of the RGWCollector and instantiates
-// the individual metrics that we can collect from the RGW service
-func NewRGWCollector(exporter *Exporter, background bool) *RGWCollector {
-	labels := make(prometheus.Labels)
-	labels["cluster"] = exporter.Cluster
+// the individual metrics that we can collect from the RGW service. The
+// per-bucket metrics are only collected if bucketStats is set, and the
+// per-user ones if userStats is set.
+func NewRGWCollector(exporter *Exporter, background, bucketStats, userStats bool) *RGWCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	// The offline exporters don't run the collectors, only describe them.
+	perfCounters := exporter.RgwPerfCounters
+	if perfCounters && !exporter.offline {
+		if err := cephCLIAvailable(); err != nil {
+			exporter.Logger.WithError(err).Warn("RGW perf counters disabled, the ceph CLI reading the admin sockets is missing")
+			perfCounters = false
+		}
+	}
 
 	rgw := &RGWCollector{
 		config:            exporter.Config,
 		user:              exporter.User,
+		keyring:           exporter.Keyring,
 		background:        background,
 		logger:            exporter.Logger,
+		bucketStats:       bucketStats,
+		userStats:         userStats,
+		timeout:           exporter.RgwTimeout,
+		orphansFile:       exporter.RgwOrphansFile,
+		now:               time.Now,
+		reshards:          make(map[string]*rgwBucketReshard),
+		scrapeTime:        exporter.scrapeTime,
+		parseErrors:       exporter.parseErrors,
 		getRGWGCTaskList:  rgwGetGCTaskList,
+		getRGWGCMaxObjs:   rgwGetGCMaxObjs,
 		getRGWReshardList: rgwGetReshardList,
+		getRGWBucketStats: rgwGetBucketStats,
+		getRGWLimitCheck:  rgwGetBucketLimitCheck,
+		getRGWZone:        rgwGetZone,
+		getRGWPeriod:      rgwGetPeriod,
+		getRGWSyncStatus:  rgwGetSyncStatus,
+		getRGWUserList:    rgwGetUserList,
+		getRGWUserInfo:    rgwGetUserInfo,
+
+		perfCounters: perfCounters,
+		listRGWAdminSockets: func() ([]string, error) {
+			return filepath.Glob(rgwAdminSocketGlob)
+		},
+		getRGWPerfDump: rgwGetPerfDump,
 
 		GCActiveTasks: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rgw_gc_active_tasks",
 				Help:        "RGW GC active task count",
 				ConstLabels: labels,
@@ -146,7 +626,7 @@ func NewRGWCollector(exporter *Exporter, background bool) *RGWCollector {
 		),
 		GCActiveObjects: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rgw_gc_active_objects",
 				Help:        "RGW GC active object count",
 				ConstLabels: labels,
@@ -155,7 +635,7 @@ func NewRGWCollector(exporter *Exporter, background bool) *RGWCollector {
 		),
 		GCPendingTasks: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rgw_gc_pending_tasks",
 				Help:        "RGW GC pending task count",
 				ConstLabels: labels,
@@ -164,17 +644,35 @@ func NewRGWCollector(exporter *Exporter, background bool) *RGWCollector {
 		),
 		GCPendingObjects: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rgw_gc_pending_objects",
 				Help:        "RGW GC pending object count",
 				ConstLabels: labels,
 			},
 			[]string{},
 		),
+		GCQueueLength: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "rgw_gc_queue_length",
+				Help:        "RGW GC task count per shard",
+				ConstLabels: labels,
+			},
+			[]string{"shard"},
+		),
+		GCOldestActiveTaskAge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "rgw_gc_oldest_active_task_age_seconds",
+				Help:        "Seconds since the oldest active RGW GC task expired, 0 without active tasks",
+				ConstLabels: labels,
+			},
+			[]string{},
+		),
 
 		ActiveReshards: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace:   cephNamespace,
+				Namespace:   namespace,
 				Name:        "rgw_active_reshards",
 				Help:        "RGW active bucket reshard operations",
 				ConstLabels: labels,
@@ -182,22 +680,185 @@ func NewRGWCollector(exporter *Exporter, background bool) *RGWCollector {
 			[]string{},
 		),
 		ActiveBucketReshard: prometheus.NewDesc(
-			fmt.Sprintf("%s_%s", cephNamespace, "rgw_bucket_reshard"),
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_reshard"),
 			"RGW bucket reshard operation",
 			[]string{"bucket"},
 			labels,
 		),
+		BucketSecondsSinceReshard: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_seconds_since_reshard"),
+			"Seconds since the RGW bucket was last resharded, -1 if it wasn't while the exporter ran",
+			[]string{"bucket"},
+			labels,
+		),
+		BucketVersioningEnabled: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_versioning_enabled"),
+			"RGW bucket versioning enabled",
+			[]string{"bucket"},
+			labels,
+		),
+		BucketIncompleteMultipartUploads: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_incomplete_multipart_uploads"),
+			"RGW bucket incomplete multipart upload count",
+			[]string{"bucket"},
+			labels,
+		),
+		BucketUsedBytes: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_used_bytes"),
+			"RGW bucket used bytes",
+			[]string{"bucket", "owner"},
+			labels,
+		),
+		BucketObjects: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_objects"),
+			"RGW bucket object count",
+			[]string{"bucket", "owner"},
+			labels,
+		),
+		BucketNumShards: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_num_shards"),
+			"RGW bucket index shard count",
+			[]string{"bucket", "owner"},
+			labels,
+		),
+		BucketObjectsPerShard: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_objects_per_shard"),
+			"RGW bucket average object count per index shard",
+			[]string{"bucket", "owner"},
+			labels,
+		),
+		BucketShardFillStatus: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_bucket_shard_fill_status"),
+			"RGW bucket index shard fill status against rgw_max_objs_per_shard",
+			[]string{"bucket", "owner", "status"},
+			labels,
+		),
+		ZoneBuckets: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_zone_buckets_total"),
+			"RGW bucket count of the local zone",
+			[]string{"zone"},
+			labels,
+		),
+		ZoneObjects: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_zone_objects_total"),
+			"RGW object count of the local zone",
+			[]string{"zone"},
+			labels,
+		),
+		UserQuotaMaxBytes: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_user_quota_max_bytes"),
+			"RGW user quota max bytes, -1 if unlimited",
+			[]string{"user"},
+			labels,
+		),
+		UserQuotaMaxObjects: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_user_quota_max_objects"),
+			"RGW user quota max objects, -1 if unlimited",
+			[]string{"user"},
+			labels,
+		),
+		UserUsedBytes: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_user_used_bytes"),
+			"RGW user used bytes across all of its buckets",
+			[]string{"user"},
+			labels,
+		),
+		SyncBehindShards: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_sync_behind_shards"),
+			"RGW multisite data sync shards behind the source zone",
+			[]string{"source_zone"},
+			labels,
+		),
+		SyncRecoveringShards: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_sync_recovering_shards"),
+			"RGW multisite data sync shards recovering from errors",
+			[]string{"source_zone"},
+			labels,
+		),
+		ZoneInfo: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_zone_info"),
+			"Zone, zonegroup and realm of the local RGW zone, and whether it is the master zone of the realm",
+			[]string{"zone", "zonegroup", "realm", "is_master"},
+			labels,
+		),
+		OrphanObjects: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_orphan_objects"),
+			"RADOS objects of the RGW data pools no bucket index refers to, as of the last rgw-orphan-list run",
+			nil,
+			labels,
+		),
+		OrphanListTimestamp: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_orphan_list_timestamp_seconds"),
+			"Unix time the output of the last rgw-orphan-list run was written",
+			nil,
+			labels,
+		),
+		OpTotal: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_op_total"),
+			"RGW requests served by the radosgw instance, per op",
+			[]string{"rgw", "op"},
+			labels,
+		),
+		FailedOpTotal: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s", namespace, "rgw_failed_op_total"),
+			"RGW requests the radosgw instance failed to serve",
+			[]string{"rgw"},
+			labels,
+		),
+	}
+
+	if rgw.timeout <= 0 {
+		rgw.timeout = defaultRGWTimeout
 	}
 
 	return rgw
 }
 
+// runCommand runs one of the radosgw-admin commands, giving up on it once
+// the timeout is exceeded.
+func (r *RGWCollector) runCommand(fn func(context.Context, string, string, string) ([]byte, error)) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	out, err := fn(ctx, r.config, r.user, r.keyring)
+	r.scrapeTime.observeCLI(start)
+	if err != nil && ctx.Err() != nil {
+		// The process got killed, report why.
+		return nil, fmt.Errorf("radosgw-admin timed out after %s: %w", r.timeout, ctx.Err())
+	}
+
+	return out, err
+}
+
+// gcMaxObjs returns the number of GC shards the tasks are spread over,
+// rgw_gc_max_objs as radosgw-admin sees it since the gc list it runs covers
+// that many shards, falling back to its default.
+func (r *RGWCollector) gcMaxObjs() int {
+	data, err := r.runCommand(r.getRGWGCMaxObjs)
+	if err != nil {
+		r.logger.WithError(err).Warn("failed getting rgw_gc_max_objs, assuming the default")
+		return rgwDefaultGCMaxObjs
+	}
+
+	maxObjs, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || maxObjs <= 0 {
+		r.parseErrors.observe("rgw")
+		r.logger.WithField("value", string(data)).Warn("failed parsing rgw_gc_max_objs, assuming the default")
+		return rgwDefaultGCMaxObjs
+	}
+
+	return maxObjs
+}
+
 func (r *RGWCollector) collectorList() []prometheus.Collector {
 	return []prometheus.Collector{
 		r.GCActiveTasks,
 		r.GCActiveObjects,
 		r.GCPendingTasks,
 		r.GCPendingObjects,
+		r.GCQueueLength,
+		r.GCOldestActiveTaskAge,
 		r.ActiveReshards,
 	}
 }
@@ -205,13 +866,33 @@ func (r *RGWCollector) collectorList() []prometheus.Collector {
 func (r *RGWCollector) descriptorList() []*prometheus.Desc {
 	return []*prometheus.Desc{
 		r.ActiveBucketReshard,
+		r.BucketSecondsSinceReshard,
+		r.BucketVersioningEnabled,
+		r.BucketIncompleteMultipartUploads,
+		r.BucketUsedBytes,
+		r.BucketObjects,
+		r.BucketNumShards,
+		r.BucketObjectsPerShard,
+		r.BucketShardFillStatus,
+		r.ZoneBuckets,
+		r.ZoneObjects,
+		r.ZoneInfo,
+		r.UserQuotaMaxBytes,
+		r.UserQuotaMaxObjects,
+		r.UserUsedBytes,
+		r.SyncBehindShards,
+		r.SyncRecoveringShards,
+		r.OrphanObjects,
+		r.OrphanListTimestamp,
+		r.OpTotal,
+		r.FailedOpTotal,
 	}
 }
 
-func (r *RGWCollector) backgroundCollect(ch chan<- prometheus.Metric) error {
+func (r *RGWCollector) backgroundCollect() {
 	for {
 		r.logger.WithField("background", r.background).Debug("collecting RGW stats")
-		err := r.collect(ch)
+		err := r.collect()
 		if err != nil {
 			r.logger.WithField("background", r.background).WithError(err).Error("error collecting RGW stats")
 		}
@@ -219,8 +900,40 @@ func (r *RGWCollector) backgroundCollect(ch chan<- prometheus.Metric) error {
 	}
 }
 
-func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
-	data, err := r.getRGWGCTaskList(r.config, r.user)
+// collect gathers the RGW metrics of one cycle, they are the ones reported
+// from then on. The metrics of the previous cycle are kept if one of the
+// commands timed out, as the cycle is then incomplete.
+func (r *RGWCollector) collect() error {
+	var (
+		metrics []prometheus.Metric
+		buf     = make(chan prometheus.Metric)
+		done    = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		for metric := range buf {
+			metrics = append(metrics, metric)
+		}
+	}()
+
+	err := r.collectMetrics(buf)
+	close(buf)
+	<-done
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	r.metricsMu.Lock()
+	r.metrics = metrics
+	r.metricsMu.Unlock()
+
+	return err
+}
+
+func (r *RGWCollector) collectMetrics(ch chan<- prometheus.Metric) error {
+	data, err := r.runCommand(r.getRGWGCTaskList)
 	if err != nil {
 		return fmt.Errorf("failed getting gc task list: %w", err)
 	}
@@ -228,6 +941,7 @@ func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
 	tasks := make([]rgwTaskGC, 0)
 	err = json.Unmarshal(data, &tasks)
 	if err != nil {
+		r.parseErrors.observe("rgw")
 		return fmt.Errorf("failed unmarshalling gc task data: %w", err)
 	}
 
@@ -236,14 +950,29 @@ func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
 		gcActiveObjectCount  = int(0)
 		gcPendingTaskCount   = int(0)
 		gcPendingObjectCount = int(0)
+		gcShardTaskCount     = make([]int, r.gcMaxObjs())
+		gcOldestActiveAge    time.Duration
 	)
 
-	now := time.Now()
+	now := r.now()
 	for _, task := range tasks {
-		if now.Sub(task.ExpiresAt()) > 0 {
+		gcShardTaskCount[task.Shard(len(gcShardTaskCount))]++
+
+		expiresAt, err := parseRGWGCTime(task.Time)
+		if err != nil {
+			// Count it as pending, it can't be told how stale it is.
+			r.parseErrors.observe("rgw")
+			r.logger.WithError(err).WithField("tag", task.Tag).Debug("failed parsing gc task time")
+			expiresAt = now
+		}
+
+		if age := now.Sub(expiresAt); age > 0 {
 			// timer expired these are active
 			gcActiveTaskCount += 1
 			gcActiveObjectCount += len(task.Objects)
+			if age > gcOldestActiveAge {
+				gcOldestActiveAge = age
+			}
 		} else {
 			gcPendingTaskCount += 1
 			gcPendingObjectCount += len(task.Objects)
@@ -256,11 +985,22 @@ func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
 	r.GCActiveObjects.WithLabelValues().Set(float64(gcActiveObjectCount))
 	r.GCPendingObjects.WithLabelValues().Set(float64(gcPendingObjectCount))
 
+	r.GCOldestActiveTaskAge.WithLabelValues().Set(gcOldestActiveAge.Seconds())
+
+	for shard, count := range gcShardTaskCount {
+		r.GCQueueLength.WithLabelValues(strconv.Itoa(shard)).Set(float64(count))
+	}
+	// The shards past a lowered rgw_gc_max_objs are gone.
+	for shard := len(gcShardTaskCount); shard < r.gcShards; shard++ {
+		r.GCQueueLength.DeleteLabelValues(strconv.Itoa(shard))
+	}
+	r.gcShards = len(gcShardTaskCount)
+
 	var (
 		activeReshardOps int
 	)
 
-	data, err = r.getRGWReshardList(r.config, r.user)
+	data, err = r.runCommand(r.getRGWReshardList)
 	if err != nil {
 		return fmt.Errorf("failed getting bucket reshard list: %w", err)
 	}
@@ -268,6 +1008,7 @@ func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
 	ops := make([]rgwReshardOp, 0)
 	err = json.Unmarshal(data, &ops)
 	if err != nil {
+		r.parseErrors.observe("rgw")
 		return fmt.Errorf("failed unmarshalling bucket reshard list: %w", err)
 	}
 
@@ -282,6 +1023,482 @@ func (r *RGWCollector) collect(ch chan<- prometheus.Metric) error {
 
 	activeReshardOps = len(ops)
 	r.ActiveReshards.WithLabelValues().Set(float64(activeReshardOps))
+
+	var buckets []rgwBucketStats
+	if r.bucketStats || r.userStats {
+		buckets, err = r.getBucketStats()
+		if err != nil {
+			return err
+		}
+
+		if r.bucketStats {
+			r.collectBucketStats(ch, buckets)
+
+			r.collectBucketShardFill(ch)
+
+			if err := r.collectZoneStats(ch, buckets); err != nil {
+				return err
+			}
+		}
+
+		if r.userStats {
+			if err := r.collectUserStats(ch, buckets); err != nil {
+				return err
+			}
+		}
+	}
+
+	r.collectReshardAges(ch, ops, buckets)
+
+	if r.perfCounters {
+		r.collectPerfCounters(ch)
+	}
+
+	if r.orphansFile != "" {
+		r.collectOrphans(ch)
+	}
+
+	r.collectZoneInfo(ch)
+
+	return r.collectSyncStatus(ch)
+}
+
+// collectOrphans reports the orphans found by the last rgw-orphan-list run,
+// which lists the name of every orphaned RADOS object on its own line. The
+// scan reads every object of the data pools, so it is left to the operators
+// to run it periodically rather than done on every collection. A failure
+// is logged but doesn't fail the collection.
+func (r *RGWCollector) collectOrphans(ch chan<- prometheus.Metric) {
+	f, err := os.Open(r.orphansFile)
+	if err != nil {
+		r.logger.WithError(err).Error("failed opening rgw-orphan-list output")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		r.logger.WithError(err).Error("failed getting rgw-orphan-list output info")
+		return
+	}
+
+	var orphans int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			orphans++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		r.logger.WithError(err).Error("failed reading rgw-orphan-list output")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		r.OrphanObjects,
+		prometheus.GaugeValue,
+		float64(orphans),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		r.OrphanListTimestamp,
+		prometheus.GaugeValue,
+		float64(info.ModTime().Unix()),
+	)
+}
+
+// collectZoneInfo reports which zone the exporter is watching. A zone
+// outside of any realm has no period, it is then reported without a
+// zonegroup nor a realm and as the master, being the only zone. A failure
+// is logged but doesn't fail the collection.
+func (r *RGWCollector) collectZoneInfo(ch chan<- prometheus.Metric) {
+	data, err := r.runCommand(r.getRGWZone)
+	if err != nil {
+		r.logger.WithError(err).Error("failed getting zone")
+		return
+	}
+
+	var zone rgwZone
+	if err := json.Unmarshal(data, &zone); err != nil {
+		r.parseErrors.observe("rgw")
+		r.logger.WithError(err).Error("failed unmarshalling zone")
+		return
+	}
+
+	var (
+		zonegroup, realm string
+		isMaster         = true
+	)
+
+	data, err = r.runCommand(r.getRGWPeriod)
+	if err != nil {
+		r.logger.WithError(err).Debug("failed getting period, assuming the zone isn't part of a realm")
+	} else {
+		var period rgwPeriod
+		if err := json.Unmarshal(data, &period); err != nil {
+			r.parseErrors.observe("rgw")
+			r.logger.WithError(err).Error("failed unmarshalling period")
+			return
+		}
+
+		realm = period.RealmName
+		isMaster = period.MasterZone == zone.ID
+		for _, zg := range period.PeriodMap.Zonegroups {
+			for _, z := range zg.Zones {
+				if z.ID == zone.ID {
+					zonegroup = zg.Name
+				}
+			}
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		r.ZoneInfo,
+		prometheus.GaugeValue,
+		1,
+		zone.Name,
+		zonegroup,
+		realm,
+		strconv.FormatBool(isMaster),
+	)
+}
+
+// collectPerfCounters reports the request counters of every radosgw
+// instance whose admin socket is reachable from the exporter, i.e. the
+// instances running on the same host, with their /var/run/ceph shared with
+// a containerized exporter. An instance failing to answer is skipped, the
+// others are still reported.
+func (r *RGWCollector) collectPerfCounters(ch chan<- prometheus.Metric) {
+	sockets, err := r.listRGWAdminSockets()
+	if err != nil {
+		r.logger.WithError(err).Error("failed listing rgw admin sockets")
+		return
+	}
+
+	for _, asok := range sockets {
+		name := rgwDaemonName(asok)
+
+		data, err := r.runCommand(func(ctx context.Context, _, _, _ string) ([]byte, error) {
+			return r.getRGWPerfDump(ctx, asok)
+		})
+		if err != nil {
+			r.logger.WithField("rgw", name).WithError(err).Error("failed getting rgw perf dump")
+			continue
+		}
+
+		perfDump := struct {
+			RGW map[string]json.RawMessage `json:"rgw"`
+		}{}
+		if err := json.Unmarshal(data, &perfDump); err != nil {
+			r.parseErrors.observe("rgw")
+			r.logger.WithField("rgw", name).WithError(err).Error("failed unmarshalling rgw perf dump")
+			continue
+		}
+
+		counter := func(key string) (float64, bool) {
+			var v float64
+			raw, ok := perfDump.RGW[key]
+			if !ok || json.Unmarshal(raw, &v) != nil {
+				return 0, false
+			}
+			return v, true
+		}
+
+		for op, keys := range rgwPerfOpCounters {
+			for _, key := range keys {
+				if v, ok := counter(key); ok {
+					ch <- prometheus.MustNewConstMetric(r.OpTotal, prometheus.CounterValue, v, name, op)
+					break
+				}
+			}
+		}
+
+		if v, ok := counter("failed_req"); ok {
+			ch <- prometheus.MustNewConstMetric(r.FailedOpTotal, prometheus.CounterValue, v, name)
+		}
+	}
+}
+
+// rgwBucketReshard is the last reshard of a bucket observed by the exporter.
+type rgwBucketReshard struct {
+	numShards int
+	// at is when the reshard was observed, zero if it never was.
+	at time.Time
+}
+
+// collectReshardAges reports how long ago each bucket was last resharded.
+// A reshard is observed when a bucket shows up in the reshard list with a
+// new shard count, or when its shard count changes between two bucket
+// stats. buckets is nil if the bucket stats weren't collected, the buckets
+// that never were in the reshard list are then unknown.
+func (r *RGWCollector) collectReshardAges(ch chan<- prometheus.Metric, ops []rgwReshardOp, buckets []rgwBucketStats) {
+	r.reshardsMu.Lock()
+	defer r.reshardsMu.Unlock()
+
+	now := r.now()
+
+	resharding := make(map[string]struct{}, len(ops))
+	for _, op := range ops {
+		resharding[op.BucketName] = struct{}{}
+		if reshard, ok := r.reshards[op.BucketName]; !ok || reshard.numShards != op.NewNumShards {
+			r.reshards[op.BucketName] = &rgwBucketReshard{numShards: op.NewNumShards, at: now}
+		}
+	}
+
+	if buckets != nil {
+		seen := make(map[string]struct{}, len(buckets))
+		for _, bucket := range buckets {
+			seen[bucket.Bucket] = struct{}{}
+
+			// The stats still hold the old shard count until the reshard
+			// completes.
+			if _, ok := resharding[bucket.Bucket]; ok {
+				continue
+			}
+
+			reshard, ok := r.reshards[bucket.Bucket]
+			switch {
+			case !ok:
+				r.reshards[bucket.Bucket] = &rgwBucketReshard{numShards: bucket.NumShards}
+			case reshard.numShards != bucket.NumShards:
+				r.reshards[bucket.Bucket] = &rgwBucketReshard{numShards: bucket.NumShards, at: now}
+			}
+		}
+
+		// Forget about the deleted buckets.
+		for bucket := range r.reshards {
+			if _, ok := seen[bucket]; !ok {
+				delete(r.reshards, bucket)
+			}
+		}
+	}
+
+	for bucket, reshard := range r.reshards {
+		age := float64(-1)
+		if !reshard.at.IsZero() {
+			age = now.Sub(reshard.at).Seconds()
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketSecondsSinceReshard,
+			prometheus.GaugeValue,
+			age,
+			bucket,
+		)
+	}
+}
+
+func (r *RGWCollector) collectSyncStatus(ch chan<- prometheus.Metric) error {
+	data, err := r.runCommand(r.getRGWSyncStatus)
+	if err != nil {
+		return fmt.Errorf("failed getting sync status: %w", err)
+	}
+
+	for _, source := range parseRGWSyncStatus(data) {
+		ch <- prometheus.MustNewConstMetric(
+			r.SyncBehindShards,
+			prometheus.GaugeValue,
+			float64(source.BehindShards),
+			source.Zone,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.SyncRecoveringShards,
+			prometheus.GaugeValue,
+			float64(source.RecoveringShards),
+			source.Zone,
+		)
+	}
+
+	return nil
+}
+
+func (r *RGWCollector) getBucketStats() ([]rgwBucketStats, error) {
+	data, err := r.runCommand(r.getRGWBucketStats)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting bucket stats: %w", err)
+	}
+
+	buckets := make([]rgwBucketStats, 0)
+	err = json.Unmarshal(data, &buckets)
+	if err != nil {
+		r.parseErrors.observe("rgw")
+		return nil, fmt.Errorf("failed unmarshalling bucket stats: %w", err)
+	}
+
+	return buckets, nil
+}
+
+func (r *RGWCollector) collectBucketStats(ch chan<- prometheus.Metric, buckets []rgwBucketStats) {
+	for _, bucket := range buckets {
+		versioningEnabled := 0
+		if bucket.VersioningEnabled {
+			versioningEnabled = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketVersioningEnabled,
+			prometheus.GaugeValue,
+			float64(versioningEnabled),
+			bucket.Bucket,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketIncompleteMultipartUploads,
+			prometheus.GaugeValue,
+			float64(bucket.Usage.MultiMeta.NumObjects),
+			bucket.Bucket,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketUsedBytes,
+			prometheus.GaugeValue,
+			bucket.Usage.Main.Size,
+			bucket.Bucket,
+			bucket.Owner,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketObjects,
+			prometheus.GaugeValue,
+			float64(bucket.Usage.Main.NumObjects),
+			bucket.Bucket,
+			bucket.Owner,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.BucketNumShards,
+			prometheus.GaugeValue,
+			float64(bucket.NumShards),
+			bucket.Bucket,
+			bucket.Owner,
+		)
+	}
+}
+
+// collectBucketShardFill reports how full the index shards of every bucket
+// are on average, to tell the buckets to reshard before their index slows
+// down. The bucket limit check only has the average of the shards, counting
+// the objects of each shard takes listing its index object. A failure is
+// logged but doesn't fail the collection.
+func (r *RGWCollector) collectBucketShardFill(ch chan<- prometheus.Metric) {
+	data, err := r.runCommand(r.getRGWLimitCheck)
+	if err != nil {
+		r.logger.WithError(err).Error("failed getting bucket limit check")
+		return
+	}
+
+	users := make([]rgwBucketLimitCheck, 0)
+	if err := json.Unmarshal(data, &users); err != nil {
+		r.parseErrors.observe("rgw")
+		r.logger.WithError(err).Error("failed unmarshalling bucket limit check")
+		return
+	}
+
+	for _, user := range users {
+		for _, bucket := range user.Buckets {
+			ch <- prometheus.MustNewConstMetric(r.BucketObjectsPerShard, prometheus.GaugeValue, bucket.ObjectsPerShard, bucket.Bucket, user.UserID)
+
+			status, _, _ := strings.Cut(bucket.FillStatus, " ")
+			ch <- prometheus.MustNewConstMetric(r.BucketShardFillStatus, prometheus.GaugeValue, 1, bucket.Bucket, user.UserID, strings.ToLower(status))
+		}
+	}
+}
+
+// collectZoneStats reports the totals of the local zone. Every zone of a
+// multisite setup is exported by the exporter of its own cluster, the zone
+// label tells them apart.
+func (r *RGWCollector) collectZoneStats(ch chan<- prometheus.Metric, buckets []rgwBucketStats) error {
+	data, err := r.runCommand(r.getRGWZone)
+	if err != nil {
+		return fmt.Errorf("failed getting zone: %w", err)
+	}
+
+	var zone rgwZone
+	if err := json.Unmarshal(data, &zone); err != nil {
+		r.parseErrors.observe("rgw")
+		return fmt.Errorf("failed unmarshalling zone: %w", err)
+	}
+
+	var objects int
+	for _, bucket := range buckets {
+		objects += bucket.Usage.Main.NumObjects
+	}
+
+	ch <- prometheus.MustNewConstMetric(r.ZoneBuckets, prometheus.GaugeValue, float64(len(buckets)), zone.Name)
+	ch <- prometheus.MustNewConstMetric(r.ZoneObjects, prometheus.GaugeValue, float64(objects), zone.Name)
+
+	return nil
+}
+
+func (r *RGWCollector) collectUserStats(ch chan<- prometheus.Metric, buckets []rgwBucketStats) error {
+	usedBytes := make(map[string]float64)
+	for _, bucket := range buckets {
+		usedBytes[bucket.Owner] += bucket.Usage.Main.Size
+	}
+
+	data, err := r.runCommand(r.getRGWUserList)
+	if err != nil {
+		return fmt.Errorf("failed getting user list: %w", err)
+	}
+
+	uids := make([]string, 0)
+	err = json.Unmarshal(data, &uids)
+	if err != nil {
+		r.parseErrors.observe("rgw")
+		return fmt.Errorf("failed unmarshalling user list: %w", err)
+	}
+
+	for _, uid := range uids {
+		data, err := r.runCommand(func(ctx context.Context, config, user, keyring string) ([]byte, error) {
+			return r.getRGWUserInfo(ctx, config, user, keyring, uid)
+		})
+		if errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			r.logger.WithField("user", uid).WithError(err).Error("failed getting rgw user info")
+			continue
+		}
+
+		info := rgwUserInfo{}
+		err = json.Unmarshal(data, &info)
+		if err != nil {
+			r.parseErrors.observe("rgw")
+			r.logger.WithField("user", uid).WithError(err).Error("failed unmarshalling rgw user info")
+			continue
+		}
+
+		// A disabled quota doesn't apply, which is the same as an
+		// unlimited one. Ceph reports unlimited values as -1.
+		maxBytes, maxObjects := float64(-1), float64(-1)
+		if info.UserQuota.Enabled {
+			maxBytes = math.Max(info.UserQuota.MaxSize, -1)
+			maxObjects = math.Max(info.UserQuota.MaxObjects, -1)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			r.UserQuotaMaxBytes,
+			prometheus.GaugeValue,
+			maxBytes,
+			uid,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.UserQuotaMaxObjects,
+			prometheus.GaugeValue,
+			maxObjects,
+			uid,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			r.UserUsedBytes,
+			prometheus.GaugeValue,
+			usedBytes[uid],
+			uid,
+		)
+	}
+
 	return nil
 }
 
@@ -298,21 +1515,38 @@ func (r *RGWCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 // Collect sends all the collected metrics to the provided prometheus channel.
-// It requires the caller to handle synchronization.
-func (r *RGWCollector) Collect(ch chan<- prometheus.Metric, version *Version) {
+// In background mode these are the metrics of the last complete background
+// collection. It requires the caller to handle synchronization.
+func (r *RGWCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	var err error
 	if !r.background {
 		r.logger.WithField("background", r.background).Debug("collecting RGW stats")
-		err := r.collect(ch)
+		err = r.collect()
 		if err != nil {
 			r.logger.WithField("background", r.background).WithError(err).Error("error collecting RGW stats")
 		}
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Don't report the gauges of an incomplete cycle.
+			return err
+		}
 	}
 
 	if r.background {
-		go r.backgroundCollect(ch)
+		r.backgroundOnce.Do(func() {
+			go r.backgroundCollect()
+		})
 	}
 
 	for _, metric := range r.collectorList() {
 		metric.Collect(ch)
 	}
+
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	for _, metric := range r.metrics {
+		ch <- metric
+	}
+
+	return err
 }
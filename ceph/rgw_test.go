@@ -15,12 +15,18 @@
 package ceph
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -106,6 +112,11 @@ func TestRGWGC(t *testing.T) {
 				regexp.MustCompile(`ceph_rgw_gc_active_objects{cluster="ceph"} 4`),
 				regexp.MustCompile(`ceph_rgw_gc_pending_tasks{cluster="ceph"} 1`),
 				regexp.MustCompile(`ceph_rgw_gc_pending_objects{cluster="ceph"} 3`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="0"} 0`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="12"} 1`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="14"} 2`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="31"} 0`),
+				regexp.MustCompile(`ceph_rgw_gc_oldest_active_task_age_seconds{cluster="ceph"} 1\.54706573043554\d*e\+09`),
 			},
 		},
 		{
@@ -116,6 +127,8 @@ func TestRGWGC(t *testing.T) {
 				regexp.MustCompile(`ceph_rgw_gc_active_objects{cluster="ceph"} 0`),
 				regexp.MustCompile(`ceph_rgw_gc_pending_tasks{cluster="ceph"} 0`),
 				regexp.MustCompile(`ceph_rgw_gc_pending_objects{cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="0"} 0`),
+				regexp.MustCompile(`ceph_rgw_gc_oldest_active_task_age_seconds{cluster="ceph"} 0`),
 			},
 		},
 		{
@@ -140,10 +153,13 @@ func TestRGWGC(t *testing.T) {
 
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
 			e.cc = map[string]versionedCollector{
-				"rgw": NewRGWCollector(e, false),
+				"rgw": NewRGWCollector(e, false, false, false),
 			}
 
-			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(cluster string, user string) ([]byte, error) {
+			e.cc["rgw"].(*RGWCollector).now = func() time.Time {
+				return time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+			}
+			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				if tt.input != nil {
 					return tt.input, nil
 				}
@@ -175,6 +191,88 @@ func TestRGWGC(t *testing.T) {
 	}
 }
 
+func TestRGWGCMaxObjs(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	rgw := NewRGWCollector(e, false, false, false)
+	e.cc = map[string]versionedCollector{
+		"rgw": rgw,
+	}
+
+	rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[{"tag": "00000000-0001-0000-0000-9ec86fa9a561.9695966.3129536\u0000", "time": "2024-01-10 14:00:00.0.000000s", "objs": []}]`), nil
+	}
+	rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name      string
+		gcMaxObjs []byte
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "unknown",
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="12"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="31"} 0\n`),
+			},
+		},
+		{
+			name:      "lowered",
+			gcMaxObjs: []byte("8\n"),
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="4"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="7"} 0\n`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="8"}`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="12"}`),
+			},
+		},
+		{
+			name:      "malformed",
+			gcMaxObjs: []byte("eight\n"),
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="12"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_gc_queue_length{cluster="ceph",shard="31"} 0\n`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rgw.getRGWGCMaxObjs = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.gcMaxObjs != nil {
+					return tt.gcMaxObjs, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.Regexp(t, re, string(buf))
+			}
+			for _, re := range tt.reUnmatch {
+				require.NotRegexp(t, re, string(buf))
+			}
+		})
+	}
+}
+
 func TestRGWReshardStats(t *testing.T) {
 	for _, tt := range []struct {
 		input     []byte
@@ -215,14 +313,294 @@ func TestRGWReshardStats(t *testing.T) {
 
 			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
 			e.cc = map[string]versionedCollector{
-				"rgw": NewRGWCollector(e, false),
+				"rgw": NewRGWCollector(e, false, false, false),
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.input != nil {
+					return tt.input, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), string(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestRGWBucketStats(t *testing.T) {
+	for _, tt := range []struct {
+		input       []byte
+		bucketStats bool
+		version     string
+		reMatch     []*regexp.Regexp
+		reUnmatch   []*regexp.Regexp
+	}{
+		{
+			input: []byte(`
+[
+	{
+		"bucket": "bucket-versioned",
+		"num_shards": 11,
+		"tenant": "",
+		"id": "97c1cfac-009f-4f7d-8d9d-9097c322c606.51988974.133",
+		"owner": "user-1",
+		"versioned": true,
+		"versioning_enabled": true,
+		"usage": {
+			"rgw.main": {
+				"size": 1073741824,
+				"size_actual": 1073745920,
+				"size_utilized": 1073741824,
+				"size_kb": 1048576,
+				"size_kb_actual": 1048580,
+				"size_kb_utilized": 1048576,
+				"num_objects": 12
+			},
+			"rgw.multimeta": {
+				"size": 0,
+				"size_actual": 0,
+				"size_utilized": 1728,
+				"size_kb": 0,
+				"size_kb_actual": 0,
+				"size_kb_utilized": 2,
+				"num_objects": 64
+			}
+		}
+	},
+	{
+		"bucket": "bucket-unversioned",
+		"num_shards": 11,
+		"tenant": "",
+		"id": "97c1cfac-009f-4f7d-8d9d-9097c322c606.51988974.134",
+		"owner": "user-1",
+		"versioned": false,
+		"versioning_enabled": false,
+		"usage": {}
+	},
+	{
+		"bucket": "bucket-suspended",
+		"num_shards": 11,
+		"tenant": "",
+		"id": "97c1cfac-009f-4f7d-8d9d-9097c322c606.51988974.135",
+		"owner": "user-1",
+		"versioned": true,
+		"versioning_enabled": false,
+		"usage": {}
+	}
+]
+`),
+			bucketStats: true,
+			version:     `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_bucket_versioning_enabled{bucket="bucket-versioned",cluster="ceph"} 1`),
+				regexp.MustCompile(`ceph_rgw_bucket_versioning_enabled{bucket="bucket-unversioned",cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_rgw_bucket_versioning_enabled{bucket="bucket-suspended",cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_rgw_bucket_incomplete_multipart_uploads{bucket="bucket-versioned",cluster="ceph"} 64`),
+				regexp.MustCompile(`ceph_rgw_bucket_incomplete_multipart_uploads{bucket="bucket-unversioned",cluster="ceph"} 0`),
+				regexp.MustCompile(`ceph_rgw_bucket_used_bytes{bucket="bucket-versioned",cluster="ceph",owner="user-1"} 1.073741824e\+09`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects{bucket="bucket-versioned",cluster="ceph",owner="user-1"} 12`),
+				regexp.MustCompile(`ceph_rgw_bucket_num_shards{bucket="bucket-versioned",cluster="ceph",owner="user-1"} 11`),
+				regexp.MustCompile(`ceph_rgw_bucket_used_bytes{bucket="bucket-unversioned",cluster="ceph",owner="user-1"} 0`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects{bucket="bucket-unversioned",cluster="ceph",owner="user-1"} 0`),
+				regexp.MustCompile(`ceph_rgw_zone_buckets_total{cluster="ceph",zone="us-east"} 3`),
+				regexp.MustCompile(`ceph_rgw_zone_objects_total{cluster="ceph",zone="us-east"} 12`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects_per_shard{bucket="bucket-versioned",cluster="ceph",owner="user-1"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects_per_shard{bucket="bucket-hot",cluster="ceph",owner="user-2"} 110000\n`),
+				regexp.MustCompile(`ceph_rgw_bucket_shard_fill_status{bucket="bucket-versioned",cluster="ceph",owner="user-1",status="ok"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_bucket_shard_fill_status{bucket="bucket-warm",cluster="ceph",owner="user-2",status="warn"} 1\n`),
+				regexp.MustCompile(`ceph_rgw_bucket_shard_fill_status{bucket="bucket-hot",cluster="ceph",owner="user-2",status="over"} 1\n`),
+			},
+		},
+		{
+			input:       []byte(`[{"bucket": "bucket-versioned", "versioning_enabled": true}]`),
+			bucketStats: false,
+			version:     `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_bucket_versioning_enabled`),
+				regexp.MustCompile(`ceph_rgw_bucket_incomplete_multipart_uploads`),
+				regexp.MustCompile(`ceph_rgw_bucket_used_bytes`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects`),
+				regexp.MustCompile(`ceph_rgw_bucket_num_shards`),
+				regexp.MustCompile(`ceph_rgw_zone_buckets_total`),
+				regexp.MustCompile(`ceph_rgw_zone_objects_total`),
+				regexp.MustCompile(`ceph_rgw_bucket_objects_per_shard`),
+				regexp.MustCompile(`ceph_rgw_bucket_shard_fill_status`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"rgw": NewRGWCollector(e, false, tt.bucketStats, false),
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWBucketStats = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.input != nil {
+					return tt.input, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWZone = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`{"id": "8ea7e0b2-5a6c-4d3e-9f1a-7b8c9d0e1f2a", "name": "us-east", "domain_root": "us-east.rgw.meta:root"}`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWLimitCheck = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`
+[
+	{
+		"user_id": "user-1",
+		"buckets": [
+			{"bucket": "bucket-versioned", "tenant": "", "num_objects": 12, "num_shards": 11, "objects_per_shard": 1, "fill_status": "OK"}
+		]
+	},
+	{
+		"user_id": "user-2",
+		"buckets": [
+			{"bucket": "bucket-warm", "tenant": "", "num_objects": 1012000, "num_shards": 11, "objects_per_shard": 92000, "fill_status": "WARN 92.00%"},
+			{"bucket": "bucket-hot", "tenant": "", "num_objects": 1210000, "num_shards": 11, "objects_per_shard": 110000, "fill_status": "OVER 110.00%"}
+		]
+	}
+]`), nil
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), string(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestRGWSyncStatus(t *testing.T) {
+	for _, tt := range []struct {
+		input     []byte
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			input: []byte(`
+          realm 1e1ab7a5-8e0c-4b5b-9c1c-2d9b0b7c1f1e (gold)
+      zonegroup 6e0f4b5b-1c2d-4e5f-8a9b-0c1d2e3f4a5b (us)
+           zone 8ea7e0b2-5a6c-4d3e-9f1a-7b8c9d0e1f2a (us-east)
+  metadata sync no sync (zone is master)
+      data sync source: 22d9c1f0-3b4a-4c5d-8e6f-7a8b9c0d1e2f (us-west)
+                        syncing
+                        full sync: 0/128 shards
+                        incremental sync: 128/128 shards
+                        data is behind on 3 shards
+                        behind shards: [49,50,51]
+                        oldest incremental change not applied: 2024-01-10T12:00:00.000000+0000 [49]
+                        5 shards are recovering
+                        recovering shards: [0,1,2,3,4]
+                source: 3f1e2d3c-4b5a-4c6d-9e7f-8a9b0c1d2e3f (us-central)
+                        syncing
+                        full sync: 0/128 shards
+                        incremental sync: 128/128 shards
+                        data is caught up with source
+`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_sync_behind_shards{cluster="ceph",source_zone="us-west"} 3`),
+				regexp.MustCompile(`ceph_rgw_sync_recovering_shards{cluster="ceph",source_zone="us-west"} 5`),
+				regexp.MustCompile(`ceph_rgw_sync_behind_shards{cluster="ceph",source_zone="us-central"} 0`),
+				regexp.MustCompile(`ceph_rgw_sync_recovering_shards{cluster="ceph",source_zone="us-central"} 0`),
+			},
+		},
+		{
+			input: []byte(`
+          realm  ()
+      zonegroup 6e0f4b5b-1c2d-4e5f-8a9b-0c1d2e3f4a5b (default)
+           zone 8ea7e0b2-5a6c-4d3e-9f1a-7b8c9d0e1f2a (default)
+  metadata sync no sync (zone is master)
+`),
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_sync_behind_shards`),
+				regexp.MustCompile(`ceph_rgw_sync_recovering_shards`),
+			},
+		},
+		{
+			input:   nil,
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_active_reshards{cluster="ceph"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_sync_behind_shards`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"rgw": NewRGWCollector(e, false, false, false),
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
 			}
 
-			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(cluster, user string) ([]byte, error) {
+			e.cc["rgw"].(*RGWCollector).getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				return []byte(`[]`), nil
 			}
 
-			e.cc["rgw"].(*RGWCollector).getRGWReshardList = func(cluster, user string) ([]byte, error) {
+			e.cc["rgw"].(*RGWCollector).getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
 				if tt.input != nil {
 					return tt.input, nil
 				}
@@ -253,3 +631,686 @@ func TestRGWReshardStats(t *testing.T) {
 		}()
 	}
 }
+
+func TestRGWUserStats(t *testing.T) {
+	for _, tt := range []struct {
+		users     []byte
+		userInfo  map[string][]byte
+		buckets   []byte
+		userStats bool
+		version   string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			users: []byte(`["user-1", "user-2", "user-3"]`),
+			userInfo: map[string][]byte{
+				"user-1": []byte(`{"user_id": "user-1", "user_quota": {"enabled": true, "check_on_raw": false, "max_size": 1099511627776, "max_size_kb": 1073741824, "max_objects": 100000}}`),
+				"user-2": []byte(`{"user_id": "user-2", "user_quota": {"enabled": true, "check_on_raw": false, "max_size": -1, "max_size_kb": 0, "max_objects": 5000}}`),
+				"user-3": []byte(`{"user_id": "user-3", "user_quota": {"enabled": false, "check_on_raw": false, "max_size": 1099511627776, "max_size_kb": 1073741824, "max_objects": -1}}`),
+			},
+			buckets: []byte(`
+[
+	{"bucket": "bucket-1", "owner": "user-1", "num_shards": 11, "usage": {"rgw.main": {"size": 1073741824, "num_objects": 12}}},
+	{"bucket": "bucket-2", "owner": "user-1", "num_shards": 11, "usage": {"rgw.main": {"size": 2147483648, "num_objects": 24}}},
+	{"bucket": "bucket-3", "owner": "user-2", "num_shards": 11, "usage": {"rgw.main": {"size": 1024, "num_objects": 1}}}
+]`),
+			userStats: true,
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_user_quota_max_bytes{cluster="ceph",user="user-1"} 1.099511627776e\+12`),
+				regexp.MustCompile(`ceph_rgw_user_quota_max_objects{cluster="ceph",user="user-1"} 100000`),
+				regexp.MustCompile(`ceph_rgw_user_used_bytes{cluster="ceph",user="user-1"} 3.221225472e\+09`),
+				regexp.MustCompile(`ceph_rgw_user_quota_max_bytes{cluster="ceph",user="user-2"} -1`),
+				regexp.MustCompile(`ceph_rgw_user_quota_max_objects{cluster="ceph",user="user-2"} 5000`),
+				regexp.MustCompile(`ceph_rgw_user_used_bytes{cluster="ceph",user="user-2"} 1024`),
+				regexp.MustCompile(`ceph_rgw_user_quota_max_bytes{cluster="ceph",user="user-3"} -1`),
+				regexp.MustCompile(`ceph_rgw_user_quota_max_objects{cluster="ceph",user="user-3"} -1`),
+				regexp.MustCompile(`ceph_rgw_user_used_bytes{cluster="ceph",user="user-3"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				// Per-bucket stats weren't asked for.
+				regexp.MustCompile(`ceph_rgw_bucket_used_bytes`),
+			},
+		},
+		{
+			users:     []byte(`["user-1"]`),
+			buckets:   []byte(`[]`),
+			userStats: false,
+			version:   `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_user_`),
+			},
+		},
+	} {
+		func() {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"rgw": NewRGWCollector(e, false, false, tt.userStats),
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWBucketStats = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return tt.buckets, nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWUserList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return tt.users, nil
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWUserInfo = func(_ context.Context, cluster, user, keyring, uid string) ([]byte, error) {
+				if out, ok := tt.userInfo[uid]; ok {
+					return out, nil
+				}
+				return nil, errors.New("fake error")
+			}
+
+			e.cc["rgw"].(*RGWCollector).getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return nil, nil
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), string(buf))
+			}
+
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf))
+			}
+		}()
+	}
+}
+
+func TestParseRGWGCTime(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  time.Time
+		err   bool
+	}{
+		{
+			input: "1975-01-01 16:31:09.0.564455s",
+			want:  time.Date(1975, 1, 1, 16, 31, 9, 564455000, time.UTC),
+		},
+		{
+			input: "3075-01-01 11:30:09.0.123456s",
+			want:  time.Date(3075, 1, 1, 11, 30, 9, 123456000, time.UTC),
+		},
+		{
+			input: "2024-01-10 13:00:00",
+			want:  time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			input: "2024-01-10 13:00:00.0.5s",
+			want:  time.Date(2024, 1, 10, 13, 0, 0, 500000000, time.UTC),
+		},
+		{
+			input: "2024-01-10T13:00:00Z",
+			err:   true,
+		},
+		{
+			input: "2024-13-10 13:00:00.0.564455s",
+			err:   true,
+		},
+		{
+			input: "",
+			err:   true,
+		},
+	} {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseRGWGCTime(tt.input)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, tt.want.Equal(got), got.String())
+		})
+	}
+}
+
+func TestRGWTimeout(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), RgwTimeout: 50 * time.Millisecond}
+	rgw := NewRGWCollector(e, false, false, false)
+	e.cc = map[string]versionedCollector{
+		"rgw": rgw,
+	}
+
+	rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+
+	rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[{"bucket_name": "bucket-1", "old_num_shards": 3, "new_num_shards": 12}]`), nil
+	}
+
+	// A hung admin socket, the process only gets killed once the deadline
+	// is exceeded.
+	rgw.getRGWSyncStatus = func(ctx context.Context, cluster, user, keyring string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, errors.New("signal: killed")
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.NotRegexp(t, regexp.MustCompile(`ceph_rgw_`), string(buf))
+}
+
+func TestRGWOrphans(t *testing.T) {
+	dir := t.TempDir()
+
+	orphans := filepath.Join(dir, "orphan-list-20240110130000.out")
+	err := os.WriteFile(orphans, []byte(`default.rgw.buckets.data:a1b2c3.4567.1__shadow_.xyz_1
+default.rgw.buckets.data:a1b2c3.4567.1__shadow_.xyz_2
+default.rgw.buckets.data:a1b2c3.4567.1__multipart_photo.jpg.2~abc.1
+
+`), 0o644)
+	require.NoError(t, err)
+	written := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(orphans, written, written))
+
+	for _, tt := range []struct {
+		name      string
+		file      string
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			name: "orphans",
+			file: orphans,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_orphan_objects{cluster="ceph"} 3\n`),
+				regexp.MustCompile(`ceph_rgw_orphan_list_timestamp_seconds{cluster="ceph"} 1.7048916e\+09\n`),
+			},
+		},
+		{
+			name: "missing output",
+			file: filepath.Join(dir, "missing.out"),
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_active_reshards{cluster="ceph"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_orphan_`),
+			},
+		},
+		{
+			name: "disabled",
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_orphan_`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New(), RgwOrphansFile: tt.file}
+			rgw := NewRGWCollector(e, false, false, false)
+			rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+			rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+			rgw.getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte{}, nil
+			}
+			rgw.listRGWAdminSockets = func() ([]string, error) {
+				return nil, nil
+			}
+			e.cc = map[string]versionedCollector{
+				"rgw": rgw,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
+
+func TestRGWZoneInfo(t *testing.T) {
+	period := []byte(`
+{
+	"id": "6bbb4a54-2cd3-4f67-9a20-8f5e8a0ba0c1",
+	"epoch": 3,
+	"period_map": {
+		"id": "6bbb4a54-2cd3-4f67-9a20-8f5e8a0ba0c1",
+		"zonegroups": [
+			{
+				"id": "b4c8d1b8-7b2e-4dd8-a7e0-b0fd5a1d4c0a",
+				"name": "us",
+				"is_master": "true",
+				"master_zone": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2",
+				"zones": [
+					{"id": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2", "name": "us-east"},
+					{"id": "8a7b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", "name": "us-west"}
+				]
+			}
+		]
+	},
+	"master_zonegroup": "b4c8d1b8-7b2e-4dd8-a7e0-b0fd5a1d4c0a",
+	"master_zone": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2",
+	"realm_id": "0f3e2a1b-5c6d-4e7f-8a9b-0c1d2e3f4a5b",
+	"realm_name": "gold",
+	"realm_epoch": 2
+}`)
+
+	for _, tt := range []struct {
+		name      string
+		zone      []byte
+		period    []byte
+		reMatch   []*regexp.Regexp
+		reUnmatch []*regexp.Regexp
+	}{
+		{
+			name:   "master zone",
+			zone:   []byte(`{"id": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2", "name": "us-east"}`),
+			period: period,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_zone_info{cluster="ceph",is_master="true",realm="gold",zone="us-east",zonegroup="us"} 1\n`),
+			},
+		},
+		{
+			name:   "secondary zone",
+			zone:   []byte(`{"id": "8a7b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", "name": "us-west"}`),
+			period: period,
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_zone_info{cluster="ceph",is_master="false",realm="gold",zone="us-west",zonegroup="us"} 1\n`),
+			},
+		},
+		{
+			// radosgw-admin fails to get the period of a zone without realm.
+			name: "no realm",
+			zone: []byte(`{"id": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2", "name": "default"}`),
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_zone_info{cluster="ceph",is_master="true",realm="",zone="default",zonegroup=""} 1\n`),
+			},
+		},
+		{
+			name: "zone failure",
+			reMatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_active_reshards{cluster="ceph"} 0`),
+			},
+			reUnmatch: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_rgw_zone_info`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			rgw := NewRGWCollector(e, false, false, false)
+			rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+			rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte(`[]`), nil
+			}
+			rgw.getRGWZone = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.zone != nil {
+					return tt.zone, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			rgw.getRGWPeriod = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				if tt.period != nil {
+					return tt.period, nil
+				}
+				return nil, errors.New("fake error")
+			}
+			rgw.getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+				return []byte{}, nil
+			}
+			rgw.listRGWAdminSockets = func() ([]string, error) {
+				return nil, nil
+			}
+			e.cc = map[string]versionedCollector{
+				"rgw": rgw,
+			}
+
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.reMatch {
+				require.True(t, re.Match(buf), re.String())
+			}
+			for _, re := range tt.reUnmatch {
+				require.False(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
+
+func TestRGWBucketSecondsSinceReshard(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	rgw := NewRGWCollector(e, false, true, false)
+	e.cc = map[string]versionedCollector{
+		"rgw": rgw,
+	}
+
+	now := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+	rgw.now = func() time.Time {
+		return now
+	}
+
+	var reshards, buckets []byte
+
+	rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+
+	rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return reshards, nil
+	}
+
+	rgw.getRGWBucketStats = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return buckets, nil
+	}
+
+	rgw.getRGWZone = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"id": "8ea7e0b2-5a6c-4d3e-9f1a-7b8c9d0e1f2a", "name": "us-east"}`), nil
+	}
+
+	rgw.getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, nil
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() string {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	// First pass, nothing was resharded yet.
+	reshards = []byte(`[]`)
+	buckets = []byte(`
+[
+	{"bucket": "bucket-1", "owner": "user-1", "num_shards": 11, "usage": {"rgw.main": {"size": 1024, "num_objects": 1}}},
+	{"bucket": "bucket-2", "owner": "user-1", "num_shards": 11, "usage": {"rgw.main": {"size": 1024, "num_objects": 1}}}
+]`)
+	buf := scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-1",cluster="ceph"} -1`), buf)
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-2",cluster="ceph"} -1`), buf)
+
+	// Second pass, bucket-1 is being resharded.
+	now = now.Add(30 * time.Second)
+	reshards = []byte(`[{"bucket_name": "bucket-1", "old_num_shards": 11, "new_num_shards": 23}]`)
+	buf = scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-1",cluster="ceph"} 0`), buf)
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-2",cluster="ceph"} -1`), buf)
+
+	// Third pass, the reshard is done and the bucket stats caught up, which
+	// isn't another reshard.
+	now = now.Add(time.Minute)
+	reshards = []byte(`[]`)
+	buckets = []byte(`
+[
+	{"bucket": "bucket-1", "owner": "user-1", "num_shards": 23, "usage": {"rgw.main": {"size": 1024, "num_objects": 1}}},
+	{"bucket": "bucket-2", "owner": "user-1", "num_shards": 11, "usage": {"rgw.main": {"size": 1024, "num_objects": 1}}}
+]`)
+	buf = scrape()
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-1",cluster="ceph"} 60`), buf)
+	require.Regexp(t, regexp.MustCompile(`ceph_rgw_bucket_seconds_since_reshard{bucket="bucket-2",cluster="ceph"} -1`), buf)
+}
+
+func TestRGWBackground(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	rgw := NewRGWCollector(e, true, false, false)
+
+	var cycles int32
+	rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		atomic.AddInt32(&cycles, 1)
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	rgw.getRGWZone = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`{"id": "1e4d1f5c-9c1a-4a41-9a9b-51d2b3a0f7e2", "name": "us-east"}`), nil
+	}
+	rgw.getRGWPeriod = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	rgw.listRGWAdminSockets = func() ([]string, error) {
+		return nil, nil
+	}
+
+	// Every scrape gets its own channel, closed once Collect returns like
+	// the registry does, the background collection must not use them.
+	collect := func() []prometheus.Metric {
+		ch := make(chan prometheus.Metric)
+		done := make(chan []prometheus.Metric)
+		go func() {
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+			done <- metrics
+		}()
+
+		require.NoError(t, rgw.Collect(ch, nil))
+		close(ch)
+		return <-done
+	}
+
+	collect()
+	require.Eventually(t, func() bool {
+		rgw.metricsMu.Lock()
+		defer rgw.metricsMu.Unlock()
+		return len(rgw.metrics) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		var zoneInfo bool
+		for _, metric := range collect() {
+			if strings.Contains(metric.Desc().String(), `"ceph_rgw_zone_info"`) {
+				zoneInfo = true
+			}
+		}
+		require.True(t, zoneInfo)
+	}
+
+	// A single background collection runs, whatever the number of scrapes.
+	require.Equal(t, int32(1), atomic.LoadInt32(&cycles))
+}
+
+func TestRGWPerfCounters(t *testing.T) {
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	rgw := NewRGWCollector(e, false, false, false)
+	rgw.getRGWGCTaskList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWReshardList = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWSyncStatus = func(_ context.Context, cluster, user, keyring string) ([]byte, error) {
+		return nil, errors.New("fake error")
+	}
+	rgw.perfCounters = true
+	rgw.listRGWAdminSockets = func() ([]string, error) {
+		return []string{
+			"/var/run/ceph/ceph-client.rgw.gw1.asok",
+			"/var/run/ceph/ceph-client.rgw.default.host2.abcdef.7.94361245372416.asok",
+			"/var/run/ceph/ceph-client.rgw.gw3.asok",
+		}, nil
+	}
+	rgw.getRGWPerfDump = func(_ context.Context, asok string) ([]byte, error) {
+		switch asok {
+		case "/var/run/ceph/ceph-client.rgw.gw1.asok":
+			return []byte(`{"rgw": {"req": 120, "failed_req": 4, "get": 80, "get_b": 1048576, "get_initial_lat": {"avgcount": 80, "sum": 1.2, "avgtime": 0.015}, "put": 30, "put_b": 4096, "qlen": 0, "qactive": 1}}`), nil
+		case "/var/run/ceph/ceph-client.rgw.default.host2.abcdef.7.94361245372416.asok":
+			return []byte(`{"rgw": {"req": 50, "failed_req": 1, "get_obj_ops": 20, "put_obj_ops": 10, "del_obj_ops": 5, "list_obj_ops": 12}}`), nil
+		}
+		return nil, errors.New("connection refused")
+	}
+	e.cc = map[string]versionedCollector{
+		"rgw": rgw,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="get",rgw="client.rgw.gw1"} 80\n`),
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="put",rgw="client.rgw.gw1"} 30\n`),
+		regexp.MustCompile(`ceph_rgw_failed_op_total{cluster="ceph",rgw="client.rgw.gw1"} 4\n`),
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="get",rgw="client.rgw.default.host2.abcdef"} 20\n`),
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="put",rgw="client.rgw.default.host2.abcdef"} 10\n`),
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="delete",rgw="client.rgw.default.host2.abcdef"} 5\n`),
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="list",rgw="client.rgw.default.host2.abcdef"} 12\n`),
+		regexp.MustCompile(`ceph_rgw_failed_op_total{cluster="ceph",rgw="client.rgw.default.host2.abcdef"} 1\n`),
+	} {
+		require.True(t, re.Match(buf), re.String())
+	}
+
+	for _, re := range []*regexp.Regexp{
+		// Pacific has no delete or list counters.
+		regexp.MustCompile(`ceph_rgw_op_total{cluster="ceph",op="delete",rgw="client.rgw.gw1"}`),
+		// gw3 didn't answer.
+		regexp.MustCompile(`rgw="client.rgw.gw3"`),
+	} {
+		require.False(t, re.Match(buf), re.String())
+	}
+}
+
+func TestRadosgwAdminArgs(t *testing.T) {
+	require.Equal(t,
+		[]string{"-c", "/etc/ceph/ceph.conf", "--user", "admin", "gc", "list"},
+		radosgwAdminArgs("/etc/ceph/ceph.conf", "admin", "", "gc", "list"),
+	)
+	require.Equal(t,
+		[]string{"-c", "/etc/ceph/ceph.conf", "--user", "exporter", "--keyring", "/etc/ceph-exporter/keyring", "gc", "list"},
+		radosgwAdminArgs("/etc/ceph/ceph.conf", "exporter", "/etc/ceph-exporter/keyring", "gc", "list"),
+	)
+}
+
+func TestRGWPerfCountersCephCLIMissing(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opt     bool
+		cliErr  error
+		enabled bool
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+		},
+		{
+			name:    "ceph cli",
+			opt:     true,
+			enabled: true,
+		},
+		{
+			name:    "no ceph cli",
+			opt:     true,
+			cliErr:  os.ErrNotExist,
+			enabled: false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(f func() error) { cephCLIAvailable = f }(cephCLIAvailable)
+			cephCLIAvailable = func() error { return tt.cliErr }
+
+			e := &Exporter{Cluster: "ceph", RgwPerfCounters: tt.opt, Logger: logrus.New()}
+			require.Equal(t, tt.enabled, NewRGWCollector(e, false, false, false).perfCounters)
+		})
+	}
+}
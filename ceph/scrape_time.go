@@ -0,0 +1,134 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// ScrapeTimeCollector wraps a Conn and accounts for the time the exporter
+// spends in librados calls and in external ceph/radosgw-admin processes. The
+// totals are reported and reset on every scrape, which makes it easy to tell
+// which of the two dominates the scrape duration.
+type ScrapeTimeCollector struct {
+	conn   Conn
+	logger *logrus.Logger
+
+	// cliNanos and radosNanos accumulate the time spent since the previous
+	// scrape, in nanoseconds.
+	cliNanos   int64
+	radosNanos int64
+
+	// CLITime reports the time in seconds spent waiting on external
+	// processes since the previous scrape.
+	CLITime *prometheus.Desc
+
+	// RadosTime reports the time in seconds spent in librados calls since
+	// the previous scrape.
+	RadosTime *prometheus.Desc
+}
+
+// *ScrapeTimeCollector must implement the Conn.
+var _ Conn = &ScrapeTimeCollector{}
+
+// NewScrapeTimeCollector creates a new ScrapeTimeCollector wrapping the
+// exporter's Conn. The returned collector should be used as the Conn for all
+// other collectors so that their librados calls are accounted for.
+func NewScrapeTimeCollector(exporter *Exporter) *ScrapeTimeCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &ScrapeTimeCollector{
+		conn:   exporter.Conn,
+		logger: exporter.Logger,
+
+		CLITime: prometheus.NewDesc(fmt.Sprintf("%s_exporter_cli_time_seconds", namespace), "Time spent by the exporter in external ceph and radosgw-admin processes since the previous scrape",
+			nil, labels,
+		),
+		RadosTime: prometheus.NewDesc(fmt.Sprintf("%s_exporter_rados_time_seconds", namespace), "Time spent by the exporter in librados calls since the previous scrape",
+			nil, labels,
+		),
+	}
+}
+
+// observeCLI accounts for the time spent in an external process started at
+// start. It is a no-op on a nil receiver, so that collectors built without
+// an exporter-wide ScrapeTimeCollector don't have to care.
+func (s *ScrapeTimeCollector) observeCLI(start time.Time) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.cliNanos, int64(time.Since(start)))
+}
+
+func (s *ScrapeTimeCollector) observeRados(start time.Time) {
+	atomic.AddInt64(&s.radosNanos, int64(time.Since(start)))
+}
+
+// MonCommand executes a monitor command and accounts for its duration.
+func (s *ScrapeTimeCollector) MonCommand(args []byte) ([]byte, string, error) {
+	defer s.observeRados(time.Now())
+
+	return s.conn.MonCommand(args)
+}
+
+// MgrCommand executes a manager command and accounts for its duration.
+func (s *ScrapeTimeCollector) MgrCommand(args [][]byte) ([]byte, string, error) {
+	defer s.observeRados(time.Now())
+
+	return s.conn.MgrCommand(args)
+}
+
+// GetPoolStats retrieves the stats of a pool and accounts for its duration.
+func (s *ScrapeTimeCollector) GetPoolStats(pool string) (*PoolStat, error) {
+	defer s.observeRados(time.Now())
+
+	return s.conn.GetPoolStats(pool)
+}
+
+// GetPoolStatsContext retrieves the stats of a pool and accounts for its
+// duration.
+func (s *ScrapeTimeCollector) GetPoolStatsContext(ctx context.Context, pool string) (*PoolStat, error) {
+	defer s.observeRados(time.Now())
+
+	return s.conn.GetPoolStatsContext(ctx, pool)
+}
+
+// Describe sends the descriptors of the scrape time metrics to the provided
+// channel.
+func (s *ScrapeTimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.CLITime
+	ch <- s.RadosTime
+}
+
+// Collect sends the time accumulated since the previous scrape to the
+// provided channel and starts over. It must only be called once all the
+// other collectors are done, so that the whole scrape is accounted for.
+func (s *ScrapeTimeCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	cli := time.Duration(atomic.SwapInt64(&s.cliNanos, 0))
+	rados := time.Duration(atomic.SwapInt64(&s.radosNanos, 0))
+
+	ch <- prometheus.MustNewConstMetric(s.CLITime, prometheus.GaugeValue, cli.Seconds())
+	ch <- prometheus.MustNewConstMetric(s.RadosTime, prometheus.GaugeValue, rados.Seconds())
+
+	return nil
+}
@@ -0,0 +1,119 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrapeTimeCollector(t *testing.T) {
+	const (
+		radosDelay = 50 * time.Millisecond
+		cliDelay   = 80 * time.Millisecond
+	)
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.MatchedBy(func(in interface{}) bool {
+		v := map[string]interface{}{}
+
+		err := json.Unmarshal(in.([]byte), &v)
+		require.NoError(t, err)
+
+		return cmp.Equal(v, map[string]interface{}{
+			"prefix": "df",
+			"detail": "detail",
+			"format": "json",
+		})
+	})).After(radosDelay).Return([]byte(`{"stats": {"total_bytes": 10}}`), "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.scrapeTime = NewScrapeTimeCollector(e)
+	e.Conn = e.scrapeTime
+
+	// Only the first scrape is slow on the CLI side.
+	slowCLI := true
+	rgw := NewRGWCollector(e, false, false, false)
+	rgw.getRGWGCTaskList = func(_ context.Context, _, _, _ string) ([]byte, error) {
+		if slowCLI {
+			time.Sleep(cliDelay)
+		}
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWReshardList = func(_ context.Context, _, _, _ string) ([]byte, error) {
+		return []byte(`[]`), nil
+	}
+	rgw.getRGWSyncStatus = func(_ context.Context, _, _, _ string) ([]byte, error) {
+		return nil, nil
+	}
+
+	e.cc = map[string]versionedCollector{
+		"clusterUsage": NewClusterUsageCollector(e),
+		"rgw":          rgw,
+	}
+
+	err := prometheus.Register(e)
+	require.NoError(t, err)
+	defer prometheus.Unregister(e)
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		buf, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return buf
+	}
+
+	seconds := func(buf []byte, metric string) float64 {
+		matched := regexp.MustCompile(metric + `{cluster="ceph"} (\S+)`).FindSubmatch(buf)
+		require.Len(t, matched, 2, metric)
+
+		v, err := strconv.ParseFloat(string(matched[1]), 64)
+		require.NoError(t, err)
+
+		return v
+	}
+
+	buf := scrape()
+	require.GreaterOrEqual(t, seconds(buf, "ceph_exporter_cli_time_seconds"), cliDelay.Seconds())
+	require.GreaterOrEqual(t, seconds(buf, "ceph_exporter_rados_time_seconds"), radosDelay.Seconds())
+
+	// The time spent during the first scrape isn't carried over to the
+	// second one.
+	slowCLI = false
+	buf = scrape()
+	require.Less(t, seconds(buf, "ceph_exporter_cli_time_seconds"), cliDelay.Seconds())
+	require.GreaterOrEqual(t, seconds(buf, "ceph_exporter_rados_time_seconds"), radosDelay.Seconds())
+}
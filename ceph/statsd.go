@@ -0,0 +1,186 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// statsdMaxPacketSize keeps the datagrams below the typical MTU so they
+// don't get fragmented.
+const statsdMaxPacketSize = 1432
+
+// statsdNameReplacer replaces the characters that have a meaning in the
+// StatsD line protocol or in the dotted metric hierarchy.
+var statsdNameReplacer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", " ", "_", "\n", "_")
+
+// StatsdEmitter periodically pushes the metrics gathered from the collectors
+// to a StatsD server, for monitoring stacks that can't scrape Prometheus.
+//
+// Gauges and untyped metrics are sent as StatsD gauges. Counters are sent as
+// StatsD counters carrying the increase since the previous push. Histograms
+// and summaries are sent as gauges for their sum and count.
+//
+// Every push gathers the exporters, which runs a full collection of their
+// clusters like a scrape does unless it falls within the CacheTTL of the last
+// one. The exporter warns at startup when the emitter runs without a CacheTTL.
+type StatsdEmitter struct {
+	gatherer prometheus.Gatherer
+	address  string
+	interval time.Duration
+	logger   *logrus.Logger
+
+	// counters holds the last pushed value of every counter, so that only
+	// the increase is sent on the next push.
+	counters map[string]float64
+}
+
+// NewStatsdEmitter creates a StatsdEmitter pushing the metrics of the given
+// gatherer to the StatsD server listening on address over UDP.
+func NewStatsdEmitter(gatherer prometheus.Gatherer, address string, logger *logrus.Logger) *StatsdEmitter {
+	return &StatsdEmitter{
+		gatherer: gatherer,
+		address:  address,
+		interval: backgroundCollectInterval,
+		logger:   logger,
+		counters: make(map[string]float64),
+	}
+}
+
+// Run pushes the metrics on every interval, it never returns.
+func (s *StatsdEmitter) Run() {
+	for {
+		if err := s.push(); err != nil {
+			s.logger.WithError(err).WithField("address", s.address).Error("error pushing metrics to statsd")
+		}
+		time.Sleep(s.interval)
+	}
+}
+
+func (s *StatsdEmitter) push() error {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever it could collect along with the error.
+		s.logger.WithError(err).Warn("error gathering metrics for statsd")
+	}
+
+	conn, err := net.Dial("udp", s.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	for _, line := range s.translate(families) {
+		if buf.Len() > 0 && buf.Len()+len(line)+1 > statsdMaxPacketSize {
+			if _, err := conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// translate turns the metric families into StatsD lines.
+func (s *StatsdEmitter) translate(families []*dto.MetricFamily) []string {
+	var lines []string
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := statsdName(family.GetName(), m.GetLabel())
+
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				lines = append(lines, statsdGauge(name, m.GetGauge().GetValue())...)
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, statsdGauge(name, m.GetUntyped().GetValue())...)
+			case dto.MetricType_COUNTER:
+				value := m.GetCounter().GetValue()
+				last, ok := s.counters[name]
+				s.counters[name] = value
+				if !ok {
+					// Nothing to compare against yet.
+					continue
+				}
+				if value < last {
+					// The counter was reset.
+					last = 0
+				}
+				lines = append(lines, statsdLine(name, value-last, "c"))
+			case dto.MetricType_HISTOGRAM:
+				lines = append(lines, statsdGauge(name+".sum", m.GetHistogram().GetSampleSum())...)
+				lines = append(lines, statsdGauge(name+".count", float64(m.GetHistogram().GetSampleCount()))...)
+			case dto.MetricType_SUMMARY:
+				lines = append(lines, statsdGauge(name+".sum", m.GetSummary().GetSampleSum())...)
+				lines = append(lines, statsdGauge(name+".count", float64(m.GetSummary().GetSampleCount()))...)
+			}
+		}
+	}
+
+	return lines
+}
+
+// statsdName flattens a metric name and its labels into a dotted StatsD
+// name, e.g. ceph_pool_used_bytes.cluster_ceph.pool_rbd.
+func statsdName(name string, labels []*dto.LabelPair) string {
+	parts := []string{statsdNameReplacer.Replace(name)}
+	for _, l := range labels {
+		parts = append(parts, statsdNameReplacer.Replace(fmt.Sprintf("%s_%s", l.GetName(), l.GetValue())))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// statsdGauge returns the lines setting a gauge to the given value. StatsD
+// treats signed gauge values as relative changes, so a negative value has to
+// be sent as a reset to zero followed by a decrement.
+func statsdGauge(name string, value float64) []string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+
+	if value < 0 {
+		return []string{statsdLine(name, 0, "g"), statsdLine(name, value, "g")}
+	}
+
+	return []string{statsdLine(name, value, "g")}
+}
+
+func statsdLine(name string, value float64, kind string) string {
+	return fmt.Sprintf("%s:%s|%s", name, strconv.FormatFloat(value, 'f', -1, 64), kind)
+}
@@ -0,0 +1,105 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// readStatsdLines reads the lines of all the datagrams received until the
+// receiver has been idle for a little while.
+func readStatsdLines(t *testing.T, receiver net.PacketConn) []string {
+	var lines []string
+
+	buf := make([]byte, 65535)
+	for {
+		require.NoError(t, receiver.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+
+		n, _, err := receiver.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return lines
+			}
+			require.NoError(t, err)
+		}
+
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func TestStatsdEmitter(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	conn := setupVersionMocks(`{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`, "{}")
+	conn.On("MonCommand", mock.Anything).Return([]byte(`
+{
+	"stats": {
+		"total_bytes": 10,
+		"total_used_bytes": 6,
+		"total_avail_bytes": 4
+	}
+}`), "", nil)
+
+	e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+	e.cc = map[string]versionedCollector{
+		"clusterUsage": NewClusterUsageCollector(e),
+	}
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_events_total",
+		Help: "Test counter",
+	}, []string{"pool"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "test_negative",
+		Help: "Test gauge",
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e, counter, gauge)
+
+	emitter := NewStatsdEmitter(registry, receiver.LocalAddr().String(), logrus.New())
+
+	counter.WithLabelValues("rbd.ssd").Add(5)
+	gauge.Set(-3)
+	require.NoError(t, emitter.push())
+
+	lines := readStatsdLines(t, receiver)
+	require.Contains(t, lines, "ceph_cluster_capacity_bytes.cluster_ceph:10|g")
+	require.Contains(t, lines, "ceph_cluster_used_bytes.cluster_ceph:6|g")
+	require.Contains(t, lines, "ceph_cluster_available_bytes.cluster_ceph:4|g")
+	require.Contains(t, lines, "test_negative:0|g")
+	require.Contains(t, lines, "test_negative:-3|g")
+	// Counters are only sent once there is a previous value to compare with.
+	for _, line := range lines {
+		require.False(t, strings.HasPrefix(line, "test_events_total"), line)
+	}
+
+	counter.WithLabelValues("rbd.ssd").Add(2)
+	require.NoError(t, emitter.push())
+
+	lines = readStatsdLines(t, receiver)
+	require.Contains(t, lines, "test_events_total.pool_rbd_ssd:2|c")
+	require.Contains(t, lines, "ceph_cluster_capacity_bytes.cluster_ceph:10|g")
+}
@@ -29,6 +29,11 @@ type Version struct {
 	Patch    int
 	Revision int
 	Commit   string
+
+	// SHA1 is the commit the release was built from and Release its code
+	// name, e.g. pacific. Both are empty for version strings without them.
+	SHA1    string
+	Release string
 }
 
 var (
@@ -46,6 +51,9 @@ var (
 
 	// Quincy is the *Version at which Ceph Quincy was released.
 	Quincy = &Version{Major: 17, Minor: 2, Patch: 0, Revision: 0, Commit: ""}
+
+	// Reef is the *Version at which Ceph Reef was released.
+	Reef = &Version{Major: 18, Minor: 2, Patch: 0, Revision: 0, Commit: ""}
 )
 
 // IsAtLeast returns true if the version is at least as new as the given constraint
@@ -82,7 +90,7 @@ func (version *Version) IsAtLeast(constraint *Version) bool {
 func (version *Version) String() string {
 	str := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
 	if version.Revision != 0 || version.Commit != "" {
-		str = fmt.Sprintf("%s-%d", str, version.Patch)
+		str = fmt.Sprintf("%s-%d", str, version.Revision)
 		if version.Commit != "" {
 			str = fmt.Sprintf("%s-%s", str, version.Commit)
 		}
@@ -136,11 +144,23 @@ func ParseCephVersion(cephVersion string) (*Version, error) {
 
 	commit := otherVersions[2]
 
+	// The build commit and the release name follow the version, e.g.
+	// "(dd0603118f56ab514f133c8d2e3adfc983942503) pacific (stable)".
+	var sha1, release string
+	if len(splitVersion) > 3 {
+		sha1 = strings.Trim(splitVersion[3], "()")
+	}
+	if len(splitVersion) > 4 {
+		release = splitVersion[4]
+	}
+
 	return &Version{
 		Major:    major,
 		Minor:    minor,
 		Patch:    patch,
 		Revision: revision,
 		Commit:   commit,
+		SHA1:     sha1,
+		Release:  release,
 	}, nil
 }
@@ -0,0 +1,57 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// VersionInfoCollector exposes the version of the cluster as reported by
+// the monitors, so that dashboards can be annotated during upgrades.
+type VersionInfoCollector struct {
+	// VersionInfo is always 1, labelled by the version, the release name
+	// and the build commit of the cluster.
+	VersionInfo *prometheus.Desc
+}
+
+// NewVersionInfoCollector creates a new VersionInfoCollector.
+func NewVersionInfoCollector(exporter *Exporter) *VersionInfoCollector {
+	labels := exporter.constLabels()
+	namespace := exporter.namespace()
+
+	return &VersionInfoCollector{
+		VersionInfo: prometheus.NewDesc(fmt.Sprintf("%s_version_info", namespace), "Version of the cluster as reported by the monitors",
+			[]string{"version", "release", "commit"}, labels,
+		),
+	}
+}
+
+// Describe sends the descriptors of the version info metrics to the provided
+// channel.
+func (v *VersionInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.VersionInfo
+}
+
+// Collect sends the version info metrics to the provided channel.
+func (v *VersionInfoCollector) Collect(ch chan<- prometheus.Metric, version *Version) error {
+	if version == nil {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(v.VersionInfo, prometheus.GaugeValue, 1, version.String(), version.Release, version.SHA1)
+	return nil
+}
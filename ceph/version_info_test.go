@@ -0,0 +1,77 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package ceph
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionInfoCollector(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		version string
+		regexes []*regexp.Regexp
+	}{
+		{
+			name:    "pacific",
+			version: `{"version":"ceph version 16.2.11-22-wasd (1984a8c33225d70559cdf27dbab81e3ce153f6ac) pacific (stable)"}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_version_info{cluster="ceph",commit="1984a8c33225d70559cdf27dbab81e3ce153f6ac",release="pacific",version="16.2.11-22-wasd"} 1`),
+			},
+		},
+		{
+			name:    "reef",
+			version: `{"version":"ceph version 18.2.1 (7fe91d5d5842e04be3b4f514d6dd990c54b29c76) reef (stable)"}`,
+			regexes: []*regexp.Regexp{
+				regexp.MustCompile(`ceph_version_info{cluster="ceph",commit="7fe91d5d5842e04be3b4f514d6dd990c54b29c76",release="reef",version="18.2.1"} 1`),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := setupVersionMocks(tt.version, "{}")
+
+			e := &Exporter{Conn: conn, Cluster: "ceph", Logger: logrus.New()}
+			e.cc = map[string]versionedCollector{
+				"versionInfo": NewVersionInfoCollector(e),
+			}
+			err := prometheus.Register(e)
+			require.NoError(t, err)
+			defer prometheus.Unregister(e)
+
+			server := httptest.NewServer(promhttp.Handler())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			for _, re := range tt.regexes {
+				require.True(t, re.Match(buf), re.String())
+			}
+		})
+	}
+}
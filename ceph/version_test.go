@@ -44,13 +44,13 @@ func TestParseCephVersion(t *testing.T) {
 		{
 			name:    "nautilus",
 			args:    args{cephVersion: "ceph version 14.2.18-97-gcc1e126 (cc1e1267bc7afc8288c718fc3e59c5a6735f6f4a) nautilus (stable)"},
-			want:    &Version{Major: 14, Minor: 2, Patch: 18, Revision: 97, Commit: "gcc1e126"},
+			want:    &Version{Major: 14, Minor: 2, Patch: 18, Revision: 97, Commit: "gcc1e126", SHA1: "cc1e1267bc7afc8288c718fc3e59c5a6735f6f4a", Release: "nautilus"},
 			wantErr: false,
 		},
 		{
 			name:    "nautilus-ceph-ansible",
 			args:    args{cephVersion: "ceph version 14.2.11-184.el8cp (44441323476fee97be0ff7a92c6065958c77f1b9) nautilus (stable)"},
-			want:    &Version{Major: 14, Minor: 2, Patch: 11, Revision: 184, Commit: "el8cp"},
+			want:    &Version{Major: 14, Minor: 2, Patch: 11, Revision: 184, Commit: "el8cp", SHA1: "44441323476fee97be0ff7a92c6065958c77f1b9", Release: "nautilus"},
 			wantErr: false,
 		},
 		{
@@ -68,7 +68,25 @@ func TestParseCephVersion(t *testing.T) {
 		{
 			name:    "real pacific",
 			args:    args{cephVersion: "ceph version 16.2.7 (dd0603118f56ab514f133c8d2e3adfc983942503) pacific (stable)"},
-			want:    &Version{Major: 16, Minor: 2, Patch: 7, Revision: 0, Commit: ""},
+			want:    &Version{Major: 16, Minor: 2, Patch: 7, Revision: 0, Commit: "", SHA1: "dd0603118f56ab514f133c8d2e3adfc983942503", Release: "pacific"},
+			wantErr: false,
+		},
+		{
+			name:    "mimic",
+			args:    args{cephVersion: "ceph version 13.2.10 (564bdc4ae87418a232fc901524470e1a0f76d641) mimic (stable)"},
+			want:    &Version{Major: 13, Minor: 2, Patch: 10, Revision: 0, Commit: "", SHA1: "564bdc4ae87418a232fc901524470e1a0f76d641", Release: "mimic"},
+			wantErr: false,
+		},
+		{
+			name:    "quincy",
+			args:    args{cephVersion: "ceph version 17.2.6 (d7ff0d10654d2280e08f1ab989c7cdf3064446a5) quincy (stable)"},
+			want:    &Version{Major: 17, Minor: 2, Patch: 6, Revision: 0, Commit: "", SHA1: "d7ff0d10654d2280e08f1ab989c7cdf3064446a5", Release: "quincy"},
+			wantErr: false,
+		},
+		{
+			name:    "reef",
+			args:    args{cephVersion: "ceph version 18.2.1 (7fe91d5d5842e04be3b4f514d6dd990c54b29c76) reef (stable)"},
+			want:    &Version{Major: 18, Minor: 2, Patch: 1, Revision: 0, Commit: "", SHA1: "7fe91d5d5842e04be3b4f514d6dd990c54b29c76", Release: "reef"},
 			wantErr: false,
 		},
 	}
@@ -86,6 +104,32 @@ func TestParseCephVersion(t *testing.T) {
 	}
 }
 
+func TestVersion_String(t *testing.T) {
+	tests := []struct {
+		name    string
+		version *Version
+		want    string
+	}{
+		{
+			name:    "release",
+			version: &Version{Major: 16, Minor: 2, Patch: 7},
+			want:    "16.2.7",
+		},
+		{
+			name:    "revision and commit",
+			version: &Version{Major: 16, Minor: 2, Patch: 11, Revision: 22, Commit: "wasd"},
+			want:    "16.2.11-22-wasd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.version.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestVersion_IsAtLeast(t *testing.T) {
 	type fields struct {
 		Major    int
@@ -133,6 +177,24 @@ func TestVersion_IsAtLeast(t *testing.T) {
 			args:   args{constraint: Pacific},
 			want:   true,
 		},
+		{
+			name:   "quincy before reef",
+			fields: fields{Major: 17, Minor: 2, Patch: 6},
+			args:   args{constraint: Reef},
+			want:   false,
+		},
+		{
+			name:   "reef after nautilus",
+			fields: fields{Major: 18, Minor: 2, Patch: 1},
+			args:   args{constraint: Nautilus},
+			want:   true,
+		},
+		{
+			name:   "mimic before nautilus",
+			fields: fields{Major: 13, Minor: 2, Patch: 10},
+			args:   args{constraint: Nautilus},
+			want:   false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -23,7 +23,17 @@ import (
 type ClusterConfig struct {
 	ClusterLabel string `yaml:"cluster_label"`
 	User         string `yaml:"user"`
+	Keyring      string `yaml:"keyring"`
 	ConfigFile   string `yaml:"config_file"`
+	PoolFilter   string `yaml:"pool_filter"`
+	RgwOrphans   string `yaml:"rgw_orphans_file"`
+
+	// RestfulURL is the URL of the restful module of ceph-mgr, the commands
+	// are sent to it instead of librados when set.
+	RestfulURL     string `yaml:"restful_url"`
+	RestfulUser    string `yaml:"restful_user"`
+	RestfulKeyFile string `yaml:"restful_key_file"`
+	RestfulCAFile  string `yaml:"restful_ca_file"`
 }
 
 // Config is the top-level configuration for Metastord.
@@ -0,0 +1,32 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//go:build norados
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coreweave/ceph_exporter/ceph"
+)
+
+// newRadosConn fails, the exporter was built without librados and can only
+// reach the clusters through the restful module.
+func newRadosConn(cluster *ClusterConfig, timeout time.Duration, logger *logrus.Logger) (ceph.Conn, error) {
+	return nil, errors.New("built without librados, set CEPH_RESTFUL_URL to go through the restful module")
+}
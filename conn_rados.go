@@ -0,0 +1,32 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//go:build !norados
+
+package main
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coreweave/ceph_exporter/ceph"
+	"github.com/coreweave/ceph_exporter/rados"
+)
+
+// newRadosConn returns a connection sending the commands of the cluster
+// through librados.
+func newRadosConn(cluster *ClusterConfig, timeout time.Duration, logger *logrus.Logger) (ceph.Conn, error) {
+	return rados.NewRadosConn(cluster.User, cluster.ConfigFile, timeout, logger)
+}
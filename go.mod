@@ -5,9 +5,11 @@ go 1.20
 require (
 	github.com/Jeffail/gabs v1.4.0
 	github.com/ceph/go-ceph v0.14.0
+	github.com/cespare/xxhash/v2 v2.3.0
 	github.com/google/go-cmp v0.5.7
 	github.com/ianschenck/envflag v0.0.0-20140720210342-9111d830d133
 	github.com/prometheus/client_golang v1.12.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.1
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
@@ -16,12 +18,10 @@ require (
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
-	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.32.1 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
 	github.com/stretchr/objx v0.1.1 // indirect
@@ -17,9 +17,12 @@ package main
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
@@ -31,7 +34,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/coreweave/ceph_exporter/ceph"
-	"github.com/coreweave/ceph_exporter/rados"
+	"github.com/coreweave/ceph_exporter/restful"
 )
 
 const (
@@ -67,6 +70,9 @@ func (ln emfileAwareTcpListener) Accept() (c net.Conn, err error) {
 	return tc, nil
 }
 
+// metricNamespaceRegex matches the valid prefixes of the metric names.
+var metricNamespaceRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
 // Verify that the exporter implements the interface correctly.
 var _ prometheus.Collector = &ceph.Exporter{}
 
@@ -76,25 +82,66 @@ func main() {
 		metricsPath    = envflag.String("TELEMETRY_PATH", "/metrics", "URL path for surfacing metrics to Prometheus")
 		exporterConfig = envflag.String("EXPORTER_CONFIG", "/etc/ceph/exporter.yml", "Path to ceph_exporter config")
 		rgwMode        = envflag.Int("RGW_MODE", 0, "Enable collection of stats from RGW (0:disabled 1:enabled 2:background)")
+		rgwBucketStats = envflag.Bool("RGW_BUCKET_STATS", false, "Enable collection of per-bucket stats from RGW (requires RGW_MODE)")
+		rgwUserStats   = envflag.Bool("RGW_USER_STATS", false, "Enable collection of per-user quota and usage stats from RGW (requires RGW_MODE)")
+		rgwTimeout     = envflag.Duration("RGW_TIMEOUT", 60*time.Second, "Timeout of each radosgw-admin command run by the RGW collector")
 		mdsMode        = envflag.Int("MDS_MODE", 0, "Enable collection of stats from MDS (0:disabled 1:enabled 2:background)")
+		mdsHistoricOps = envflag.Bool("MDS_HISTORIC_OPS", false, "Enable the duration summaries of the ops in the history of the active MDS daemons (requires MDS_MODE)")
+		mdsExemplars   = envflag.Bool("MDS_EXEMPLARS", false, "Attach the reqid of a blocked op to the MDS blocked ops as an exemplar and serve OpenMetrics to the scrapers asking for it (requires MDS_MODE)")
+		cacheTTL       = envflag.Duration("CACHE_TTL", 0, "Serve the metrics of the last collection to scrapes within this duration of it (0s means disabled)")
+		osdConfigKeys  = envflag.String("OSD_CONFIG_KEYS", "", "Comma separated config options to count the OSDs overriding (empty means disabled)")
+		cmdAttempts    = envflag.Int("COMMAND_ATTEMPTS", 3, "Attempts of the mon and mgr commands failing transiently, e.g. during a mon election")
+		scrapeTimeout  = envflag.Duration("SCRAPE_TIMEOUT", 10*time.Second, "Scrape timeout of Prometheus, the commands aren't retried past it (0s means no limit)")
+		osdLatency     = envflag.Bool("OSD_OP_LATENCY", false, "Enable the OSD op latency histograms, read from every up OSD through the ceph CLI")
+		poolOpsRate    = envflag.Bool("POOL_OPS_RATE", false, "Enable the per second read and write op rates of the pools, derived from consecutive scrapes")
+		poolInconsObjs = envflag.Bool("INCONSISTENT_OBJECTS", false, "Enable the inconsistent objects of the pools, listed through the rados CLI for every inconsistent PG")
+		osdPings       = envflag.Bool("OSD_HEARTBEAT_PINGS", false, "Enable the heartbeat ping times between every pair of OSDs, as aggregated by the active mgr")
+		pgPerOSDMin    = envflag.Int("PG_PER_OSD_MIN", 30, "Lowest recommended count of PG replicas per in OSD, below it ceph_cluster_pg_count_healthy is 0")
+		pgPerOSDMax    = envflag.Int("PG_PER_OSD_MAX", 250, "Highest recommended count of PG replicas per in OSD, above it ceph_cluster_pg_count_healthy is 0")
+		omitCluster    = envflag.Bool("OMIT_CLUSTER_LABEL", false, "Leave the cluster label out of the metrics, e.g. when Prometheus adds it (single cluster only)")
+		namespace      = envflag.String("METRICS_NAMESPACE", "ceph", "Prefix of the metric names, e.g. to tell them apart from other ceph_ metrics")
 
-		logLevel = envflag.String("LOG_LEVEL", "info", "Logging level. One of: [trace, debug, info, warn, error, fatal, panic]")
+		logLevel  = envflag.String("LOG_LEVEL", "info", "Logging level. One of: [trace, debug, info, warn, error, fatal, panic]")
+		logFormat = envflag.String("LOG_FORMAT", "text", "Logging format. One of: [text, json]")
 
 		cephCluster        = envflag.String("CEPH_CLUSTER", defaultCephClusterLabel, "Ceph cluster name")
 		cephConfig         = envflag.String("CEPH_CONFIG", defaultCephConfigPath, "Path to Ceph config file")
 		cephUser           = envflag.String("CEPH_USER", defaultCephUser, "Ceph user to connect to cluster")
+		cephKeyring        = envflag.String("CEPH_KEYRING", "", "Path to the keyring of the Ceph user for the ceph CLI and radosgw-admin (empty means found through the Ceph config)")
 		cephRadosOpTimeout = envflag.Duration("CEPH_RADOS_OP_TIMEOUT", defaultRadosOpTimeout, "Ceph rados_osd_op_timeout and rados_mon_op_timeout used to contact cluster (0s means no limit)")
+		cephPoolFilter     = envflag.String("POOL_FILTER", "", "Regular expression restricting the pools to collect usage stats from (empty means all pools)")
+		rgwOrphansFile     = envflag.String("RGW_ORPHANS_FILE", "", "Path to the output of the last rgw-orphan-list run to report the orphans of (requires RGW_MODE)")
+		rgwPerfCounters    = envflag.Bool("RGW_PERF_COUNTERS", false, "Enable the RGW request counters, read from the admin sockets of the radosgw instances on the exporter host (requires RGW_MODE)")
+
+		restfulURL     = envflag.String("CEPH_RESTFUL_URL", "", "URL of the restful module of ceph-mgr to send the commands to instead of librados, e.g. https://mgr:8003 (empty means librados)")
+		restfulUser    = envflag.String("CEPH_RESTFUL_USER", "", "User of the API key of the restful module")
+		restfulKeyPath = envflag.String("CEPH_RESTFUL_KEY_FILE", "", "Path to the file holding the API key of CEPH_RESTFUL_USER")
+		restfulCAPath  = envflag.String("CEPH_RESTFUL_CA_FILE", "", "Path to the CA certificate of the restful module (empty means the system roots)")
 
 		tlsCertPath = envflag.String("TLS_CERT_FILE_PATH", "", "Path to certificate file for TLS")
 		tlsKeyPath  = envflag.String("TLS_KEY_FILE_PATH", "", "Path to key file for TLS")
+
+		basicAuthUser         = envflag.String("BASIC_AUTH_USER", "", "User allowed to scrape the metrics with basic auth (empty means disabled)")
+		basicAuthPasswordPath = envflag.String("BASIC_AUTH_PASS_FILE", "", "Path to the file holding the basic auth password of BASIC_AUTH_USER")
+		bearerTokenPath       = envflag.String("BEARER_TOKEN_FILE", "", "Path to the file holding the bearer token allowed to scrape the metrics (empty means disabled)")
+
+		statsdAddr = envflag.String("STATSD_ADDR", "", "Host:Port of a StatsD server to also push metrics to (empty means disabled)")
 	)
 
 	envflag.Parse()
 
 	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
+	switch *logFormat {
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+		if *logFormat != "text" {
+			logger.WithField("format", *logFormat).Warn("unknown log format, using text")
+		}
+	}
 
 	if v, err := logrus.ParseLevel(*logLevel); err != nil {
 		logger.WithError(err).Warn("error setting log level")
@@ -117,35 +164,115 @@ func main() {
 			{
 				ClusterLabel: *cephCluster,
 				User:         *cephUser,
+				Keyring:      *cephKeyring,
 				ConfigFile:   *cephConfig,
+				PoolFilter:   *cephPoolFilter,
+				RgwOrphans:   *rgwOrphansFile,
+
+				RestfulURL:     *restfulURL,
+				RestfulUser:    *restfulUser,
+				RestfulKeyFile: *restfulKeyPath,
+				RestfulCAFile:  *restfulCAPath,
 			},
 		}
 	}
 
-	for _, cluster := range clusterConfigs {
-		conn, err := rados.NewRadosConn(
-			cluster.User,
-			cluster.ConfigFile,
-			*cephRadosOpTimeout,
-			logger)
+	// The metrics of several clusters can't be told apart without the
+	// cluster label.
+	if *omitCluster && len(clusterConfigs) > 1 {
+		logger.Fatal("the cluster label can only be omitted when exporting a single cluster")
+	}
+
+	if !metricNamespaceRegex.MatchString(*namespace) {
+		logger.WithField("namespace", *namespace).Fatal("invalid metrics namespace")
+	}
 
+	// Compile all the pool filters upfront, so that an invalid one is
+	// reported before connecting to any cluster.
+	poolFilters := make([]*regexp.Regexp, len(clusterConfigs))
+	for i, cluster := range clusterConfigs {
+		if len(cluster.PoolFilter) == 0 {
+			continue
+		}
+
+		poolFilter, err := regexp.Compile(cluster.PoolFilter)
 		if err != nil {
-			logger.WithError(err).WithField("cluster", cluster.ClusterLabel).Fatal("unable to create rados connection for cluster")
+			logger.WithError(err).WithField("cluster", cluster.ClusterLabel).Fatal("invalid pool filter")
 		}
+		poolFilters[i] = poolFilter
+	}
 
-		prometheus.MustRegister(ceph.NewExporter(
-			conn,
-			cluster.ClusterLabel,
-			cluster.ConfigFile,
-			cluster.User,
-			*rgwMode,
-			*mdsMode,
-			logger))
+	var osdConfigOverrideKeys []string
+	for _, key := range strings.Split(*osdConfigKeys, ",") {
+		if key = strings.TrimSpace(key); len(key) != 0 {
+			osdConfigOverrideKeys = append(osdConfigOverrideKeys, key)
+		}
+	}
+
+	exporters := make([]*ceph.Exporter, 0, len(clusterConfigs))
+	for i, cluster := range clusterConfigs {
+		var conn ceph.Conn
+		if len(cluster.RestfulURL) != 0 {
+			conn = newRestfulConn(cluster, *cephRadosOpTimeout, logger)
+		} else {
+			radosConn, err := newRadosConn(cluster, *cephRadosOpTimeout, logger)
+			if err != nil {
+				logger.WithError(err).WithField("cluster", cluster.ClusterLabel).Fatal("unable to create rados connection for cluster")
+			}
+			conn = radosConn
+		}
+
+		exporter := ceph.NewExporter(conn, cluster.ClusterLabel, ceph.ExporterOptions{
+			Config:                  cluster.ConfigFile,
+			User:                    cluster.User,
+			Keyring:                 cluster.Keyring,
+			RgwMode:                 *rgwMode,
+			RgwBucketStats:          *rgwBucketStats,
+			RgwUserStats:            *rgwUserStats,
+			RgwTimeout:              *rgwTimeout,
+			RgwOrphansFile:          cluster.RgwOrphans,
+			RgwPerfCounters:         *rgwPerfCounters,
+			MDSMode:                 *mdsMode,
+			MDSHistoricOps:          *mdsHistoricOps,
+			MDSExemplars:            *mdsExemplars,
+			PoolFilter:              poolFilters[i],
+			CacheTTL:                *cacheTTL,
+			OSDConfigOverrideKeys:   osdConfigOverrideKeys,
+			CommandAttempts:         *cmdAttempts,
+			ScrapeTimeout:           *scrapeTimeout,
+			OSDLatencyHistograms:    *osdLatency,
+			PoolOpsRates:            *poolOpsRate,
+			PoolInconsistentObjects: *poolInconsObjs,
+			OSDHeartbeatPings:       *osdPings,
+			PGPerOSDMin:             *pgPerOSDMin,
+			PGPerOSDMax:             *pgPerOSDMax,
+			OmitClusterLabel:        *omitCluster,
+			Namespace:               *namespace,
+		}, logger)
+		if exporter == nil {
+			logger.WithField("cluster", cluster.ClusterLabel).Fatal("unable to create exporter for cluster")
+		}
+		prometheus.MustRegister(exporter)
+		exporters = append(exporters, exporter)
 
 		logger.WithField("cluster", cluster.ClusterLabel).Info("exporting cluster")
 	}
 
-	http.Handle(*metricsPath, promhttp.Handler())
+	if len(*statsdAddr) != 0 {
+		logger.WithField("address", *statsdAddr).Info("pushing metrics to statsd")
+		if *cacheTTL <= 0 {
+			// Every push would collect from the clusters on top of the scrapes.
+			logger.Warn("STATSD_ADDR without CACHE_TTL, every push runs a full collection, set CACHE_TTL to the scrape interval to reuse the last scrape")
+		}
+		go ceph.NewStatsdEmitter(prometheus.DefaultGatherer, *statsdAddr, logger).Run()
+	}
+
+	// The exemplars are only exposed in the OpenMetrics format.
+	http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: *mdsExemplars}),
+	))
+	http.Handle("/healthz", ceph.NewHealthzHandler(exporters...))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>Ceph Exporter</title></head>
@@ -156,6 +283,29 @@ func main() {
 			</html>`))
 	})
 
+	var handler http.Handler = http.DefaultServeMux
+
+	auth := &ceph.HTTPAuth{Username: *basicAuthUser}
+	if len(*basicAuthPasswordPath) != 0 {
+		auth.Password = readSecretFile(*basicAuthPasswordPath, logger)
+		if len(auth.Password) == 0 {
+			logger.WithField("path", *basicAuthPasswordPath).Fatal("BASIC_AUTH_PASS_FILE holds an empty password")
+		}
+	}
+	if len(*bearerTokenPath) != 0 {
+		auth.BearerToken = readSecretFile(*bearerTokenPath, logger)
+	}
+	if err := auth.Validate(); err != nil {
+		logger.WithError(err).Fatal("BASIC_AUTH_USER and BASIC_AUTH_PASS_FILE must be set together")
+	}
+	if auth.Enabled() {
+		// The probes of the orchestrator can't authenticate.
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", handler)
+		mux.Handle("/", auth.Wrap(handler))
+		handler = mux
+	}
+
 	logger.WithField("endpoint", *metricsAddr).Info("starting ceph_exporter listener")
 
 	// Below is essentially http.ListenAndServe(), but using our custom
@@ -167,6 +317,7 @@ func main() {
 
 	if len(*tlsCertPath) != 0 && len(*tlsKeyPath) != 0 {
 		server := &http.Server{
+			Handler: handler,
 			TLSConfig: &tls.Config{
 				GetCertificate: func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
 					caFiles, err := tls.LoadX509KeyPair(*tlsCertPath, *tlsKeyPath)
@@ -184,9 +335,46 @@ func main() {
 			logrus.WithError(err).Fatal("error serving TLS requests")
 		}
 	} else {
-		err = http.Serve(emfileAwareTcpListener{ln.(*net.TCPListener), logger}, nil)
+		err = http.Serve(emfileAwareTcpListener{ln.(*net.TCPListener), logger}, handler)
 		if err != nil {
 			logrus.WithError(err).Fatal("error serving requests")
 		}
 	}
 }
+
+// readSecretFile returns the secret held in the file, without the trailing
+// newline editors tend to add.
+func readSecretFile(path string, logger *logrus.Logger) string {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		logger.WithError(err).WithField("path", path).Fatal("unable to read secret file")
+	}
+
+	return strings.TrimSpace(string(secret))
+}
+
+// newRestfulConn returns a connection sending the commands of the cluster to
+// the restful module of its ceph-mgr.
+func newRestfulConn(cluster *ClusterConfig, timeout time.Duration, logger *logrus.Logger) *restful.RestfulConn {
+	ll := logger.WithField("cluster", cluster.ClusterLabel)
+	if len(cluster.RestfulKeyFile) == 0 {
+		ll.Fatal("the restful module requires the file holding the API key")
+	}
+
+	var tlsConfig *tls.Config
+	if len(cluster.RestfulCAFile) != 0 {
+		ca, err := os.ReadFile(cluster.RestfulCAFile)
+		if err != nil {
+			ll.WithError(err).WithField("path", cluster.RestfulCAFile).Fatal("unable to read CA file of the restful module")
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			ll.WithField("path", cluster.RestfulCAFile).Fatal("no certificate found in CA file of the restful module")
+		}
+		tlsConfig = &tls.Config{RootCAs: roots}
+	}
+
+	key := readSecretFile(cluster.RestfulKeyFile, logger)
+	return restful.NewRestfulConn(cluster.RestfulURL, cluster.RestfulUser, key, tlsConfig, timeout, logger)
+}
@@ -16,6 +16,7 @@ package rados
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -139,7 +140,37 @@ func (c *RadosConn) MgrCommand(args [][]byte) (buffer []byte, info string, err e
 }
 
 // GetPoolStats returns the count of unfound objects for the given rados pool.
+//
+// Deprecated: use GetPoolStatsContext.
 func (c *RadosConn) GetPoolStats(pool string) (*ceph.PoolStat, error) {
+	return c.GetPoolStatsContext(context.Background(), pool)
+}
+
+// GetPoolStatsContext returns the count of unfound objects for the given
+// rados pool, giving up once the context is done. librados calls can't be
+// interrupted, a call given up on still runs until rados_osd_op_timeout in
+// the background.
+func (c *RadosConn) GetPoolStatsContext(ctx context.Context, pool string) (*ceph.PoolStat, error) {
+	type result struct {
+		st  *ceph.PoolStat
+		err error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		st, err := c.getPoolStats(pool)
+		ch <- result{st, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.st, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error getting stats of pool %s: %w", pool, ctx.Err())
+	}
+}
+
+func (c *RadosConn) getPoolStats(pool string) (*ceph.PoolStat, error) {
 	ll := c.logger.WithField("pool", pool).WithField("conn", c.conn.GetInstanceID())
 	ll.Trace("opening IOContext for pool")
 
@@ -0,0 +1,216 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package restful
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coreweave/ceph_exporter/ceph"
+)
+
+// RestfulConn implements the Conn interface on top of the REST API of the
+// restful module of ceph-mgr, for the exporters that can't link librados.
+// The module runs the commands it is given on the mons, which forward the
+// mgr commands to the active mgr.
+type RestfulConn struct {
+	url    string
+	user   string
+	key    string
+	client *http.Client
+	logger *logrus.Logger
+}
+
+// *RestfulConn must implement the Conn.
+var _ ceph.Conn = &RestfulConn{}
+
+// NewRestfulConn returns a new RestfulConn sending the commands to the
+// restful module listening on url, e.g. https://mgr:8003, authenticated as
+// the user of the API key. A nil tlsConfig verifies the certificate of the
+// module against the system roots and a zero timeout doesn't bound the
+// commands.
+func NewRestfulConn(url, user, key string, tlsConfig *tls.Config, timeout time.Duration, logger *logrus.Logger) *RestfulConn {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &RestfulConn{
+		url:    strings.TrimSuffix(url, "/"),
+		user:   user,
+		key:    key,
+		client: &http.Client{Transport: transport, Timeout: timeout},
+		logger: logger,
+	}
+}
+
+// restfulRequest is the subset of the state of a request to the restful
+// module we care about.
+type restfulRequest struct {
+	HasFailed bool            `json:"has_failed"`
+	Finished  []restfulResult `json:"finished"`
+	Failed    []restfulResult `json:"failed"`
+}
+
+// restfulResult is the output of one of the commands of a request.
+type restfulResult struct {
+	Outb string `json:"outb"`
+	Outs string `json:"outs"`
+}
+
+// command runs the command through the restful module and waits for it to
+// complete.
+func (c *RestfulConn) command(ctx context.Context, cmd []byte) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/request?wait=1", bytes.NewReader(cmd))
+	if err != nil {
+		return nil, "", err
+	}
+	req.SetBasicAuth(c.user, c.key)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error sending request to restful module: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading reply of restful module: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("restful module replied %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var r restfulRequest
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling reply of restful module: %w", err)
+	}
+
+	if r.HasFailed {
+		if len(r.Failed) == 0 {
+			return nil, "", fmt.Errorf("command failed")
+		}
+		return []byte(r.Failed[0].Outb), r.Failed[0].Outs, fmt.Errorf("command failed: %s", r.Failed[0].Outs)
+	}
+
+	if len(r.Finished) == 0 {
+		return nil, "", fmt.Errorf("command didn't finish")
+	}
+
+	return []byte(r.Finished[0].Outb), r.Finished[0].Outs, nil
+}
+
+// MonCommand executes a monitor command through the restful module.
+func (c *RestfulConn) MonCommand(args []byte) (buffer []byte, info string, err error) {
+	ll := c.logger.WithField("args", string(args))
+	ll.Trace("start executing mon command")
+
+	buffer, info, err = c.command(context.Background(), args)
+
+	ll.WithError(err).Trace("complete executing mon command")
+
+	return
+}
+
+// MgrCommand executes a manager command through the restful module. Like
+// librados, the parts of the command are joined.
+func (c *RestfulConn) MgrCommand(args [][]byte) (buffer []byte, info string, err error) {
+	ll := c.logger.WithField("args", string(bytes.Join(args, []byte(","))))
+	ll.Trace("start executing mgr command")
+
+	buffer, info, err = c.command(context.Background(), bytes.Join(args, nil))
+
+	ll.WithError(err).Trace("complete executing mgr command")
+
+	return
+}
+
+// GetPoolStats returns the count of unfound objects for the given pool.
+//
+// Deprecated: use GetPoolStatsContext.
+func (c *RestfulConn) GetPoolStats(pool string) (*ceph.PoolStat, error) {
+	return c.GetPoolStatsContext(context.Background(), pool)
+}
+
+// GetPoolStatsContext returns the count of unfound objects for the given
+// pool, giving up once the context is done. The pool stats of librados
+// aren't available through the restful module, the unfound objects are
+// summed up from the stats of the PGs of the pool instead.
+func (c *RestfulConn) GetPoolStatsContext(ctx context.Context, pool string) (*ceph.PoolStat, error) {
+	ll := c.logger.WithField("pool", pool)
+	ll.Trace("start getting pool stats")
+
+	cmd, err := json.Marshal(map[string]interface{}{
+		"prefix":  "pg ls-by-pool",
+		"poolstr": pool,
+		"format":  "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf, _, err := c.command(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stats of pool %s: %w", pool, err)
+	}
+
+	stats, err := parsePGStats(buf)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling stats of pool %s: %w", pool, err)
+	}
+
+	poolSt := &ceph.PoolStat{}
+	for _, pg := range stats {
+		poolSt.ObjectsUnfound += pg.StatSum.NumObjectsUnfound
+	}
+
+	ll.Trace("complete getting pool stats")
+
+	return poolSt, nil
+}
+
+// pgStat is the subset of the stats of a PG we care about.
+type pgStat struct {
+	StatSum struct {
+		NumObjectsUnfound uint64 `json:"num_objects_unfound"`
+	} `json:"stat_sum"`
+}
+
+// parsePGStats parses the output of pg ls-by-pool, an object holding the PG
+// stats since Nautilus and the bare list of them before.
+func parsePGStats(buf []byte) ([]pgStat, error) {
+	var stats []pgStat
+	if err := json.Unmarshal(buf, &stats); err == nil {
+		return stats, nil
+	}
+
+	var ls struct {
+		PGStats []pgStat `json:"pg_stats"`
+	}
+	if err := json.Unmarshal(buf, &ls); err != nil {
+		return nil, err
+	}
+
+	return ls.PGStats, nil
+}
@@ -0,0 +1,153 @@
+//   Copyright 2024 DigitalOcean
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package restful
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// newRestfulServer returns a fake restful module replying to every command
+// with the given reply, and recording the commands it was sent.
+func newRestfulServer(t *testing.T, reply string, commands *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, key, ok := r.BasicAuth()
+		if !ok || user != "exporter" || key != "s3cret" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/request", r.URL.Path)
+		require.Equal(t, "1", r.URL.Query().Get("wait"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var cmd map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &cmd))
+		*commands = append(*commands, cmd)
+
+		w.Write([]byte(reply))
+	}))
+}
+
+func TestRestfulConnCommands(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		key    string
+		reply  string
+		outb   string
+		outs   string
+		errMsg string
+	}{
+		{
+			name:  "finished",
+			key:   "s3cret",
+			reply: `{"id": "140", "has_failed": false, "is_finished": true, "finished": [{"command": "status format=json", "outb": "{\"fsid\": \"abc\"}", "outs": ""}], "failed": [], "state": "success"}`,
+			outb:  `{"fsid": "abc"}`,
+		},
+		{
+			name:   "failed",
+			key:    "s3cret",
+			reply:  `{"id": "141", "has_failed": true, "is_finished": true, "finished": [], "failed": [{"command": "osd pool autoscale-status", "outb": "", "outs": "module 'pg_autoscaler' is not enabled"}], "state": "failed"}`,
+			outs:   "module 'pg_autoscaler' is not enabled",
+			errMsg: "command failed: module 'pg_autoscaler' is not enabled",
+		},
+		{
+			name:   "unauthorized",
+			key:    "secret",
+			errMsg: "restful module replied 401 Unauthorized: Unauthorized",
+		},
+		{
+			name:   "invalid reply",
+			key:    "s3cret",
+			reply:  `<html>`,
+			errMsg: "error unmarshalling reply of restful module: invalid character '<' looking for beginning of value",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []map[string]interface{}
+			server := newRestfulServer(t, tt.reply, &commands)
+			defer server.Close()
+
+			conn := NewRestfulConn(server.URL+"/", "exporter", tt.key, nil, 0, logrus.New())
+
+			for _, run := range []func() ([]byte, string, error){
+				func() ([]byte, string, error) {
+					return conn.MonCommand([]byte(`{"prefix": "status", "format": "json"}`))
+				},
+				func() ([]byte, string, error) {
+					return conn.MgrCommand([][]byte{[]byte(`{"prefix": "status", "format": "json"}`)})
+				},
+			} {
+				outb, outs, err := run()
+				if tt.errMsg != "" {
+					require.EqualError(t, err, tt.errMsg)
+				} else {
+					require.NoError(t, err)
+				}
+				require.Equal(t, tt.outb, string(outb))
+				require.Equal(t, tt.outs, outs)
+			}
+
+			if tt.key == "s3cret" {
+				require.Len(t, commands, 2)
+				require.Equal(t, "status", commands[0]["prefix"])
+			}
+		})
+	}
+}
+
+func TestRestfulConnGetPoolStats(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		pgStats string
+	}{
+		{
+			name:    "nautilus",
+			pgStats: `{"pg_ready": true, "pg_stats": [{"pgid": "1.0", "stat_sum": {"num_objects_unfound": 2}}, {"pgid": "1.1", "stat_sum": {"num_objects_unfound": 1}}]}`,
+		},
+		{
+			name:    "mimic",
+			pgStats: `[{"pgid": "1.0", "stat_sum": {"num_objects_unfound": 2}}, {"pgid": "1.1", "stat_sum": {"num_objects_unfound": 1}}]`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			outb, err := json.Marshal(tt.pgStats)
+			require.NoError(t, err)
+
+			var commands []map[string]interface{}
+			server := newRestfulServer(t, `{"has_failed": false, "finished": [{"outb": `+string(outb)+`, "outs": ""}]}`, &commands)
+			defer server.Close()
+
+			conn := NewRestfulConn(server.URL, "exporter", "s3cret", nil, 0, logrus.New())
+
+			st, err := conn.GetPoolStatsContext(context.Background(), "rbd")
+			require.NoError(t, err)
+			require.Equal(t, uint64(3), st.ObjectsUnfound)
+
+			require.Len(t, commands, 1)
+			require.Equal(t, "pg ls-by-pool", commands[0]["prefix"])
+			require.Equal(t, "rbd", commands[0]["poolstr"])
+		})
+	}
+}